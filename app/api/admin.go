@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rs/zerolog/log"
+)
+
+type adminService struct {
+	storage db.Storage
+}
+
+// AdminUser represents user in admin API responses
+type AdminUser struct {
+	ID       int64      `json:"id"`
+	Username string     `json:"username"`
+	Language string     `json:"language"`
+	IsAdmin  bool       `json:"is_admin"`
+	Blocked  bool       `json:"blocked"`
+	LastSeen *time.Time `json:"last_seen"`
+}
+
+// UserActivity represents dictionary size and quiz activity of the user
+type UserActivity struct {
+	Words    int        `json:"words"`
+	Mastered int        `json:"mastered"`
+	Answers  int        `json:"answers"`
+	LastQuiz *time.Time `json:"last_quiz"`
+}
+
+// AdminUserDetails represents user with its activity in admin API responses
+type AdminUserDetails struct {
+	AdminUser
+	Activity UserActivity `json:"activity"`
+}
+
+// AdminUserUpdate holds changes of the user role, missing fields are kept
+type AdminUserUpdate struct {
+	IsAdmin *bool `json:"is_admin"`
+	Blocked *bool `json:"blocked"`
+}
+
+func newAdminUser(u db.User) AdminUser {
+	return AdminUser{
+		ID:       int64(u.ID),
+		Username: u.Username,
+		Language: u.Language,
+		IsAdmin:  u.IsAdmin,
+		Blocked:  u.Blocked,
+		LastSeen: u.LastSeen,
+	}
+}
+
+// writeJSON marshals value and writes it to response
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	response, jerr := json.Marshal(value)
+	if jerr != nil {
+		log.Error().Err(jerr).Msg("failed to marshal response")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if _, err := w.Write(response); err != nil {
+		log.Warn().Err(err).Msg("failed to write response")
+	}
+}
+
+// getUser returns user by ID from URL, error response is written if the user can't be returned
+func (s adminService) getUser(w http.ResponseWriter, r *http.Request) (db.User, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user ID")
+		return db.User{}, false
+	}
+	user, err := s.storage.GetUser(db.UserID(id))
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "user not found")
+			return db.User{}, false
+		}
+		log.Error().Err(err).Int64("user", id).Msg("failed to get user")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return db.User{}, false
+	}
+	return user, true
+}
+
+// GetUsers returns all users sorted by ID
+func (s adminService) GetUsers(w http.ResponseWriter, _ *http.Request) {
+	users, err := s.storage.GetUsers()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get users")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	res := make([]AdminUser, 0, len(users))
+	for _, u := range users {
+		res = append(res, newAdminUser(u))
+	}
+	writeJSON(w, res)
+}
+
+// GetUser returns user with its dictionary size and quiz activity
+func (s adminService) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getUser(w, r)
+	if !ok {
+		return
+	}
+	activity, err := db.GetUserActivity(s.storage, user)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(user.ID)).Msg("failed to get user activity")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	writeJSON(w, AdminUserDetails{
+		AdminUser: newAdminUser(user),
+		Activity: UserActivity{
+			Words:    activity.Words,
+			Mastered: activity.Mastered,
+			Answers:  activity.Answers,
+			LastQuiz: activity.LastQuiz,
+		},
+	})
+}
+
+// UpdateUser grants or revokes admin role and blocks or unblocks the user,
+// admins can't change themselves
+func (s adminService) UpdateUser(w http.ResponseWriter, r *http.Request) {
+	adminID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	var req AdminUserUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	user, ok := s.getUser(w, r)
+	if !ok {
+		return
+	}
+	id := user.ID
+	user, err := db.UpdateUserAccess(s.storage, adminID, id, db.UserAccess{IsAdmin: req.IsAdmin, Blocked: req.Blocked})
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrSelfUpdate):
+			writeError(w, http.StatusBadRequest, "can't update yourself")
+		case errors.Is(err, db.ErrAdminBlocked):
+			writeError(w, http.StatusConflict, "admin can't be blocked")
+		default:
+			log.Error().Err(err).Int64("user", int64(id)).Msg("failed to update user")
+			writeError(w, http.StatusInternalServerError, "internal error")
+		}
+		return
+	}
+	writeJSON(w, newAdminUser(user))
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminOnly(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+	const path = "/api/v1/admin/users"
+
+	r := doRequest(t, http.MethodGet, ts.URL+path, "", "")
+	assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+
+	r = doRequest(t, http.MethodGet, ts.URL+path, getTestJWT(), "")
+	assert.Equal(t, http.StatusForbidden, r.StatusCode)
+
+	require.NoError(t, storage.SaveUser(db.User{ID: testUserID}))
+	r = doRequest(t, http.MethodGet, ts.URL+path, getTestJWT(), "")
+	assert.Equal(t, http.StatusForbidden, r.StatusCode)
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"error":"forbidden"}`, string(body))
+
+	require.NoError(t, storage.SaveUser(db.User{ID: testUserID, IsAdmin: true}))
+	r = doRequest(t, http.MethodGet, ts.URL+path, getTestJWT(), "")
+	assert.Equal(t, http.StatusOK, r.StatusCode)
+
+	t.Run("personal token", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+"/api/v1/tokens/", getTestJWT(), `{"name": "sync", "scope": "write"}`)
+		require.Equal(t, http.StatusCreated, r.StatusCode)
+		var token CreatedPersonalToken
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&token))
+		for _, tc := range []struct{ method, path string }{
+			{http.MethodGet, path},
+			{http.MethodGet, "/api/v1/admin/users/1"},
+			{http.MethodPatch, "/api/v1/admin/users/2"},
+			{http.MethodPost, "/api/v1/dictionary/word/test"},
+			{http.MethodGet, "/api/v1/dictionary/word/test/history"},
+			{http.MethodPost, "/api/v1/dictionary/word/test/revert/1"},
+			{http.MethodPost, "/api/v1/dictionary/word/test/refresh"},
+		} {
+			r := doRequest(t, tc.method, ts.URL+tc.path, "Bearer "+token.Token, "{}")
+			assert.Equal(t, http.StatusForbidden, r.StatusCode, tc.method+" "+tc.path)
+		}
+	})
+}
+
+func TestAdminGetUsers(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+	lastSeen := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, storage.SaveUser(db.User{ID: testUserID, IsAdmin: true, Username: "admin"}))
+	require.NoError(t, storage.SaveUser(db.User{ID: 2, Username: "test", Language: "en", Blocked: true, LastSeen: &lastSeen}))
+
+	r := doRequest(t, http.MethodGet, ts.URL+"/api/v1/admin/users", getTestJWT(), "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"id": 1, "username": "admin", "language": "", "is_admin": true, "blocked": false, "last_seen": null},
+		{"id": 2, "username": "test", "language": "en", "is_admin": false, "blocked": true, "last_seen": "2023-01-01T00:00:00Z"}
+	]`, string(body))
+}
+
+func TestAdminGetUser(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+	require.NoError(t, storage.SaveUser(db.User{ID: testUserID, IsAdmin: true}))
+	require.NoError(t, storage.SaveUser(db.User{ID: 2, Username: "test"}))
+	lastQuiz := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: 2, Word: "cat", LastQuiz: &lastQuiz}))
+	require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: 2, Word: "dog"}))
+
+	t.Run("success", func(t *testing.T) {
+		r := doRequest(t, http.MethodGet, ts.URL+"/api/v1/admin/users/2", getTestJWT(), "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		var res AdminUserDetails
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&res))
+		assert.Equal(t, "test", res.Username)
+		assert.Equal(t, UserActivity{Words: 2, LastQuiz: &lastQuiz}, res.Activity)
+	})
+	t.Run("not found", func(t *testing.T) {
+		r := doRequest(t, http.MethodGet, ts.URL+"/api/v1/admin/users/3", getTestJWT(), "")
+		assert.Equal(t, http.StatusNotFound, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"user not found"}`, string(body))
+	})
+	t.Run("invalid ID", func(t *testing.T) {
+		r := doRequest(t, http.MethodGet, ts.URL+"/api/v1/admin/users/invalid", getTestJWT(), "")
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+}
+
+func TestAdminUpdateUser(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+	require.NoError(t, storage.SaveUser(db.User{ID: testUserID, IsAdmin: true}))
+	require.NoError(t, storage.SaveUser(db.User{ID: 2}))
+	userURL := func(id string) string { return ts.URL + "/api/v1/admin/users/" + id }
+
+	t.Run("grant admin", func(t *testing.T) {
+		r := doRequest(t, http.MethodPatch, userURL("2"), getTestJWT(), `{"is_admin": true}`)
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		var res AdminUser
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&res))
+		assert.True(t, res.IsAdmin)
+		user, err := storage.GetUser(2)
+		require.NoError(t, err)
+		assert.True(t, user.IsAdmin)
+	})
+	t.Run("block admin", func(t *testing.T) {
+		r := doRequest(t, http.MethodPatch, userURL("2"), getTestJWT(), `{"blocked": true}`)
+		assert.Equal(t, http.StatusConflict, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"admin can't be blocked"}`, string(body))
+	})
+	t.Run("revoke admin and block", func(t *testing.T) {
+		r := doRequest(t, http.MethodPatch, userURL("2"), getTestJWT(), `{"is_admin": false, "blocked": true}`)
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		user, err := storage.GetUser(2)
+		require.NoError(t, err)
+		assert.False(t, user.IsAdmin)
+		assert.True(t, user.Blocked)
+	})
+	t.Run("grant admin and block", func(t *testing.T) {
+		r := doRequest(t, http.MethodPatch, userURL("2"), getTestJWT(), `{"is_admin": true, "blocked": false}`)
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		r = doRequest(t, http.MethodPatch, userURL("2"), getTestJWT(), `{"is_admin": false, "blocked": true}`)
+		require.Equal(t, http.StatusOK, r.StatusCode)
+
+		r = doRequest(t, http.MethodPatch, userURL("2"), getTestJWT(), `{"is_admin": true, "blocked": true}`)
+		assert.Equal(t, http.StatusConflict, r.StatusCode)
+		user, err := storage.GetUser(2)
+		require.NoError(t, err)
+		assert.False(t, user.IsAdmin)
+		assert.True(t, user.Blocked)
+	})
+	t.Run("self", func(t *testing.T) {
+		r := doRequest(t, http.MethodPatch, userURL("1"), getTestJWT(), `{"is_admin": false}`)
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"can't update yourself"}`, string(body))
+	})
+	t.Run("not found", func(t *testing.T) {
+		r := doRequest(t, http.MethodPatch, userURL("3"), getTestJWT(), `{"is_admin": true}`)
+		assert.Equal(t, http.StatusNotFound, r.StatusCode)
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		r := doRequest(t, http.MethodPatch, userURL("2"), getTestJWT(), "invalid")
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+}
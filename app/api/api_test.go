@@ -1,11 +1,15 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"time"
 
 	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/lookup"
 )
 
 const (
@@ -28,6 +32,10 @@ func (d ErrorStorage) GetUserDictionary(db.UserID) (map[db.UserDictionaryItem]db
 	return nil, errors.New("test")
 }
 
+func (d ErrorStorage) IterateUserDictionary(db.UserID, func(db.UserDictionaryItem, db.DictionaryItem) error) error {
+	return errors.New("test")
+}
+
 func (d ErrorStorage) Get(string) (db.DictionaryItem, error) {
 	return db.DictionaryItem{}, errors.New("test")
 }
@@ -37,19 +45,54 @@ func (d ErrorStorage) Save(item db.DictionaryItem) error {
 
 }
 
+// stubLookuper is a dummy lookuper knowing only the words with "known" prefix.
+type stubLookuper struct{}
+
+func (l stubLookuper) Get(_ context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	if !strings.HasPrefix(word, "known") {
+		return nil, nil
+	}
+	item := db.DictionaryItem{Word: word}
+	return &item, storage.Save(item)
+}
+
+func (l stubLookuper) GetNormalized(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	return l.Get(ctx, word, storage)
+}
+
+func (l stubLookuper) Preview(_ context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	if !strings.HasPrefix(word, "known") {
+		return nil, nil
+	}
+	return &db.DictionaryItem{Word: word}, nil
+}
+
+func (l stubLookuper) Refresh(_ context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	if word == "changed" {
+		return nil, lookup.ErrChanged
+	}
+	item, err := storage.Get(word)
+	if err != nil {
+		return nil, err
+	}
+	fetchedAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	item.FetchedAt, item.Incomplete = &fetchedAt, nil
+	return &item, storage.Save(item)
+}
+
 // getTestServer returns a test server.
 func getTestServer(storage db.Storage) (*httptest.Server, func()) {
 	if storage == nil {
 		storage = db.NewInMemoryStorage()
 	}
 
-	server := NewServer(storage, testTGToken, testJWTSecret)
+	server := NewServer(storage, stubLookuper{}, testTGToken, testJWTSecret)
 	srv := httptest.NewServer(server.router)
 	return srv, srv.Close
 }
 
 // getTestJWT returns a test JWT signed with testJWTSecret
 func getTestJWT() string {
-	token, _ := (&authService{telegramToken: testTGToken, jwtSecret: []byte(testJWTSecret)}).createToken(testUserID)
+	token, _, _ := (&authService{telegramToken: testTGToken, jwtSecret: []byte(testJWTSecret)}).createToken(testUserID, "")
 	return "Bearer " + token
 }
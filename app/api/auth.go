@@ -1,14 +1,15 @@
 package api
 
 import (
-	"bytes"
 	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
@@ -19,90 +20,158 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-// JWTClaims custom claims with user id
+// accessTokenTTL is a lifetime of access JWT tokens
+const accessTokenTTL = time.Hour
+
+// JWTClaims custom claims with user id and refresh session id, token ID is kept in jti claim
 type JWTClaims struct {
-	User *int64 `json:"user"`
+	User    *int64 `json:"user"`
+	Session string `json:"sid,omitempty"`
 	jwt.StandardClaims
 }
 
 // AuthResponse response for authentication
 type AuthResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 // authService implements methods for API authentication
 type authService struct {
 	telegramToken string
 	jwtSecret     []byte
+	storage       db.Storage
 }
 
-// createToken creates JWT token
-func (s *authService) createToken(userID int64) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{
-		User: &userID,
+// createToken creates access JWT token with a new ID, session may be empty
+func (s *authService) createToken(userID int64, session string) (string, JWTClaims, error) {
+	now := time.Now().UTC()
+	claims := JWTClaims{
+		User:    &userID,
+		Session: session,
 		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: time.Now().UTC().Add(time.Hour * 24).Unix(),
-			NotBefore: time.Now().UTC().Unix(),
+			Id:        db.GenerateID(),
+			ExpiresAt: now.Add(accessTokenTTL).Unix(),
+			NotBefore: now.Unix(),
 		},
-	})
-	tokenStr, err := token.SignedString(s.jwtSecret)
+	}
+	tokenStr, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
 	if err != nil {
-		return "", fmt.Errorf("signing token: %w", err)
+		return "", claims, fmt.Errorf("signing token: %w", err)
 	}
-	return tokenStr, nil
+	return tokenStr, claims, nil
 }
 
-// TelegramRedirectHandler handles authentication after Telegram redirect
-func (s *authService) TelegramRedirectHandler(w http.ResponseWriter, r *http.Request) {
-	// validate request
-	secretKey := sha256.Sum256([]byte(s.telegramToken))
-	keys := []string{}
-	query := r.URL.Query()
-	for key := range query {
-		keys = append(keys, key)
+// authMaxAge limits age of authentication data signed by telegram
+const authMaxAge = 24 * time.Hour
+
+// webAppKey is a key used to derive secret for Mini App init data validation
+const webAppKey = "WebAppData"
+
+// telegram authentication data errors
+var (
+	errInvalidHash     = errors.New("invalid hash")
+	errInvalidAuthDate = errors.New("invalid auth_date")
+	errAuthExpired     = errors.New("auth data expired")
+)
+
+// WebAppAuthRequest is a request for Mini App authentication
+type WebAppAuthRequest struct {
+	InitData string `json:"init_data"`
+}
+
+// checkTelegramData validates authentication data signed by telegram:
+// hash should be HMAC-SHA256 of sorted key=value pairs joined by new lines signed with the secret,
+// and auth_date should not be older than authMaxAge
+func checkTelegramData(values url.Values, secret []byte, now time.Time) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		if key != "hash" {
+			keys = append(keys, key)
+		}
 	}
 	sort.Strings(keys)
-	var data bytes.Buffer
+	pairs := make([]string, 0, len(keys))
 	for _, key := range keys {
-		if key == "hash" {
-			continue
-		}
-		values := query[key]
-		for _, val := range values {
-			data.Write([]byte(fmt.Sprintf("%s=%s", key, val)))
-		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, values.Get(key)))
 	}
-	h := hmac.New(sha256.New, secretKey[:])
-	h.Write(data.Bytes())
-	hash := hex.EncodeToString(h.Sum(nil))
-	if hash != r.URL.Query().Get("hash") {
-		w.WriteHeader(http.StatusUnauthorized)
-		if _, err := w.Write([]byte("unauthorized")); err != nil {
-			log.Warn().Err(err).Msg("failed to write response")
-		}
-		return
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(strings.Join(pairs, "\n")))
+	expected := hex.EncodeToString(h.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(values.Get("hash"))) {
+		return errInvalidHash
 	}
-	userID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	authDate, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
 	if err != nil {
-		log.Error().Err(err).Str("userID", r.URL.Query().Get("id")).Msg("failed to parse user id")
-		w.WriteHeader(http.StatusBadRequest)
-		if _, err := w.Write([]byte("invalid ID")); err != nil {
-			log.Warn().Err(err).Msg("failed to write response")
-		}
-		return
+		return errInvalidAuthDate
 	}
+	if now.Sub(time.Unix(authDate, 0)) > authMaxAge {
+		return errAuthExpired
+	}
+	return nil
+}
+
+// loginWidgetSecret returns secret used to sign Login Widget data
+func (s *authService) loginWidgetSecret() []byte {
+	secret := sha256.Sum256([]byte(s.telegramToken))
+	return secret[:]
+}
+
+// webAppSecret returns secret used to sign Mini App init data
+func (s *authService) webAppSecret() []byte {
+	h := hmac.New(sha256.New, []byte(webAppKey))
+	h.Write([]byte(s.telegramToken))
+	return h.Sum(nil)
+}
 
-	// create JWT token
-	token, err := s.createToken(userID)
+// writeUnauthorized writes unauthorized response
+func writeUnauthorized(w http.ResponseWriter) {
+	writeError(w, http.StatusUnauthorized, "unauthorized")
+}
+
+// writeForbidden writes forbidden response
+func writeForbidden(w http.ResponseWriter) {
+	writeError(w, http.StatusForbidden, "forbidden")
+}
+
+// checkNotBlocked writes forbidden response and returns false if the user is blocked,
+// unknown users aren't blocked
+func (s *authService) checkNotBlocked(w http.ResponseWriter, userID db.UserID) bool {
+	user, err := s.storage.GetUser(userID)
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		return true
+	case err != nil:
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to get user")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return false
+	case user.Blocked:
+		writeError(w, http.StatusForbidden, "user is blocked")
+		return false
+	}
+	return true
+}
+
+// writeToken starts refresh session for the user and writes its tokens to response
+func (s *authService) writeToken(w http.ResponseWriter, userID int64) {
+	if !s.checkNotBlocked(w, db.UserID(userID)) {
+		return
+	}
+	resp, err := s.startSession(userID)
 	if err != nil {
-		log.Error().Err(err).Msg("failed to create token")
-		w.WriteHeader(http.StatusInternalServerError)
+		log.Error().Err(err).Int64("user", userID).Msg("failed to start session")
+		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
-	jdata, jerr := json.Marshal(AuthResponse{Token: token})
+	s.writeAuthResponse(w, resp)
+}
+
+// writeAuthResponse writes tokens to response
+func (s *authService) writeAuthResponse(w http.ResponseWriter, resp AuthResponse) {
+	jdata, jerr := json.Marshal(resp)
 	if jerr != nil {
 		log.Error().Err(jerr).Msg("failed to marshal json")
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 	if _, err := w.Write(jdata); err != nil {
@@ -110,7 +179,52 @@ func (s *authService) TelegramRedirectHandler(w http.ResponseWriter, r *http.Req
 	}
 }
 
-// UserCtx checks authorization token and adds user to context
+// TelegramRedirectHandler handles authentication after Telegram Login Widget redirect
+func (s *authService) TelegramRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if err := checkTelegramData(query, s.loginWidgetSecret(), time.Now()); err != nil {
+		log.Debug().Err(err).Msg("invalid login widget data")
+		writeUnauthorized(w)
+		return
+	}
+	userID, err := strconv.ParseInt(query.Get("id"), 10, 64)
+	if err != nil {
+		log.Error().Err(err).Str("userID", query.Get("id")).Msg("failed to parse user id")
+		writeError(w, http.StatusBadRequest, "invalid ID")
+		return
+	}
+	s.writeToken(w, userID)
+}
+
+// WebAppHandler handles authentication of Telegram Mini App by its init data
+func (s *authService) WebAppHandler(w http.ResponseWriter, r *http.Request) {
+	var req WebAppAuthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	values, err := url.ParseQuery(req.InitData)
+	if err != nil {
+		writeUnauthorized(w)
+		return
+	}
+	if err := checkTelegramData(values, s.webAppSecret(), time.Now()); err != nil {
+		log.Debug().Err(err).Msg("invalid web app init data")
+		writeUnauthorized(w)
+		return
+	}
+	var user struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(values.Get("user")), &user); err != nil || user.ID == 0 {
+		writeError(w, http.StatusBadRequest, "invalid user")
+		return
+	}
+	s.writeToken(w, user.ID)
+}
+
+// UserCtx checks authorization token and adds user and its scope to context.
+// Both access JWT tokens and personal access tokens are accepted, JWT claims are added to context too
 func (s *authService) UserCtx(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestToken := r.Header.Get("Authorization")
@@ -119,40 +233,109 @@ func (s *authService) UserCtx(next http.Handler) http.Handler {
 		}
 		requestToken = strings.Replace(requestToken, "Bearer ", "", 1)
 		if requestToken == "" {
-			w.WriteHeader(http.StatusUnauthorized)
-			if _, err := w.Write([]byte("unauthorized")); err != nil {
-				log.Warn().Err(err).Msg("failed to write response")
-			}
+			writeUnauthorized(w)
+			return
+		}
+		if _, ok := db.ParsePersonalToken(requestToken); ok {
+			s.personalTokenCtx(next, w, r, requestToken)
 			return
 		}
 		token, err := jwt.ParseWithClaims(requestToken, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 			return s.jwtSecret, nil
 		})
 		if err != nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			if _, err := w.Write([]byte("unauthorized")); err != nil {
-				log.Warn().Err(err).Msg("failed to write response")
-			}
+			writeUnauthorized(w)
 			return
 		}
 
 		claims := token.Claims.(*JWTClaims)
-		if claims.User == nil {
-			w.WriteHeader(http.StatusUnauthorized)
-			if _, err := w.Write([]byte("unauthorized")); err != nil {
-				log.Warn().Err(err).Msg("failed to write response")
-			}
+		now := time.Now().Unix()
+		if claims.User == nil || claims.Id == "" || claims.NotBefore > now || claims.ExpiresAt < now {
+			writeUnauthorized(w)
 			return
 		}
-		now := time.Now().Unix()
-		if claims.NotBefore > now || claims.ExpiresAt < now {
-			w.WriteHeader(http.StatusUnauthorized)
-			if _, err := w.Write([]byte("unauthorized")); err != nil {
-				log.Warn().Err(err).Msg("failed to write response")
-			}
+		revoked, err := s.storage.IsTokenRevoked(claims.Id)
+		if err != nil {
+			log.Error().Err(err).Msg("failed to check revoked token")
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		if revoked {
+			writeUnauthorized(w)
+			return
+		}
+		// access tokens issued before the user was blocked are rejected too
+		if !s.checkNotBlocked(w, db.UserID(*claims.User)) {
 			return
 		}
 		ctx := context.WithValue(r.Context(), ctxUserIDKey, db.UserID(*claims.User))
+		ctx = context.WithValue(ctx, ctxScopeKey, db.ScopeWrite)
+		ctx = context.WithValue(ctx, ctxClaimsKey, *claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// personalTokenCtx checks personal access token and its scope,
+// read only tokens are allowed to make only GET and HEAD requests
+func (s *authService) personalTokenCtx(next http.Handler, w http.ResponseWriter, r *http.Request, raw string) {
+	id, _ := db.ParsePersonalToken(raw)
+	token, err := s.storage.GetPersonalToken(id)
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			log.Error().Err(err).Msg("failed to get personal token")
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		writeUnauthorized(w)
+		return
+	}
+	if !token.Check(raw) {
+		writeUnauthorized(w)
+		return
+	}
+	if !s.checkNotBlocked(w, token.User) {
+		return
+	}
+	if !token.IsWritable() && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeForbidden(w)
+		return
+	}
+	ctx := context.WithValue(r.Context(), ctxUserIDKey, token.User)
+	ctx = context.WithValue(ctx, ctxScopeKey, token.Scope)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// SessionOnly allows only requests authorized by access JWT token,
+// so personal access tokens can't manage credentials. Should be used after UserCtx
+func (s *authService) SessionOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(ctxClaimsKey).(JWTClaims); !ok {
+			writeForbidden(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// AdminOnly allows only requests of admin users. Should be used after UserCtx
+func (s *authService) AdminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+		if !ok {
+			log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		user, err := s.storage.GetUser(userID)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to get user")
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		if !user.IsAdmin || user.Blocked {
+			writeForbidden(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
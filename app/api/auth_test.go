@@ -1,28 +1,37 @@
 package api
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt"
+	"github.com/rbhz/tg-dictionary/app/db"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestRedirectTelegramHandler(t *testing.T) {
 	const path = "/api/v1/auth/telegram"
+	s := &authService{telegramToken: testTGToken}
 	requestParams := map[string]string{
 		"id":         "1",
 		"first_name": "John",
 		"username":   "jDoe",
 		"photo_url":  "http://test.com/image.png",
-		"auth_date":  "1653049612",
-		"hash":       "7403f73546fca556ae1a79942e7ea43d593052fde2ee71f122785c4cbaf53c27",
+		"auth_date":  strconv.FormatInt(time.Now().Unix(), 10),
 	}
+	requestParams["hash"] = signTelegramData(requestParams, s.loginWidgetSecret())
 	paramsOrder := []string{"id", "first_name", "username", "photo_url", "auth_date", "hash"}
 	client := &http.Client{
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -30,6 +39,7 @@ func TestRedirectTelegramHandler(t *testing.T) {
 		},
 	}
 	checkValidToken := func(t *testing.T, token string) {
+		t.Helper()
 		parsedToken, err := jwt.ParseWithClaims(token, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 			return []byte(testJWTSecret), nil
 		})
@@ -84,6 +94,25 @@ func TestRedirectTelegramHandler(t *testing.T) {
 		assert.NotEmpty(t, rData.Token)
 		checkValidToken(t, rData.Token)
 	})
+	t.Run("blocked user", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		require.NoError(t, storage.SaveUser(db.User{ID: 1, Blocked: true}))
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		require.NoError(t, err)
+		q := req.URL.Query()
+		for _, p := range paramsOrder {
+			q.Add(p, requestParams[p])
+		}
+		req.URL.RawQuery = q.Encode()
+		r, err := client.Do(req)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"user is blocked"}`, string(body))
+	})
 	t.Run("invalid hash", func(t *testing.T) {
 		ts, cancel := getTestServer(nil)
 		defer cancel()
@@ -103,7 +132,28 @@ func TestRedirectTelegramHandler(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
 		body, err := io.ReadAll(r.Body)
 		require.NoError(t, err)
-		assert.Equal(t, "unauthorized", string(body))
+		assert.JSONEq(t, `{"error":"unauthorized"}`, string(body))
+	})
+	t.Run("expired auth date", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		params := make(map[string]string, len(requestParams))
+		for k, v := range requestParams {
+			params[k] = v
+		}
+		params["auth_date"] = strconv.FormatInt(time.Now().Add(-authMaxAge-time.Minute).Unix(), 10)
+		delete(params, "hash")
+		params["hash"] = signTelegramData(params, s.loginWidgetSecret())
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		require.NoError(t, err)
+		q := req.URL.Query()
+		for _, p := range paramsOrder {
+			q.Add(p, params[p])
+		}
+		req.URL.RawQuery = q.Encode()
+		r, err := client.Do(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
 	})
 	t.Run("missing id", func(t *testing.T) {
 		ts, cancel := getTestServer(nil)
@@ -122,7 +172,7 @@ func TestRedirectTelegramHandler(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
 		body, err := io.ReadAll(r.Body)
 		require.NoError(t, err)
-		assert.Equal(t, "unauthorized", string(body))
+		assert.JSONEq(t, `{"error":"unauthorized"}`, string(body))
 
 	})
 	t.Run("invalid id", func(t *testing.T) {
@@ -144,8 +194,123 @@ func TestRedirectTelegramHandler(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
 		body, err := io.ReadAll(r.Body)
 		require.NoError(t, err)
-		assert.Equal(t, "unauthorized", string(body))
+		assert.JSONEq(t, `{"error":"unauthorized"}`, string(body))
+	})
+}
+
+func TestWebAppHandler(t *testing.T) {
+	const path = "/api/v1/auth/webapp"
+	s := &authService{telegramToken: testTGToken}
+	initData := func(params map[string]string, secret []byte) string {
+		params["hash"] = signTelegramData(params, secret)
+		values := url.Values{}
+		for k, v := range params {
+			values.Set(k, v)
+		}
+		return values.Encode()
+	}
+	validParams := func() map[string]string {
+		return map[string]string{
+			"query_id":  "AAHdF6IQAAAAAN0XohDhrOrc",
+			"user":      `{"id":1,"first_name":"John","username":"jDoe","language_code":"en"}`,
+			"auth_date": strconv.FormatInt(time.Now().Unix(), 10),
+		}
+	}
+	post := func(t *testing.T, body string) *http.Response {
+		t.Helper()
+		ts, cancel := getTestServer(nil)
+		t.Cleanup(cancel)
+		r, err := http.Post(ts.URL+path, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		t.Cleanup(func() { r.Body.Close() })
+		return r
+	}
+	postInitData := func(t *testing.T, data string) *http.Response {
+		t.Helper()
+		body, err := json.Marshal(WebAppAuthRequest{InitData: data})
+		require.NoError(t, err)
+		return post(t, string(body))
+	}
+
+	t.Run("success", func(t *testing.T) {
+		r := postInitData(t, initData(validParams(), s.webAppSecret()))
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+		var rData AuthResponse
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&rData))
+		assert.NotEmpty(t, rData.RefreshToken)
+		parsedToken, err := jwt.ParseWithClaims(rData.Token, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+			return []byte(testJWTSecret), nil
+		})
+		require.NoError(t, err)
+		claims, ok := parsedToken.Claims.(*JWTClaims)
+		require.True(t, ok)
+		require.NotNil(t, claims.User)
+		assert.Equal(t, int64(1), *claims.User)
+	})
+	t.Run("login widget secret", func(t *testing.T) {
+		r := postInitData(t, initData(validParams(), s.loginWidgetSecret()))
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	})
+	t.Run("tampered data", func(t *testing.T) {
+		data := initData(validParams(), s.webAppSecret())
+		data = strings.Replace(data, "%22id%22%3A1", "%22id%22%3A2", 1)
+		r := postInitData(t, data)
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	})
+	t.Run("expired auth date", func(t *testing.T) {
+		params := validParams()
+		params["auth_date"] = strconv.FormatInt(time.Now().Add(-authMaxAge-time.Minute).Unix(), 10)
+		r := postInitData(t, initData(params, s.webAppSecret()))
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
 	})
+	t.Run("missing auth date", func(t *testing.T) {
+		params := validParams()
+		delete(params, "auth_date")
+		r := postInitData(t, initData(params, s.webAppSecret()))
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	})
+	t.Run("invalid user", func(t *testing.T) {
+		params := validParams()
+		params["user"] = `{"first_name":"John"}`
+		r := postInitData(t, initData(params, s.webAppSecret()))
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		r := post(t, "invalid")
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+}
+
+func TestCheckTelegramData(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	params := map[string]string{"id": "1", "auth_date": strconv.FormatInt(now.Add(-time.Hour).Unix(), 10)}
+	params["hash"] = signTelegramData(params, secret)
+	values := url.Values{}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	assert.NoError(t, checkTelegramData(values, secret, now))
+	assert.ErrorIs(t, checkTelegramData(values, []byte("other"), now), errInvalidHash)
+	assert.ErrorIs(t, checkTelegramData(values, secret, now.Add(authMaxAge)), errAuthExpired)
+}
+
+// signTelegramData returns hash of the data as telegram signs it
+func signTelegramData(params map[string]string, secret []byte) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if k != "hash" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+params[k])
+	}
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(strings.Join(pairs, "\n")))
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 func TestUserCtxMiddleware(t *testing.T) {
@@ -156,7 +321,8 @@ func TestUserCtxMiddleware(t *testing.T) {
 		http.MethodDelete,
 		http.MethodPatch,
 	}
-	s := &authService{telegramToken: testTGToken, jwtSecret: []byte(testJWTSecret)}
+	storage := db.NewInMemoryStorage()
+	s := &authService{telegramToken: testTGToken, jwtSecret: []byte(testJWTSecret), storage: storage}
 	handler := s.UserCtx(&emptyHandler{})
 
 	checkSuccess := func(t *testing.T, header string) {
@@ -174,20 +340,34 @@ func TestUserCtxMiddleware(t *testing.T) {
 		for _, method := range methods {
 			req, err := http.NewRequest(method, "/", nil)
 			require.NoError(t, err)
+			req.Header.Add("Authorization", header)
 			recorder := httptest.NewRecorder()
 			handler.ServeHTTP(recorder, req)
 			r := recorder.Result()
 			assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
 			body, err := io.ReadAll(r.Body)
 			require.NoError(t, err)
-			assert.Equal(t, "unauthorized", string(body))
+			assert.JSONEq(t, `{"error":"unauthorized"}`, string(body))
 		}
 	}
 	t.Run("success", func(t *testing.T) {
-		testJWT, err := s.createToken(1)
+		testJWT, _, err := s.createToken(1, "")
 		require.NoError(t, err)
 		checkSuccess(t, "Bearer "+testJWT)
 	})
+	t.Run("blocked user", func(t *testing.T) {
+		testJWT, _, err := s.createToken(1, "")
+		require.NoError(t, err)
+		require.NoError(t, storage.SaveUser(db.User{ID: 1, Blocked: true}))
+		defer func() { require.NoError(t, storage.SaveUser(db.User{ID: 1})) }()
+		req, err := http.NewRequest(http.MethodGet, "/", nil)
+		require.NoError(t, err)
+		req.Header.Add("Authorization", "Bearer "+testJWT)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.JSONEq(t, `{"error":"user is blocked"}`, recorder.Body.String())
+	})
 	t.Run("without header", func(t *testing.T) {
 		checkError(t, "")
 	})
@@ -195,12 +375,12 @@ func TestUserCtxMiddleware(t *testing.T) {
 		checkError(t, "Bearer invalidJWT")
 	})
 	t.Run("invalid prefix", func(t *testing.T) {
-		testJWT, err := s.createToken(1)
+		testJWT, _, err := s.createToken(1, "")
 		require.NoError(t, err)
 		checkError(t, "Invalid "+testJWT)
 	})
 	t.Run("invalid JWT sign", func(t *testing.T) {
-		testJWT, err := (&authService{telegramToken: testTGToken, jwtSecret: []byte(testJWTSecret + "1")}).createToken(1)
+		testJWT, _, err := (&authService{telegramToken: testTGToken, jwtSecret: []byte(testJWTSecret + "1")}).createToken(1, "")
 		require.NoError(t, err)
 		checkError(t, "Bearer "+testJWT)
 	})
@@ -259,4 +439,24 @@ func TestUserCtxMiddleware(t *testing.T) {
 		require.NoError(t, err)
 		checkError(t, "Bearer "+testJWT)
 	})
+	t.Run("revoked JWT", func(t *testing.T) {
+		testJWT, claims, err := s.createToken(1, "")
+		require.NoError(t, err)
+		checkSuccess(t, "Bearer "+testJWT)
+		require.NoError(t, storage.RevokeToken(claims.Id, time.Unix(claims.ExpiresAt, 0)))
+		checkError(t, "Bearer "+testJWT)
+	})
+	t.Run("without JWT ID", func(t *testing.T) {
+		userID := int64(1)
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, JWTClaims{
+			User: &userID,
+			StandardClaims: jwt.StandardClaims{
+				ExpiresAt: time.Now().UTC().Add(time.Hour).Unix(),
+				NotBefore: time.Now().UTC().Unix(),
+			},
+		})
+		testJWT, err := token.SignedString(s.jwtSecret)
+		require.NoError(t, err)
+		checkError(t, "Bearer "+testJWT)
+	})
 }
@@ -1,48 +1,336 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/export"
+	"github.com/rbhz/tg-dictionary/app/importer"
+	"github.com/rbhz/tg-dictionary/app/lookup"
 	"github.com/rs/zerolog/log"
 )
 
+// Phonetics represents word pronunciation in API response
+type Phonetics struct {
+	Text  string `json:"text"`
+	Audio string `json:"audio"`
+}
+
+// Meaning represents a single definition of the word in API response
+type Meaning struct {
+	PartOfSpeech string   `json:"part_of_speech"`
+	Definition   string   `json:"definition"`
+	Examples     []string `json:"examples"`
+	Synonyms     []string `json:"synonyms"`
+	Antonyms     []string `json:"antonyms"`
+}
+
+// Translation represents a single translation of the word in API response
+type Translation struct {
+	Text         string `json:"text"`
+	Audio        string `json:"audio"`
+	Language     string `json:"language"`
+	PartOfSpeech string `json:"part_of_speech"`
+}
+
+// Word represents dictionary item in API requests and responses
+type Word struct {
+	Word         string        `json:"word"`
+	Phonetics    Phonetics     `json:"phonetics"`
+	Meanings     []Meaning     `json:"meanings"`
+	Translations []Translation `json:"translations"`
+	// EditedBy is an ID of the admin who edited the word last, it's omitted for data fetched from providers
+	EditedBy int64 `json:"edited_by,omitempty"`
+	// FetchedAt and Incomplete are omitted for words saved before they were kept
+	FetchedAt  *time.Time `json:"fetched_at,omitempty"`
+	Incomplete []string   `json:"incomplete,omitempty"`
+}
+
+// WordRevision represents a version of the word from its edit history in API response
+type WordRevision struct {
+	Rev          int       `json:"rev"`
+	Author       int64     `json:"author"`
+	Created      time.Time `json:"created"`
+	RevertedFrom int       `json:"reverted_from"`
+	Diff         []string  `json:"diff"`
+	Word         Word      `json:"word"`
+}
+
+// UserItem represents user specific data of dictionary item in API response
+type UserItem struct {
+	Word     string     `json:"word"`
+	Created  time.Time  `json:"created"`
+	LastQuiz *time.Time `json:"last_quiz"`
+	// Translations, Notes and Examples are added by the user
+	Translations []string `json:"translations"`
+	Notes        string   `json:"notes"`
+	Examples     []string `json:"examples"`
+	Tags         []string `json:"tags"`
+}
+
 // UserDictionaryItem represents user dictionary item in API response
 type UserDictionaryItem struct {
-	Word     db.DictionaryItem
-	UserItem db.UserDictionaryItem
+	Word     Word     `json:"word"`
+	UserItem UserItem `json:"user_item"`
+}
+
+// DictionaryPage represents page of user dictionary in API response,
+// NextCursor is passed as cursor query parameter to get the next page and is empty for the last one
+type DictionaryPage struct {
+	Items      []UserDictionaryItem `json:"items"`
+	Total      int                  `json:"total"`
+	NextCursor string               `json:"next_cursor"`
+}
+
+// importMaxBodySize limits size of imported file
+const importMaxBodySize = 1 << 20
+
+// importTimeout limits time of words lookup on import, words left are reported as skipped
+const importTimeout = time.Minute
+
+// UserItemUpdate holds user specific item data to change, nil fields are kept
+type UserItemUpdate struct {
+	Translations *[]string `json:"translations"`
+	Notes        *string   `json:"notes"`
+	Examples     *[]string `json:"examples"`
+	Tags         *[]string `json:"tags"`
+}
+
+// TagCount represents user tag with number of tagged items in API response
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// ImportResult represents result of a single record import in API response
+type ImportResult struct {
+	Word         string          `json:"word"`
+	Status       importer.Status `json:"status"`
+	Translations []string        `json:"translations"`
+	// Existing holds current user translations for conflicts
+	Existing []string `json:"existing"`
+}
+
+// ImportReport represents results of dictionary import in API response
+type ImportReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Results []ImportResult `json:"results"`
+}
+
+// nonNil returns empty slice instead of nil, so lists are never null in responses
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+func newWord(item db.DictionaryItem) Word {
+	res := Word{
+		Word:         item.Word,
+		Phonetics:    Phonetics{Text: item.Phonetics.Text, Audio: item.Phonetics.Audio},
+		Meanings:     make([]Meaning, 0, len(item.Meanings)),
+		Translations: make([]Translation, 0, len(item.Translations)),
+		EditedBy:     int64(item.EditedBy),
+		FetchedAt:    item.FetchedAt,
+		Incomplete:   item.Incomplete,
+	}
+	for _, m := range item.Meanings {
+		res.Meanings = append(res.Meanings, Meaning{
+			PartOfSpeech: m.PartOfSpeech,
+			Definition:   m.Definition,
+			Examples:     nonNil(m.Examples),
+			Synonyms:     nonNil(m.Synonyms),
+			Antonyms:     nonNil(m.Antonyms),
+		})
+	}
+	for _, t := range item.Translations {
+		res.Translations = append(res.Translations, Translation{
+			Text: t.Text, Audio: t.Audio, Language: t.Language, PartOfSpeech: t.PartOfSpeech,
+		})
+	}
+	return res
+}
+
+func newWordRevision(rev db.WordRevision) WordRevision {
+	return WordRevision{
+		Rev:          rev.Rev,
+		Author:       int64(rev.Author),
+		Created:      rev.Created,
+		RevertedFrom: rev.RevertedFrom,
+		Diff:         nonNil(rev.Diff),
+		Word:         newWord(rev.Item),
+	}
+}
+
+// dictionaryItem converts word back to dictionary item
+func (w Word) dictionaryItem() db.DictionaryItem {
+	item := db.DictionaryItem{Word: w.Word}
+	item.Phonetics.Text, item.Phonetics.Audio = w.Phonetics.Text, w.Phonetics.Audio
+	for _, m := range w.Meanings {
+		item.Meanings = append(item.Meanings, db.Meaning{
+			PartOfSpeech: m.PartOfSpeech,
+			Definition:   m.Definition,
+			Examples:     m.Examples,
+			Synonyms:     m.Synonyms,
+			Antonyms:     m.Antonyms,
+		})
+	}
+	for _, t := range w.Translations {
+		item.Translations = append(item.Translations, db.Translation{
+			Text: t.Text, Audio: t.Audio, Language: t.Language, PartOfSpeech: t.PartOfSpeech,
+		})
+	}
+	return item
+}
+
+func newUserItem(userItem db.UserDictionaryItem) UserItem {
+	custom := userItem.CustomData()
+	return UserItem{
+		Word:         userItem.Word,
+		Created:      userItem.Created,
+		LastQuiz:     userItem.LastQuiz,
+		Translations: nonNil(custom.Translations),
+		Notes:        custom.Notes,
+		Examples:     nonNil(custom.Examples),
+		Tags:         nonNil(userItem.Tags()),
+	}
+}
+
+func newUserDictionaryItem(userItem db.UserDictionaryItem, item db.DictionaryItem) UserDictionaryItem {
+	return UserDictionaryItem{Word: newWord(item), UserItem: newUserItem(userItem)}
+}
+
+func newImportReport(report importer.Report) ImportReport {
+	res := ImportReport{DryRun: report.DryRun, Results: make([]ImportResult, 0, len(report.Results))}
+	for _, r := range report.Results {
+		res.Results = append(res.Results, ImportResult{
+			Word:         r.Word,
+			Status:       r.Status,
+			Translations: nonNil(r.Translations),
+			Existing:     nonNil(r.Existing),
+		})
+	}
+	return res
+}
+
+// getQueryTags returns normalized tags passed as tag query parameters
+func getQueryTags(r *http.Request) ([]string, error) {
+	values := r.URL.Query()["tag"]
+	tags := make([]string, 0, len(values))
+	for _, v := range values {
+		tag, err := db.NormalizeTag(v)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// getDictionaryQuery returns user dictionary query from pos, lang, created_from, created_to, prefix,
+// sort, order, cursor and limit query parameters, dates are RFC 3339 timestamps or YYYY-MM-DD dates
+func getDictionaryQuery(r *http.Request) (db.DictionaryQuery, error) {
+	values := r.URL.Query()
+	query := db.DictionaryQuery{
+		PartOfSpeech: values.Get("pos"),
+		Language:     values.Get("lang"),
+		Prefix:       values.Get("prefix"),
+		Sort:         db.DictionarySort(values.Get("sort")),
+		Cursor:       values.Get("cursor"),
+	}
+	var err error
+	if query.CreatedFrom, err = parseQueryTime(values.Get("created_from")); err != nil {
+		return query, errors.New("invalid created_from")
+	}
+	if query.CreatedTo, err = parseQueryTime(values.Get("created_to")); err != nil {
+		return query, errors.New("invalid created_to")
+	}
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, errors.New("invalid order")
+	}
+	if limit := values.Get("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit <= 0 || query.Limit > db.MaxPageLimit {
+			return query, errors.New("invalid limit")
+		}
+	}
+	return query, nil
+}
+
+// parseQueryTime parses RFC 3339 timestamp or YYYY-MM-DD date, empty value is zero time
+func parseQueryTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// writeInvalidTag writes bad request response for invalid tags
+func writeInvalidTag(w http.ResponseWriter) {
+	writeError(w, http.StatusBadRequest, "invalid tag")
 }
 
 // dictionaryService implements methods for dictionary API
 type dictionaryService struct {
 	storage db.Storage
+	lookup  lookup.Lookuper
 }
 
-// GetUserDictionary returns user dictionary
+// GetUserDictionary returns page of user dictionary,
+// items can be filtered, sorted and paginated with query parameters parsed by getDictionaryQuery
 func (d dictionaryService) GetUserDictionary(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
 	if !ok {
 		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
-	dictionary, err := d.storage.GetUserDictionary(userID)
+	tags, err := getQueryTags(r)
 	if err != nil {
+		writeInvalidTag(w)
+		return
+	}
+	query, err := getDictionaryQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	query.Tags = tags
+	page, err := db.QueryUserDictionary(d.storage, userID, query)
+	if err != nil {
+		if errors.Is(err, db.ErrInvalidCursor) || errors.Is(err, db.ErrInvalidSort) {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to get user dictionary")
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
-	dict := make([]UserDictionaryItem, 0, len(dictionary))
-	for userItem, item := range dictionary {
-		dict = append(dict, UserDictionaryItem{Word: item, UserItem: userItem})
+	res := DictionaryPage{Items: make([]UserDictionaryItem, 0, len(page.Entries)), Total: page.Total, NextCursor: page.NextCursor}
+	for _, e := range page.Entries {
+		res.Items = append(res.Items, newUserDictionaryItem(e.UserItem, e.Item))
 	}
-	response, jerr := json.Marshal(dict)
+	response, jerr := json.Marshal(res)
 	if jerr != nil {
 		log.Error().Err(jerr).Int64("user", int64(userID)).Msg("failed to marshal user dictionary")
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 	if _, err := w.Write(response); err != nil {
@@ -50,25 +338,202 @@ func (d dictionaryService) GetUserDictionary(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+// GetUserTags returns user tags with number of tagged items sorted by tag
+func (d dictionaryService) GetUserTags(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	dictionary, err := d.storage.GetUserDictionary(userID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to get user dictionary")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	counts := db.TagCounts(dictionary)
+	tags := make([]TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+	response, jerr := json.Marshal(tags)
+	if jerr != nil {
+		log.Error().Err(jerr).Int64("user", int64(userID)).Msg("failed to marshal user tags")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if _, err := w.Write(response); err != nil {
+		log.Warn().Err(err).Msg("failed to write response")
+	}
+}
+
+// ExportUserDictionary returns user dictionary as a file in requested format,
+// items can be filtered by tags like in GetUserDictionary
+func (d dictionaryService) ExportUserDictionary(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	format, err := export.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "unknown format")
+		return
+	}
+	tags, err := getQueryTags(r)
+	if err != nil {
+		writeInvalidTag(w)
+		return
+	}
+	dictionary, err := d.storage.GetUserDictionary(userID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to get user dictionary")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	dictionary = db.FilterByTags(dictionary, tags)
+	buf := &bytes.Buffer{}
+	if err := export.Write(buf, format, dictionary); err != nil {
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to export user dictionary")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", format.FileName()))
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Warn().Err(err).Msg("failed to write response")
+	}
+}
+
+// ImportUserDictionary imports words from CSV/TSV request body to user dictionary,
+// file layout and import mode are passed as query parameters. Lookups are limited by importTimeout
+func (d dictionaryService) ImportUserDictionary(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	opts := importer.DefaultOptions()
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/tab-separated-values") {
+		opts.Separator = '\t'
+	}
+	for key, values := range r.URL.Query() {
+		if err := opts.Set(key, values[0]); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	records, err := importer.Parse(http.MaxBytesReader(w, r.Body, importMaxBodySize), opts)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), importTimeout)
+	defer cancel()
+	report, err := importer.Import(ctx, d.storage, d.lookup, userID, records, opts)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to import user dictionary")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	response, jerr := json.Marshal(newImportReport(report))
+	if jerr != nil {
+		log.Error().Err(jerr).Int64("user", int64(userID)).Msg("failed to marshal import report")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if _, err := w.Write(response); err != nil {
+		log.Warn().Err(err).Msg("failed to write response")
+	}
+}
+
+// UpdateUserItem changes user specific data of the item in user dictionary,
+// the item is returned with its statistics like in GetUserItem
+func (d dictionaryService) UpdateUserItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	word := chi.URLParam(r, "word")
+	var update UserItemUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	userItem, err := d.storage.GetUserItem(userID, word)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "word not found")
+			return
+		}
+		log.Error().Err(err).Str("word", word).Msg("failed to get user item")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	custom := userItem.CustomData()
+	if update.Translations != nil {
+		custom.Translations = cleanStrings(*update.Translations)
+	}
+	if update.Notes != nil {
+		custom.Notes = strings.TrimSpace(*update.Notes)
+	}
+	if update.Examples != nil {
+		custom.Examples = cleanStrings(*update.Examples)
+	}
+	userItem.SetCustom(custom)
+	if update.Tags != nil {
+		tags := make([]string, 0, len(*update.Tags))
+		for _, t := range *update.Tags {
+			tag, err := db.NormalizeTag(t)
+			if err != nil {
+				writeInvalidTag(w)
+				return
+			}
+			tags = append(tags, tag)
+		}
+		userItem.SetTags(tags)
+	}
+	if err := d.storage.SaveUserItem(userItem); err != nil {
+		log.Error().Err(err).Str("word", word).Msg("failed to save user item")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	d.writeItemDetails(w, http.StatusOK, userItem)
+}
+
+// cleanStrings trims values and removes empty ones
+func cleanStrings(values []string) []string {
+	var result []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
 // GetWord returns single word data
 func (d dictionaryService) GetWord(w http.ResponseWriter, r *http.Request) {
 	word := chi.URLParam(r, "word")
 	wordData, err := d.storage.Get(word)
 	if err != nil {
 		if errors.Is(err, db.ErrNotFound) {
-			w.WriteHeader(http.StatusNotFound)
-			if _, err := w.Write([]byte("word not found")); err != nil {
-				log.Warn().Err(err).Msg("failed to write response")
-			}
+			writeError(w, http.StatusNotFound, "word not found")
 			return
 		}
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
-	response, jerr := json.Marshal(wordData)
+	response, jerr := json.Marshal(newWord(wordData))
 	if jerr != nil {
 		log.Error().Err(jerr).Str("word", word).Msg("failed to marshal dictionary item")
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -83,34 +548,96 @@ func (d dictionaryService) UpdateWord(w http.ResponseWriter, r *http.Request) {
 	userID := uid.(db.UserID)
 	user, err := d.storage.GetUser(userID)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 	if !user.IsAdmin {
-		w.WriteHeader(http.StatusForbidden)
-		if _, err := w.Write([]byte("forbidden")); err != nil {
-			log.Warn().Err(err).Msg("failed to write response")
-		}
+		writeError(w, http.StatusForbidden, "forbidden")
 		return
 	}
-	var word db.DictionaryItem
-	if err := json.NewDecoder(r.Body).Decode(&word); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		if _, err := w.Write([]byte("invalid JSON")); err != nil {
-			log.Warn().Err(err).Msg("failed to write response")
-		}
+	var req Word
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
 		return
 	}
+	word := req.dictionaryItem()
 	if len(word.Meanings) == 0 && len(word.Translations) == 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		if _, err := w.Write([]byte("word must have at least one meaning or translation")); err != nil {
-			log.Warn().Err(err).Msg("failed to write response")
-		}
+		writeError(w, http.StatusBadRequest, "word must have at least one meaning or translation")
 		return
 	}
-	if err := d.storage.Save(word); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if _, err := db.SaveWord(d.storage, word, userID, time.Now()); err != nil {
+		log.Error().Err(err).Str("word", word.Word).Msg("failed to save word")
+		writeError(w, http.StatusInternalServerError, "internal error")
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// GetWordHistory returns word revisions, the oldest first
+func (d dictionaryService) GetWordHistory(w http.ResponseWriter, r *http.Request) {
+	word := chi.URLParam(r, "word")
+	revisions, err := d.storage.GetWordRevisions(word)
+	if err != nil {
+		log.Error().Err(err).Str("word", word).Msg("failed to get word revisions")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if len(revisions) == 0 {
+		// words saved before the history was kept have no revisions yet
+		if _, err := d.storage.Get(word); err != nil {
+			if errors.Is(err, db.ErrNotFound) {
+				writeError(w, http.StatusNotFound, "word not found")
+				return
+			}
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+	}
+	res := make([]WordRevision, 0, len(revisions))
+	for _, rev := range revisions {
+		res = append(res, newWordRevision(rev))
+	}
+	writeJSON(w, res)
+}
+
+// RevertWord restores word from the revision, the restored data is saved as a new revision
+func (d dictionaryService) RevertWord(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(ctxUserIDKey).(db.UserID)
+	word := chi.URLParam(r, "word")
+	rev, err := strconv.Atoi(chi.URLParam(r, "rev"))
+	if err != nil || rev < 1 {
+		writeError(w, http.StatusBadRequest, "invalid revision")
+		return
+	}
+	revision, err := db.RevertWord(d.storage, word, rev, userID, time.Now())
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "revision not found")
+			return
+		}
+		log.Error().Err(err).Str("word", word).Int("rev", rev).Msg("failed to revert word")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	writeJSON(w, newWordRevision(revision))
+}
+
+// RefreshWord fetches word data from dictionary providers again
+func (d dictionaryService) RefreshWord(w http.ResponseWriter, r *http.Request) {
+	word := chi.URLParam(r, "word")
+	item, err := d.lookup.Refresh(r.Context(), word, d.storage)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "word not found")
+			return
+		}
+		if errors.Is(err, lookup.ErrChanged) {
+			writeError(w, http.StatusConflict, "word is changed while refreshing")
+			return
+		}
+		log.Error().Err(err).Str("word", word).Msg("failed to refresh word")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	writeJSON(w, newWord(*item))
+}
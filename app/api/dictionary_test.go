@@ -1,10 +1,12 @@
 package api
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rbhz/tg-dictionary/app/db"
 	"github.com/stretchr/testify/assert"
@@ -29,10 +31,174 @@ func TestGetUserDictionary(t *testing.T) {
 		assert.Equal(t, http.StatusOK, r.StatusCode)
 		body, err := io.ReadAll(r.Body)
 		require.NoError(t, err)
-		expected := `[{"Word":{"Word":"test","Phonetics":{"Text":"","Audio":""},"Meanings":null,"Translations":null},` +
-			`"UserItem":{"Word":"test","User":1,"Created":"0001-01-01T00:00:00Z","LastQuiz":null}}]`
+		expected := `{"items":[{"word":{"word":"test","phonetics":{"text":"","audio":""},"meanings":[],"translations":[]},` +
+			`"user_item":{"word":"test","created":"0001-01-01T00:00:00Z","last_quiz":null,` +
+			`"translations":[],"notes":"","examples":[],"tags":[]}}],"total":1,"next_cursor":""}`
 		assert.Equal(t, expected, string(body))
 	})
+	t.Run("filter by tag", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		for _, item := range []db.UserDictionaryItem{
+			{User: db.UserID(testUserID), Word: "cat", Custom: &db.UserItemCustom{Tags: []string{"home"}}},
+			{User: db.UserID(testUserID), Word: "desk", Custom: &db.UserItemCustom{Tags: []string{"work"}}},
+			{User: db.UserID(testUserID), Word: "dog"},
+		} {
+			require.NoError(t, storage.Save(db.DictionaryItem{Word: item.Word}))
+			require.NoError(t, storage.SaveUserItem(item))
+		}
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path+"?tag=%23Work", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		expected := `{"items":[{"word":{"word":"desk","phonetics":{"text":"","audio":""},"meanings":[],"translations":[]},` +
+			`"user_item":{"word":"desk","created":"0001-01-01T00:00:00Z","last_quiz":null,` +
+			`"translations":[],"notes":"","examples":[],"tags":["work"]}}],"total":1,"next_cursor":""}`
+		assert.Equal(t, expected, string(body))
+	})
+	t.Run("pagination", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, word := range []string{"cat", "car", "dog", "cow"} {
+			require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{
+				User: db.UserID(testUserID), Word: word, Created: created.Add(time.Duration(i) * time.Hour),
+			}))
+		}
+
+		url := ts.URL + path + "?sort=created&order=desc&limit=2&created_from=2023-01-01T01:00:00Z"
+		r := doRequest(t, http.MethodGet, url, getTestJWT(), "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		var page DictionaryPage
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&page))
+		require.Len(t, page.Items, 2)
+		assert.Equal(t, "cow", page.Items[0].UserItem.Word)
+		assert.Equal(t, "dog", page.Items[1].UserItem.Word)
+		assert.Equal(t, 3, page.Total)
+		require.NotEmpty(t, page.NextCursor)
+
+		r = doRequest(t, http.MethodGet, url+"&cursor="+page.NextCursor, getTestJWT(), "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		page = DictionaryPage{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&page))
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, "car", page.Items[0].UserItem.Word)
+		assert.Empty(t, page.NextCursor)
+
+		r = doRequest(t, http.MethodGet, ts.URL+path+"?prefix=ca&created_to=2023-01-02", getTestJWT(), "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		page = DictionaryPage{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&page))
+		require.Len(t, page.Items, 2)
+		assert.Equal(t, "car", page.Items[0].UserItem.Word)
+		assert.Equal(t, "cat", page.Items[1].UserItem.Word)
+	})
+	t.Run("invalid query", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		for query, msg := range map[string]string{
+			"sort=invalid":         "invalid sort",
+			"order=up":             "invalid order",
+			"limit=0":              "invalid limit",
+			"limit=1000":           "invalid limit",
+			"created_from=invalid": "invalid created_from",
+			"created_to=1.1.2023":  "invalid created_to",
+			"cursor=invalid":       "invalid cursor",
+		} {
+			r := doRequest(t, http.MethodGet, ts.URL+path+"?"+query, getTestJWT(), "")
+			assert.Equal(t, http.StatusBadRequest, r.StatusCode, query)
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"error":"`+msg+`"}`, string(body), query)
+		}
+	})
+	t.Run("invalid tag", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path+"?tag=a%7Cb", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+	t.Run("empty", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"items":[],"total":0,"next_cursor":""}`, string(body))
+	})
+	t.Run("storage error", func(t *testing.T) {
+		storage := ErrorStorage{db.NewInMemoryStorage()}
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, r.StatusCode)
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		require.NoError(t, err)
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"unauthorized"}`, string(body))
+	})
+}
+
+func TestGetUserTags(t *testing.T) {
+	const path = "/api/v1/dictionary/tags"
+	t.Run("success", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		for _, item := range []db.UserDictionaryItem{
+			{User: db.UserID(testUserID), Word: "cat", Custom: &db.UserItemCustom{Tags: []string{"home"}}},
+			{User: db.UserID(testUserID), Word: "desk", Custom: &db.UserItemCustom{Tags: []string{"home", "work"}}},
+			{User: db.UserID(testUserID), Word: "dog"},
+			{User: db.UserID(testUserID + 1), Word: "pen", Custom: &db.UserItemCustom{Tags: []string{"school"}}},
+		} {
+			require.NoError(t, storage.Save(db.DictionaryItem{Word: item.Word}))
+			require.NoError(t, storage.SaveUserItem(item))
+		}
+
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `[{"tag":"home","count":2},{"tag":"work","count":1}]`, string(body))
+	})
 	t.Run("empty", func(t *testing.T) {
 		ts, cancel := getTestServer(nil)
 		defer cancel()
@@ -47,6 +213,58 @@ func TestGetUserDictionary(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, `[]`, string(body))
 	})
+	t.Run("storage error", func(t *testing.T) {
+		ts, cancel := getTestServer(ErrorStorage{db.NewInMemoryStorage()})
+		defer cancel()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, r.StatusCode)
+	})
+}
+
+func TestExportUserDictionary(t *testing.T) {
+	const path = "/api/v1/dictionary/export"
+	t.Run("success", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		require.NoError(t, storage.Save(db.DictionaryItem{Word: "test"}))
+		require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: db.UserID(testUserID), Word: "test"}))
+
+		for format, contentType := range map[string]string{
+			"":     "text/csv; charset=utf-8",
+			"tsv":  "text/tab-separated-values; charset=utf-8",
+			"json": "application/json",
+		} {
+			req, err := http.NewRequest(http.MethodGet, ts.URL+path+"?format="+format, nil)
+			require.NoError(t, err)
+			req.Header.Set("Authorization", getTestJWT())
+			r, err := http.DefaultClient.Do(req)
+
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, r.StatusCode)
+			assert.Equal(t, contentType, r.Header.Get("Content-Type"))
+			assert.Contains(t, r.Header.Get("Content-Disposition"), "attachment")
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), "test")
+		}
+	})
+	t.Run("unknown format", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path+"?format=xml", nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
 	t.Run("storage error", func(t *testing.T) {
 		storage := ErrorStorage{db.NewInMemoryStorage()}
 		ts, cancel := getTestServer(storage)
@@ -68,9 +286,208 @@ func TestGetUserDictionary(t *testing.T) {
 
 		require.NoError(t, err)
 		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	})
+}
+
+func TestImportUserDictionary(t *testing.T) {
+	const path = "/api/v1/dictionary/import"
+	const data = "word\ttranslation\nknown1\t\nknown2\tперевод\nunknown\t\nfake\tподделка\n"
+	t.Run("success", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodPost, ts.URL+path+"?header", strings.NewReader(data))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		req.Header.Set("Content-Type", "text/tab-separated-values")
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		expected := `{"dry_run":false,"results":[{"word":"known1","status":"added","translations":[],"existing":[]},` +
+			`{"word":"known2","status":"added","translations":["перевод"],"existing":[]},` +
+			`{"word":"unknown","status":"unknown","translations":[],"existing":[]},` +
+			`{"word":"fake","status":"added","translations":["подделка"],"existing":[]}]}`
+		assert.Equal(t, expected, string(body))
+		dict, err := storage.GetUserDictionary(db.UserID(testUserID))
+		require.NoError(t, err)
+		assert.Len(t, dict, 3)
+		item, err := storage.GetUserItem(db.UserID(testUserID), "fake")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"подделка"}, item.CustomTranslations())
+	})
+	t.Run("dry run", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		require.NoError(t, storage.Save(db.DictionaryItem{Word: "known2"}))
+		require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{
+			User:   db.UserID(testUserID),
+			Word:   "known2",
+			Custom: &db.UserItemCustom{Translations: []string{"другой"}},
+		}))
+		req, err := http.NewRequest(
+			http.MethodPost, ts.URL+path+"?header&dry_run=true&sep=tab", strings.NewReader(data),
+		)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
 		body, err := io.ReadAll(r.Body)
 		require.NoError(t, err)
-		assert.Equal(t, "unauthorized", string(body))
+		expected := `{"dry_run":true,"results":[{"word":"known1","status":"added","translations":[],"existing":[]},` +
+			`{"word":"known2","status":"conflict","translations":["перевод"],"existing":["другой"]},` +
+			`{"word":"unknown","status":"added","translations":[],"existing":[]},` +
+			`{"word":"fake","status":"added","translations":["подделка"],"existing":[]}]}`
+		assert.Equal(t, expected, string(body))
+		dict, err := storage.GetUserDictionary(db.UserID(testUserID))
+		require.NoError(t, err)
+		assert.Len(t, dict, 1)
+	})
+	t.Run("invalid options", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodPost, ts.URL+path+"?word=0", strings.NewReader(data))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodPost, ts.URL+path, strings.NewReader(data))
+		require.NoError(t, err)
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	})
+}
+
+func TestUpdateUserItem(t *testing.T) {
+	const path = "/api/v1/dictionary/items"
+	getStorage := func(t *testing.T) *db.InMemoryStorage {
+		storage := db.NewInMemoryStorage()
+		require.NoError(t, storage.Save(db.DictionaryItem{Word: "give up"}))
+		require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{
+			User:   db.UserID(testUserID),
+			Word:   "give up",
+			Custom: &db.UserItemCustom{Translations: []string{"сдаться"}, Notes: "old"},
+		}))
+		return storage
+	}
+	t.Run("success", func(t *testing.T) {
+		storage := getStorage(t)
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		body := `{"notes": " new note ", "examples": ["Never give up!", " "]}`
+		req, err := http.NewRequest(http.MethodPatch, ts.URL+path+"/give%20up", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+		respBody, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		expected := `{"word":{"word":"give up","phonetics":{"text":"","audio":""},"meanings":[],"translations":[]},` +
+			`"user_item":{"word":"give up","created":"0001-01-01T00:00:00Z","last_quiz":null,` +
+			`"translations":["сдаться"],"notes":"new note","examples":["Never give up!"],"tags":[]},` +
+			`"stats":{"total":0,"correct":0,"streak":0,"mastery_level":0,"mastery_threshold":5,"mastered":false}}`
+		assert.Equal(t, expected, string(respBody))
+		item, err := storage.GetUserItem(db.UserID(testUserID), "give up")
+		require.NoError(t, err)
+		assert.Equal(t, &db.UserItemCustom{
+			Translations: []string{"сдаться"}, Notes: "new note", Examples: []string{"Never give up!"},
+		}, item.Custom)
+	})
+	t.Run("clear", func(t *testing.T) {
+		storage := getStorage(t)
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		body := `{"notes": "", "translations": []}`
+		req, err := http.NewRequest(http.MethodPatch, ts.URL+path+"/give%20up", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+		item, err := storage.GetUserItem(db.UserID(testUserID), "give up")
+		require.NoError(t, err)
+		assert.Nil(t, item.Custom)
+	})
+	t.Run("tags", func(t *testing.T) {
+		storage := getStorage(t)
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		body := `{"tags": ["#Phrasal", "verbs", "phrasal"]}`
+		req, err := http.NewRequest(http.MethodPatch, ts.URL+path+"/give%20up", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+		item, err := storage.GetUserItem(db.UserID(testUserID), "give up")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"phrasal", "verbs"}, item.Tags())
+		assert.Equal(t, "old", item.Custom.Notes)
+	})
+	t.Run("invalid tag", func(t *testing.T) {
+		storage := getStorage(t)
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodPatch, ts.URL+path+"/give%20up", strings.NewReader(`{"tags": ["a b!"]}`))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+		item, err := storage.GetUserItem(db.UserID(testUserID), "give up")
+		require.NoError(t, err)
+		assert.Nil(t, item.Tags())
+	})
+	t.Run("missing", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodPatch, ts.URL+path+"/test", strings.NewReader(`{}`))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, r.StatusCode)
+	})
+	t.Run("invalid json", func(t *testing.T) {
+		storage := getStorage(t)
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodPatch, ts.URL+path+"/give%20up", strings.NewReader(`NOT JSON`))
+		require.NoError(t, err)
+		req.Header.Set("Authorization", getTestJWT())
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		req, err := http.NewRequest(http.MethodPatch, ts.URL+path+"/test", strings.NewReader(`{}`))
+		require.NoError(t, err)
+		r, err := http.DefaultClient.Do(req)
+
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
 	})
 }
 
@@ -91,7 +508,7 @@ func TestGetWord(t *testing.T) {
 		assert.Equal(t, http.StatusOK, r.StatusCode)
 		body, err := io.ReadAll(r.Body)
 		require.NoError(t, err)
-		expected := `{"Word":"test","Phonetics":{"Text":"","Audio":""},"Meanings":null,"Translations":null}`
+		expected := `{"word":"test","phonetics":{"text":"","audio":""},"meanings":[],"translations":[]}`
 		assert.Equal(t, expected, string(body))
 	})
 	t.Run("missing", func(t *testing.T) {
@@ -128,19 +545,19 @@ func TestGetWord(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
 		body, err := io.ReadAll(r.Body)
 		require.NoError(t, err)
-		assert.Equal(t, "unauthorized", string(body))
+		assert.JSONEq(t, `{"error":"unauthorized"}`, string(body))
 	})
 }
 
 func TestUpdateWord(t *testing.T) {
 	const path = "/api/v1/dictionary/word"
 	const wordJSON = `{
-	"Word":"test",
-	"Meanings": [
-		{"PartOfSpeech" :"noun", "Definition" : "test word", "Examples": ["test example"]}
+	"word":"test",
+	"meanings": [
+		{"part_of_speech" :"noun", "definition" : "test word", "examples": ["test example"]}
 	],
-	"Translations": [
-		{"Text": "тест", "Language": "ru", "PartOfSpeech": "noun"}
+	"translations": [
+		{"text": "тест", "language": "ru", "part_of_speech": "noun"}
 	]
 }`
 	t.Run("success", func(t *testing.T) {
@@ -167,6 +584,10 @@ func TestUpdateWord(t *testing.T) {
 		assert.Equal(t, "noun", word.Translations[0].PartOfSpeech)
 		assert.Equal(t, "ru", word.Translations[0].Language)
 		assert.Equal(t, "тест", word.Translations[0].Text)
+		assert.Equal(t, db.UserID(testUserID), word.EditedBy)
+		revisions, err := storage.GetWordRevisions("test")
+		require.NoError(t, err)
+		assert.Len(t, revisions, 2)
 	})
 	t.Run("new word", func(t *testing.T) {
 		storage := db.NewInMemoryStorage()
@@ -210,7 +631,7 @@ func TestUpdateWord(t *testing.T) {
 		ts, cancel := getTestServer(storage)
 		defer cancel()
 		require.NoError(t, storage.SaveUser(db.User{ID: db.UserID(testUserID), IsAdmin: true}))
-		req, err := http.NewRequest(http.MethodPost, ts.URL+path+"/test", strings.NewReader(`{"Word": "test"}`))
+		req, err := http.NewRequest(http.MethodPost, ts.URL+path+"/test", strings.NewReader(`{"word": "test"}`))
 		require.NoError(t, err)
 		req.Header.Set("Authorization", getTestJWT())
 		r, err := http.DefaultClient.Do(req)
@@ -259,8 +680,136 @@ func TestUpdateWord(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
 		body, err := io.ReadAll(r.Body)
 		require.NoError(t, err)
-		assert.Equal(t, "unauthorized", string(body))
+		assert.JSONEq(t, `{"error":"unauthorized"}`, string(body))
 		_, err = storage.Get("test")
 		assert.ErrorIs(t, err, db.ErrNotFound)
 	})
 }
+
+func TestGetWordHistory(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+	require.NoError(t, storage.SaveUser(db.User{ID: testUserID, IsAdmin: true}))
+	require.NoError(t, storage.Save(db.DictionaryItem{Word: "test"}))
+	const path = "/api/v1/dictionary/word/"
+
+	t.Run("no history", func(t *testing.T) {
+		r := doRequest(t, http.MethodGet, ts.URL+path+"test/history", getTestJWT(), "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `[]`, string(body))
+	})
+	t.Run("success", func(t *testing.T) {
+		now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		item := db.DictionaryItem{Word: "test", Translations: []db.Translation{{Text: "тест", Language: "ru"}}}
+		_, err := db.SaveWord(storage, item, testUserID, now)
+		require.NoError(t, err)
+
+		r := doRequest(t, http.MethodGet, ts.URL+path+"test/history", getTestJWT(), "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		var res []WordRevision
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&res))
+		require.Len(t, res, 2)
+		assert.Equal(t, 1, res[0].Rev)
+		assert.Equal(t, int64(0), res[0].Author)
+		assert.Equal(t, []string{}, res[0].Diff)
+		assert.Equal(t, 2, res[1].Rev)
+		assert.Equal(t, int64(testUserID), res[1].Author)
+		assert.Equal(t, now, res[1].Created)
+		assert.Equal(t, []string{"+ translation (ru): тест"}, res[1].Diff)
+		assert.Equal(t, int64(testUserID), res[1].Word.EditedBy)
+	})
+	t.Run("not found", func(t *testing.T) {
+		r := doRequest(t, http.MethodGet, ts.URL+path+"unknown/history", getTestJWT(), "")
+		assert.Equal(t, http.StatusNotFound, r.StatusCode)
+	})
+	t.Run("not admin user", func(t *testing.T) {
+		require.NoError(t, storage.SaveUser(db.User{ID: testUserID}))
+		defer func() { require.NoError(t, storage.SaveUser(db.User{ID: testUserID, IsAdmin: true})) }()
+		r := doRequest(t, http.MethodGet, ts.URL+path+"test/history", getTestJWT(), "")
+		assert.Equal(t, http.StatusForbidden, r.StatusCode)
+	})
+}
+
+func TestRevertWord(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+	require.NoError(t, storage.SaveUser(db.User{ID: testUserID, IsAdmin: true}))
+	original := db.DictionaryItem{Word: "test", Translations: []db.Translation{{Text: "тест", Language: "ru"}}}
+	require.NoError(t, storage.Save(original))
+	_, err := db.SaveWord(storage, db.DictionaryItem{Word: "test"}, testUserID, time.Now())
+	require.NoError(t, err)
+	const path = "/api/v1/dictionary/word/"
+
+	t.Run("success", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path+"test/revert/1", getTestJWT(), "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		var res WordRevision
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&res))
+		assert.Equal(t, 3, res.Rev)
+		assert.Equal(t, 1, res.RevertedFrom)
+		assert.Equal(t, []string{"+ translation (ru): тест"}, res.Diff)
+		item, err := storage.Get("test")
+		require.NoError(t, err)
+		assert.Equal(t, original.Translations, item.Translations)
+	})
+	t.Run("not found", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path+"test/revert/10", getTestJWT(), "")
+		assert.Equal(t, http.StatusNotFound, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"revision not found"}`, string(body))
+	})
+	t.Run("invalid revision", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path+"test/revert/first", getTestJWT(), "")
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"invalid revision"}`, string(body))
+	})
+}
+
+func TestRefreshWord(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+	require.NoError(t, storage.Save(db.DictionaryItem{Word: "test", Incomplete: []string{"yandex"}}))
+	const path = "/api/v1/dictionary/word/"
+
+	t.Run("not admin user", func(t *testing.T) {
+		require.NoError(t, storage.SaveUser(db.User{ID: testUserID}))
+		r := doRequest(t, http.MethodPost, ts.URL+path+"test/refresh", getTestJWT(), "")
+		assert.Equal(t, http.StatusForbidden, r.StatusCode)
+	})
+	require.NoError(t, storage.SaveUser(db.User{ID: testUserID, IsAdmin: true}))
+	t.Run("success", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path+"test/refresh", getTestJWT(), "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{
+			"word": "test", "phonetics": {"text": "", "audio": ""}, "meanings": [], "translations": [],
+			"fetched_at": "2023-01-01T00:00:00Z"
+		}`, string(body))
+		item, err := storage.Get("test")
+		require.NoError(t, err)
+		assert.Empty(t, item.Incomplete)
+	})
+	t.Run("changed while refreshing", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path+"changed/refresh", getTestJWT(), "")
+		assert.Equal(t, http.StatusConflict, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"word is changed while refreshing"}`, string(body))
+	})
+	t.Run("not found", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path+"unknown/refresh", getTestJWT(), "")
+		assert.Equal(t, http.StatusNotFound, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"word not found"}`, string(body))
+	})
+}
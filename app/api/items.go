@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rs/zerolog/log"
+)
+
+// ItemStats holds quiz statistics and mastery of user dictionary item
+type ItemStats struct {
+	Total            int  `json:"total"`
+	Correct          int  `json:"correct"`
+	Streak           int  `json:"streak"`
+	MasteryLevel     int  `json:"mastery_level"`
+	MasteryThreshold int  `json:"mastery_threshold"`
+	Mastered         bool `json:"mastered"`
+}
+
+// UserItemDetails represents user dictionary item with its statistics in API response
+type UserItemDetails struct {
+	UserDictionaryItem
+	Stats ItemStats `json:"stats"`
+}
+
+// AddItemRequest holds word to add to user dictionary
+type AddItemRequest struct {
+	Word string `json:"word"`
+}
+
+// getItemDetails returns user item with dictionary data and statistics,
+// mastery is calculated with threshold picked by the user
+func (d dictionaryService) getItemDetails(userItem db.UserDictionaryItem) (UserItemDetails, error) {
+	item, err := d.storage.Get(userItem.Word)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return UserItemDetails{}, err
+	}
+	threshold := db.DefaultMasteryThreshold
+	user, err := d.storage.GetUser(userItem.User)
+	switch {
+	case err == nil:
+		threshold = user.Config.EffectiveMasteryThreshold()
+	case !errors.Is(err, db.ErrNotFound):
+		return UserItemDetails{}, err
+	}
+	total := userItem.TotalStats()
+	return UserItemDetails{
+		UserDictionaryItem: newUserDictionaryItem(userItem, item),
+		Stats: ItemStats{
+			Total:            total.Total,
+			Correct:          total.Correct,
+			Streak:           userItem.Streak(),
+			MasteryLevel:     userItem.MasteryLevel(threshold),
+			MasteryThreshold: threshold,
+			Mastered:         userItem.IsMastered(threshold),
+		},
+	}, nil
+}
+
+// writeItemDetails writes user item with its statistics to response
+func (d dictionaryService) writeItemDetails(w http.ResponseWriter, status int, userItem db.UserDictionaryItem) {
+	details, err := d.getItemDetails(userItem)
+	if err != nil {
+		log.Error().Err(err).Str("word", userItem.Word).Msg("failed to get item details")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	response, jerr := json.Marshal(details)
+	if jerr != nil {
+		log.Error().Err(jerr).Str("word", userItem.Word).Msg("failed to marshal user item")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	w.WriteHeader(status)
+	if _, err := w.Write(response); err != nil {
+		log.Warn().Err(err).Msg("failed to write response")
+	}
+}
+
+// GetUserItem returns item from user dictionary with its statistics
+func (d dictionaryService) GetUserItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	word := chi.URLParam(r, "word")
+	userItem, err := d.storage.GetUserItem(userID, word)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "word not found")
+			return
+		}
+		log.Error().Err(err).Str("word", word).Msg("failed to get user item")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	d.writeItemDetails(w, http.StatusOK, userItem)
+}
+
+// AddUserItem looks word up and adds it to user dictionary,
+// 201 is returned for added words and 200 for words which are already there
+func (d dictionaryService) AddUserItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	var req AddItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	word := db.NormalizeWord(req.Word)
+	if word == "" || len(strings.Fields(word)) > db.MaxPhraseWords {
+		writeError(w, http.StatusBadRequest, "invalid word")
+		return
+	}
+	item, err := d.lookup.GetNormalized(r.Context(), word, d.storage)
+	if err != nil {
+		log.Error().Err(err).Str("word", word).Msg("failed to get word data")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if item == nil {
+		writeError(w, http.StatusNotFound, "word not found")
+		return
+	}
+	userItem, added, err := db.AddUserItem(d.storage, userID, item.Word)
+	if err != nil {
+		log.Error().Err(err).Str("word", item.Word).Int64("user", int64(userID)).Msg("failed to save user item")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	status := http.StatusOK
+	if added {
+		status = http.StatusCreated
+	}
+	d.writeItemDetails(w, status, userItem)
+}
+
+// DeleteUserItem removes word from user dictionary
+func (d dictionaryService) DeleteUserItem(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	word := chi.URLParam(r, "word")
+	if err := d.storage.DeleteUserItem(userID, word); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "word not found")
+			return
+		}
+		log.Error().Err(err).Str("word", word).Msg("failed to delete user item")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUserItem(t *testing.T) {
+	const path = "/api/v1/dictionary/items/"
+	storage := db.NewInMemoryStorage()
+	require.NoError(t, storage.SaveUser(db.User{ID: testUserID, Config: db.UserConfig{MasteryThreshold: 3}}))
+	require.NoError(t, storage.Save(db.DictionaryItem{Word: "give up"}))
+	require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{
+		User: testUserID,
+		Word: "give up",
+		Stats: &db.UserItemStats{
+			Quizzes: map[string]db.QuizStats{"a": {Total: 3, Correct: 2}, "b": {Total: 2, Correct: 2}},
+			Streak:  3,
+		},
+	}))
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+
+	t.Run("success", func(t *testing.T) {
+		r := doRequest(t, http.MethodGet, ts.URL+path+"give%20up", getTestJWT(), "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		var res UserItemDetails
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&res))
+		assert.Equal(t, "give up", res.Word.Word)
+		assert.Equal(t, "give up", res.UserItem.Word)
+		assert.Equal(t, ItemStats{
+			Total: 5, Correct: 4, Streak: 3, MasteryLevel: 3, MasteryThreshold: 3, Mastered: true,
+		}, res.Stats)
+	})
+	t.Run("not found", func(t *testing.T) {
+		r := doRequest(t, http.MethodGet, ts.URL+path+"unknown", getTestJWT(), "")
+		assert.Equal(t, http.StatusNotFound, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"word not found"}`, string(body))
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		r := doRequest(t, http.MethodGet, ts.URL+path+"give%20up", "", "")
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	})
+}
+
+func TestAddUserItem(t *testing.T) {
+	const path = "/api/v1/dictionary/items"
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+
+	t.Run("added", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), `{"word": " Known  Word "}`)
+		require.Equal(t, http.StatusCreated, r.StatusCode)
+		var res UserItemDetails
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&res))
+		assert.Equal(t, "known word", res.Word.Word)
+		assert.Equal(t, "known word", res.UserItem.Word)
+		assert.Equal(t, db.DefaultMasteryThreshold, res.Stats.MasteryThreshold)
+		_, err := storage.GetUserItem(testUserID, "known word")
+		assert.NoError(t, err)
+	})
+	t.Run("exists", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), `{"word": "known word"}`)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+	})
+	t.Run("unknown word", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), `{"word": "unknown"}`)
+		assert.Equal(t, http.StatusNotFound, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"word not found"}`, string(body))
+		_, err = storage.GetUserItem(testUserID, "unknown")
+		assert.ErrorIs(t, err, db.ErrNotFound)
+	})
+	t.Run("invalid word", func(t *testing.T) {
+		for _, body := range []string{`{"word": " "}`, `{"word": "known a b c d e"}`} {
+			r := doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), body)
+			assert.Equal(t, http.StatusBadRequest, r.StatusCode, body)
+		}
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), "invalid")
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"invalid JSON"}`, string(body))
+	})
+}
+
+func TestDeleteUserItem(t *testing.T) {
+	const path = "/api/v1/dictionary/items/"
+	storage := db.NewInMemoryStorage()
+	require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: testUserID, Word: "give up"}))
+	require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: testUserID + 1, Word: "test"}))
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+
+	r := doRequest(t, http.MethodDelete, ts.URL+path+"give%20up", getTestJWT(), "")
+	assert.Equal(t, http.StatusNoContent, r.StatusCode)
+	_, err := storage.GetUserItem(testUserID, "give up")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	r = doRequest(t, http.MethodDelete, ts.URL+path+"give%20up", getTestJWT(), "")
+	assert.Equal(t, http.StatusNotFound, r.StatusCode)
+	r = doRequest(t, http.MethodDelete, ts.URL+path+"test", getTestJWT(), "")
+	assert.Equal(t, http.StatusNotFound, r.StatusCode)
+	_, err = storage.GetUserItem(testUserID+1, "test")
+	assert.NoError(t, err)
+}
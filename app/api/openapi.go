@@ -0,0 +1,20 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// openAPISpec is OpenAPI 3 document describing all /api/v1 routes
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPIHandler returns OpenAPI specification of the API
+func OpenAPIHandler(w http.ResponseWriter, _ *http.Request) {
+	if _, err := w.Write(openAPISpec); err != nil {
+		log.Warn().Err(err).Msg("failed to write response")
+	}
+}
@@ -0,0 +1,407 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const apiPrefix = "/api/v1"
+
+// specObject is a JSON object of the OpenAPI document
+type specObject = map[string]interface{}
+
+// loadSpec returns parsed OpenAPI document
+func loadSpec(t *testing.T) specObject {
+	t.Helper()
+	var spec specObject
+	require.NoError(t, json.Unmarshal(openAPISpec, &spec))
+	return spec
+}
+
+// resolveRef returns object referenced by $ref of the object, or the object itself if it has no $ref
+func resolveRef(spec, obj specObject) (specObject, error) {
+	for {
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return obj, nil
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			return nil, fmt.Errorf("unsupported $ref %q", ref)
+		}
+		var cur interface{} = spec
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			m, ok := cur.(specObject)
+			if !ok {
+				return nil, fmt.Errorf("invalid $ref %q", ref)
+			}
+			cur = m[part]
+		}
+		if obj, ok = cur.(specObject); !ok {
+			return nil, fmt.Errorf("unresolved $ref %q", ref)
+		}
+	}
+}
+
+// validateSchema checks that decoded JSON value matches the schema,
+// only the schema keywords used in openapi.json are supported
+func validateSchema(spec, schema specObject, value interface{}, path string) error {
+	schema, err := resolveRef(spec, schema)
+	if err != nil {
+		return err
+	}
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+		return fmt.Errorf("%s: null is not allowed", path)
+	}
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			if err := validateSchema(spec, sub.(specObject), value, path); err != nil {
+				return err
+			}
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || reflect.DeepEqual(e, value)
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+		}
+	}
+	switch schema["type"] {
+	case nil:
+	case "object":
+		obj, ok := value.(specObject)
+		if !ok {
+			return fmt.Errorf("%s: object expected, got %T", path, value)
+		}
+		props, _ := schema["properties"].(specObject)
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := obj[name.(string)]; !ok {
+					return fmt.Errorf("%s: required property %q is missing", path, name)
+				}
+			}
+		}
+		for name, v := range obj {
+			prop, ok := props[name].(specObject)
+			if !ok {
+				if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+					return fmt.Errorf("%s: unexpected property %q", path, name)
+				}
+				continue
+			}
+			if err := validateSchema(spec, prop, v, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: array expected, got %T", path, value)
+		}
+		items, _ := schema["items"].(specObject)
+		for idx, v := range arr {
+			if err := validateSchema(spec, items, v, fmt.Sprintf("%s[%d]", path, idx)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: string expected, got %T", path, value)
+		}
+		if schema["format"] == "date-time" {
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				return fmt.Errorf("%s: invalid date-time %q", path, s)
+			}
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: integer expected, got %v", path, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: boolean expected, got %T", path, value)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported schema type %v", path, schema["type"])
+	}
+	return nil
+}
+
+// validateResponse checks that response matches the OpenAPI document for the route pattern,
+// JSON bodies are validated against the documented schema
+func validateResponse(spec specObject, method, pattern string, r *http.Response, body []byte) error {
+	paths, _ := spec["paths"].(specObject)
+	item, ok := paths[pattern].(specObject)
+	if !ok {
+		return fmt.Errorf("path %s is not documented", pattern)
+	}
+	operation, ok := item[strings.ToLower(method)].(specObject)
+	if !ok {
+		return fmt.Errorf("%s %s is not documented", method, pattern)
+	}
+	responses, _ := operation["responses"].(specObject)
+	response, ok := responses[strconv.Itoa(r.StatusCode)].(specObject)
+	if !ok {
+		return fmt.Errorf("status %d of %s %s is not documented", r.StatusCode, method, pattern)
+	}
+	response, err := resolveRef(spec, response)
+	if err != nil {
+		return err
+	}
+	content, ok := response["content"].(specObject)
+	if !ok {
+		if len(body) != 0 {
+			return fmt.Errorf("%s %s: unexpected body with status %d", method, pattern, r.StatusCode)
+		}
+		return nil
+	}
+	contentType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return fmt.Errorf("%s %s: invalid content type: %w", method, pattern, err)
+	}
+	media, ok := content[contentType].(specObject)
+	if !ok {
+		return fmt.Errorf("%s %s: content type %s is not documented", method, pattern, contentType)
+	}
+	if contentType != "application/json" {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Errorf("%s %s: invalid JSON body: %w", method, pattern, err)
+	}
+	schema, _ := media["schema"].(specObject)
+	return validateSchema(spec, schema, value, "body")
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	ts, cancel := getTestServer(nil)
+	defer cancel()
+
+	r := doRequest(t, http.MethodGet, ts.URL+apiPrefix+"/openapi.json", "", "")
+	require.Equal(t, http.StatusOK, r.StatusCode)
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(openAPISpec), string(body))
+}
+
+func TestOpenAPIRoutes(t *testing.T) {
+	spec := loadSpec(t)
+	server := NewServer(db.NewInMemoryStorage(), stubLookuper{}, testTGToken, testJWTSecret)
+
+	routes := make(map[string]bool)
+	walk := func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		path := strings.TrimSuffix(strings.TrimPrefix(route, apiPrefix), "/")
+		routes[method+" "+path] = true
+		return nil
+	}
+	require.NoError(t, chi.Walk(server.router, walk))
+
+	documented := make(map[string]bool)
+	for path, item := range spec["paths"].(specObject) {
+		for method := range item.(specObject) {
+			if method == "parameters" {
+				continue
+			}
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+	for route := range routes {
+		assert.True(t, documented[route], "route %s is not documented", route)
+	}
+	for route := range documented {
+		assert.True(t, routes[route], "documented route %s does not exist", route)
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	spec := loadSpec(t)
+	schema := specObject{"$ref": "#/components/schemas/Quiz"}
+	valid := `{"id": "1", "display_word": "cat", "type": "translations", "choices": ["кошка"],` +
+		` "created": "2023-01-01T00:00:00Z", "result": null}`
+	cases := map[string]string{
+		"missing property":    `{"id": "1"}`,
+		"unexpected property": strings.Replace(valid, `"id"`, `"ID": "1", "id"`, 1),
+		"wrong type":          strings.Replace(valid, `["кошка"]`, `[1]`, 1),
+		"invalid enum":        strings.Replace(valid, `"translations"`, `"unknown"`, 1),
+		"invalid date-time":   strings.Replace(valid, `"2023-01-01T00:00:00Z"`, `"yesterday"`, 1),
+		"invalid nested":      strings.Replace(valid, `null`, `{"choice": 1}`, 1),
+	}
+	var value interface{}
+	require.NoError(t, json.Unmarshal([]byte(valid), &value))
+	assert.NoError(t, validateSchema(spec, schema, value, "body"))
+	for name, data := range cases {
+		value = nil
+		require.NoError(t, json.Unmarshal([]byte(data), &value), name)
+		assert.Error(t, validateSchema(spec, schema, value, "body"), name)
+	}
+}
+
+func TestOpenAPIResponses(t *testing.T) {
+	spec := loadSpec(t)
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+	auth := getTestJWT()
+	s := &authService{telegramToken: testTGToken}
+
+	// check makes request and validates the response against the spec
+	check := func(t *testing.T, method, pattern, path, auth, body string, status int) []byte {
+		t.Helper()
+		r := doRequest(t, method, ts.URL+apiPrefix+path, auth, body)
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, status, r.StatusCode, "%s %s: %s", method, path, data)
+		assert.NoError(t, validateResponse(spec, method, pattern, r, data))
+		return data
+	}
+
+	t.Run("auth", func(t *testing.T) {
+		params := map[string]string{"id": "1", "auth_date": strconv.FormatInt(time.Now().Unix(), 10)}
+		params["hash"] = signTelegramData(params, s.loginWidgetSecret())
+		values := url.Values{}
+		for k, v := range params {
+			values.Set(k, v)
+		}
+		check(t, http.MethodGet, "/auth/telegram", "/auth/telegram?"+values.Encode(), "", "", http.StatusOK)
+		check(t, http.MethodGet, "/auth/telegram", "/auth/telegram?id=1", "", "", http.StatusUnauthorized)
+
+		params = map[string]string{"user": `{"id":1}`, "auth_date": strconv.FormatInt(time.Now().Unix(), 10)}
+		params["hash"] = signTelegramData(params, s.webAppSecret())
+		values = url.Values{}
+		for k, v := range params {
+			values.Set(k, v)
+		}
+		req, err := json.Marshal(WebAppAuthRequest{InitData: values.Encode()})
+		require.NoError(t, err)
+		var tokens AuthResponse
+		data := check(t, http.MethodPost, "/auth/webapp", "/auth/webapp", "", string(req), http.StatusOK)
+		require.NoError(t, json.Unmarshal(data, &tokens))
+		check(t, http.MethodPost, "/auth/webapp", "/auth/webapp", "", "invalid", http.StatusBadRequest)
+
+		req, err = json.Marshal(RefreshRequest{RefreshToken: tokens.RefreshToken})
+		require.NoError(t, err)
+		data = check(t, http.MethodPost, "/auth/refresh", "/auth/refresh", "", string(req), http.StatusOK)
+		require.NoError(t, json.Unmarshal(data, &tokens))
+		check(t, http.MethodPost, "/auth/logout", "/auth/logout", "Bearer "+tokens.Token, "", http.StatusNoContent)
+		check(t, http.MethodPost, "/auth/refresh", "/auth/refresh", "", string(req), http.StatusUnauthorized)
+		check(t, http.MethodPost, "/auth/logout", "/auth/logout", "", "", http.StatusUnauthorized)
+	})
+
+	t.Run("tokens", func(t *testing.T) {
+		var created CreatedPersonalToken
+		data := check(t, http.MethodPost, "/tokens", "/tokens/", auth, `{"name": "script"}`, http.StatusCreated)
+		require.NoError(t, json.Unmarshal(data, &created))
+		check(t, http.MethodPost, "/tokens", "/tokens/", auth, `{"name": ""}`, http.StatusBadRequest)
+		check(t, http.MethodGet, "/tokens", "/tokens/", auth, "", http.StatusOK)
+		check(t, http.MethodGet, "/tokens", "/tokens/", "Bearer "+created.Token, "", http.StatusForbidden)
+		check(t, http.MethodDelete, "/tokens/{id}", "/tokens/"+created.ID, auth, "", http.StatusNoContent)
+		check(t, http.MethodDelete, "/tokens/{id}", "/tokens/"+created.ID, auth, "", http.StatusNotFound)
+	})
+
+	t.Run("dictionary", func(t *testing.T) {
+		require.NoError(t, storage.SaveUser(db.User{ID: testUserID}))
+		check(t, http.MethodPost, "/dictionary/items", "/dictionary/items", auth, `{"word": "known"}`, http.StatusCreated)
+		check(t, http.MethodPost, "/dictionary/items", "/dictionary/items", auth, `{"word": "known"}`, http.StatusOK)
+		check(t, http.MethodPost, "/dictionary/items", "/dictionary/items", auth, `{"word": "other"}`, http.StatusNotFound)
+		check(t, http.MethodGet, "/dictionary/items/{word}", "/dictionary/items/known", auth, "", http.StatusOK)
+		check(t, http.MethodGet, "/dictionary/items/{word}", "/dictionary/items/other", auth, "", http.StatusNotFound)
+		check(t,
+			http.MethodPatch, "/dictionary/items/{word}", "/dictionary/items/known", auth,
+			`{"translations": ["известный"], "tags": ["test"]}`, http.StatusOK,
+		)
+		check(t, http.MethodPatch, "/dictionary/items/{word}", "/dictionary/items/known", auth, "invalid", http.StatusBadRequest)
+
+		check(t, http.MethodGet, "/dictionary", "/dictionary/", auth, "", http.StatusOK)
+		check(t, http.MethodGet, "/dictionary", "/dictionary/?sort=created&order=desc&limit=1", auth, "", http.StatusOK)
+		check(t, http.MethodGet, "/dictionary", "/dictionary/?limit=0", auth, "", http.StatusBadRequest)
+		check(t, http.MethodGet, "/dictionary/tags", "/dictionary/tags", auth, "", http.StatusOK)
+		for _, format := range []string{"csv", "tsv", "json"} {
+			check(t, http.MethodGet, "/dictionary/export", "/dictionary/export?format="+format, auth, "", http.StatusOK)
+		}
+		check(t, http.MethodGet, "/dictionary/export", "/dictionary/export?format=xml", auth, "", http.StatusBadRequest)
+		check(t,
+			http.MethodPost, "/dictionary/import", "/dictionary/import?translation=2",
+			auth, "known,другой\nknown2,\nunknown,", http.StatusOK,
+		)
+		check(t, http.MethodPost, "/dictionary/import", "/dictionary/import?sep=pipe", auth, "", http.StatusBadRequest)
+
+		check(t, http.MethodGet, "/dictionary/word/{word}", "/dictionary/word/known", auth, "", http.StatusOK)
+		check(t, http.MethodGet, "/dictionary/word/{word}", "/dictionary/word/other", auth, "", http.StatusNotFound)
+		word := `{"word": "known", "translations": [{"text": "известный", "language": "ru"}]}`
+		check(t, http.MethodPost, "/dictionary/word/{word}", "/dictionary/word/known", auth, word, http.StatusForbidden)
+		require.NoError(t, storage.SaveUser(db.User{ID: testUserID, IsAdmin: true}))
+		check(t, http.MethodPost, "/dictionary/word/{word}", "/dictionary/word/known", auth, word, http.StatusOK)
+		check(t, http.MethodPost, "/dictionary/word/{word}", "/dictionary/word/known", auth, "{}", http.StatusBadRequest)
+		check(t, http.MethodGet, "/dictionary/word/{word}", "/dictionary/word/known", auth, "", http.StatusOK)
+		history := "/dictionary/word/{word}/history"
+		check(t, http.MethodGet, history, "/dictionary/word/known/history", auth, "", http.StatusOK)
+		check(t, http.MethodGet, history, "/dictionary/word/other/history", auth, "", http.StatusNotFound)
+		revert := "/dictionary/word/{word}/revert/{rev}"
+		check(t, http.MethodPost, revert, "/dictionary/word/known/revert/1", auth, "", http.StatusOK)
+		check(t, http.MethodPost, revert, "/dictionary/word/known/revert/10", auth, "", http.StatusNotFound)
+		check(t, http.MethodPost, revert, "/dictionary/word/known/revert/invalid", auth, "", http.StatusBadRequest)
+		refresh := "/dictionary/word/{word}/refresh"
+		check(t, http.MethodPost, refresh, "/dictionary/word/known/refresh", auth, "", http.StatusOK)
+		check(t, http.MethodPost, refresh, "/dictionary/word/other/refresh", auth, "", http.StatusNotFound)
+		check(t, http.MethodPost, refresh, "/dictionary/word/changed/refresh", auth, "", http.StatusConflict)
+
+		check(t, http.MethodDelete, "/dictionary/items/{word}", "/dictionary/items/known2", auth, "", http.StatusNoContent)
+		check(t, http.MethodDelete, "/dictionary/items/{word}", "/dictionary/items/known2", auth, "", http.StatusNotFound)
+	})
+
+	t.Run("quiz", func(t *testing.T) {
+		check(t, http.MethodPost, "/quiz", "/quiz/", auth, `{"tags": ["work"]}`, http.StatusUnprocessableEntity)
+		saveQuizWord(t, storage, "cat", "кошка")
+		var q Quiz
+		data := check(t, http.MethodPost, "/quiz", "/quiz/", auth, "", http.StatusCreated)
+		require.NoError(t, json.Unmarshal(data, &q))
+		check(t, http.MethodPost, "/quiz", "/quiz/", auth, "invalid", http.StatusBadRequest)
+
+		answer := "/quiz/" + q.ID + "/answer"
+		check(t, http.MethodPost, "/quiz/{id}/answer", answer, auth, `{"choice": 10}`, http.StatusBadRequest)
+		check(t, http.MethodPost, "/quiz/{id}/answer", answer, auth, `{"choice": 0}`, http.StatusOK)
+		check(t, http.MethodPost, "/quiz/{id}/answer", answer, auth, `{"choice": 0}`, http.StatusConflict)
+		check(t, http.MethodPost, "/quiz/{id}/answer", "/quiz/unknown/answer", auth, `{"choice": 0}`, http.StatusNotFound)
+
+		check(t, http.MethodGet, "/quiz/history", "/quiz/history", auth, "", http.StatusOK)
+		check(t, http.MethodGet, "/quiz/history", "/quiz/history?limit=0", auth, "", http.StatusBadRequest)
+		check(t, http.MethodGet, "/quiz/history", "/quiz/history", "", "", http.StatusUnauthorized)
+	})
+
+	t.Run("admin", func(t *testing.T) {
+		require.NoError(t, storage.SaveUser(db.User{ID: testUserID}))
+		check(t, http.MethodGet, "/admin/users", "/admin/users", auth, "", http.StatusForbidden)
+		require.NoError(t, storage.SaveUser(db.User{ID: testUserID, IsAdmin: true}))
+		require.NoError(t, storage.SaveUser(db.User{ID: 2, Username: "test"}))
+		check(t, http.MethodGet, "/admin/users", "/admin/users", auth, "", http.StatusOK)
+		check(t, http.MethodGet, "/admin/users/{id}", "/admin/users/1", auth, "", http.StatusOK)
+		check(t, http.MethodGet, "/admin/users/{id}", "/admin/users/3", auth, "", http.StatusNotFound)
+		check(t, http.MethodPatch, "/admin/users/{id}", "/admin/users/2", auth, `{"is_admin": true}`, http.StatusOK)
+		check(t, http.MethodPatch, "/admin/users/{id}", "/admin/users/2", auth, `{"blocked": true}`, http.StatusConflict)
+		check(t, http.MethodPatch, "/admin/users/{id}", "/admin/users/1", auth, `{"blocked": true}`, http.StatusBadRequest)
+	})
+}
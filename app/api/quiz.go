@@ -0,0 +1,217 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/quiz"
+	"github.com/rs/zerolog/log"
+)
+
+// quiz history limits
+const (
+	quizHistoryDefaultLimit = 20
+	quizHistoryMaxLimit     = 100
+)
+
+type quizService struct {
+	storage db.Storage
+}
+
+// QuizRequest holds tags to restrict quizzed words to, tags picked in settings are used if it's empty
+type QuizRequest struct {
+	Tags []string `json:"tags"`
+}
+
+// QuizAnswerRequest holds index of the chosen quiz choice
+type QuizAnswerRequest struct {
+	Choice int `json:"choice"`
+}
+
+// QuizResult represents quiz answer in API response
+type QuizResult struct {
+	Choice        int  `json:"choice"`
+	Correct       bool `json:"correct"`
+	CorrectChoice int  `json:"correct_choice"`
+	// Word is a quizzed word, it's hidden until the answer since it may be one of choices
+	Word string `json:"word"`
+}
+
+// Quiz represents quiz in API response, correct choice is revealed only with the result
+type Quiz struct {
+	ID          string      `json:"id"`
+	DisplayWord string      `json:"display_word"`
+	Type        string      `json:"type"`
+	Choices     []string    `json:"choices"`
+	Created     time.Time   `json:"created"`
+	Result      *QuizResult `json:"result"`
+}
+
+func newQuiz(q db.Quiz) Quiz {
+	res := Quiz{
+		ID:          q.ID,
+		DisplayWord: q.DisplayWord,
+		Type:        q.Type,
+		Choices:     make([]string, 0, len(q.Choices)),
+		Created:     q.Created,
+	}
+	for _, c := range q.Choices {
+		res.Choices = append(res.Choices, c.Text)
+	}
+	if q.Result != nil {
+		res.Result = &QuizResult{Choice: q.Result.Choice, Correct: q.Result.Correct, Word: q.Word}
+		for idx, c := range q.Choices {
+			if c.Correct {
+				res.Result.CorrectChoice = idx
+			}
+		}
+	}
+	return res
+}
+
+// writeQuiz writes quiz to response
+func writeQuiz(w http.ResponseWriter, status int, q db.Quiz) {
+	response, jerr := json.Marshal(newQuiz(q))
+	if jerr != nil {
+		log.Error().Err(jerr).Str("quiz", q.ID).Msg("failed to marshal quiz")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	w.WriteHeader(status)
+	if _, err := w.Write(response); err != nil {
+		log.Warn().Err(err).Msg("failed to write response")
+	}
+}
+
+// CreateQuiz generates new quiz for the user the same way as the bot does
+func (s quizService) CreateQuiz(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	var req QuizRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+	}
+	tags := make([]string, 0, len(req.Tags))
+	for _, t := range req.Tags {
+		tag, err := db.NormalizeTag(t)
+		if err != nil {
+			writeInvalidTag(w)
+			return
+		}
+		tags = append(tags, tag)
+	}
+	user, err := s.storage.GetUser(userID)
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to get user")
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		user = db.User{ID: userID}
+	}
+	if len(tags) == 0 {
+		tags = user.Config.QuizTags
+	}
+	q, err := quiz.New(s.storage, user, tags)
+	if err != nil {
+		switch {
+		case errors.Is(err, quiz.ErrEmptyDictionary), errors.Is(err, quiz.ErrNotEnoughWords):
+			writeError(w, http.StatusUnprocessableEntity, "not enough words")
+		case errors.Is(err, quiz.ErrNoTaggedWords):
+			writeError(w, http.StatusUnprocessableEntity, "no tagged words")
+		default:
+			log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to generate quiz")
+			writeError(w, http.StatusInternalServerError, "internal error")
+		}
+		return
+	}
+	writeQuiz(w, http.StatusCreated, q)
+}
+
+// AnswerQuiz saves answer to the user quiz and returns quiz with the result
+func (s quizService) AnswerQuiz(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	var req QuizAnswerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	q, err := s.storage.GetQuiz(chi.URLParam(r, "id"))
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Error().Err(err).Str("quiz", chi.URLParam(r, "id")).Msg("failed to get quiz")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if err != nil || q.User != userID {
+		writeError(w, http.StatusNotFound, "quiz not found")
+		return
+	}
+	if err := q.SetResult(req.Choice, s.storage); err != nil {
+		switch {
+		case errors.Is(err, db.ErrInvalidChoice):
+			writeError(w, http.StatusBadRequest, "invalid choice")
+		case errors.Is(err, db.ErrAlreadyAnswered):
+			writeError(w, http.StatusConflict, "already answered")
+		default:
+			log.Error().Err(err).Str("quiz", q.ID).Int("choice", req.Choice).Msg("failed to set quiz result")
+			writeError(w, http.StatusInternalServerError, "internal error")
+		}
+		return
+	}
+	writeQuiz(w, http.StatusOK, q)
+}
+
+// GetQuizHistory returns latest quizzes of the user, the newest first,
+// number of quizzes is set by limit query parameter
+func (s quizService) GetQuizHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	limit := quizHistoryDefaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > quizHistoryMaxLimit {
+			writeError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+	quizzes, err := s.storage.GetUserQuizzes(userID, limit)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to get user quizzes")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	res := make([]Quiz, 0, len(quizzes))
+	for _, q := range quizzes {
+		res = append(res, newQuiz(q))
+	}
+	response, jerr := json.Marshal(res)
+	if jerr != nil {
+		log.Error().Err(jerr).Int64("user", int64(userID)).Msg("failed to marshal quizzes")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if _, err := w.Write(response); err != nil {
+		log.Warn().Err(err).Msg("failed to write response")
+	}
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/quiz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// saveQuizWord saves dictionary item with a translation to the test user dictionary
+func saveQuizWord(t *testing.T, storage db.Storage, word, translation string, tags ...string) {
+	var item db.DictionaryItem
+	jdata := `{"Word": "` + word + `", "Translations": [{"Text": "` + translation + `", "Language": "ru"}]}`
+	require.NoError(t, json.Unmarshal([]byte(jdata), &item))
+	require.NoError(t, storage.Save(item))
+	userItem := db.UserDictionaryItem{User: testUserID, Word: word}
+	if len(tags) != 0 {
+		userItem.Custom = &db.UserItemCustom{Tags: tags}
+	}
+	require.NoError(t, storage.SaveUserItem(userItem))
+}
+
+func TestCreateQuiz(t *testing.T) {
+	const path = "/api/v1/quiz/"
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+
+	t.Run("empty dictionary", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), "")
+		assert.Equal(t, http.StatusUnprocessableEntity, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"not enough words"}`, string(body))
+	})
+
+	saveQuizWord(t, storage, "cat", "кошка", "home")
+	t.Run("success", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), "")
+		require.Equal(t, http.StatusCreated, r.StatusCode)
+		var res Quiz
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&res))
+		assert.Equal(t, "cat", res.DisplayWord)
+		assert.Equal(t, db.QuizTypeTranslations, res.Type)
+		assert.Len(t, res.Choices, quiz.ChoicesCount)
+		assert.Contains(t, res.Choices, "кошка")
+		assert.Nil(t, res.Result)
+		saved, err := storage.GetQuiz(res.ID)
+		require.NoError(t, err)
+		assert.Equal(t, db.UserID(testUserID), saved.User)
+	})
+	t.Run("tags", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), `{"tags": ["#Home"]}`)
+		assert.Equal(t, http.StatusCreated, r.StatusCode)
+
+		r = doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), `{"tags": ["work"]}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"no tagged words"}`, string(body))
+
+		r = doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), `{"tags": ["a|b"]}`)
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), "invalid")
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+	t.Run("unauthorized", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, ts.URL+path, "", "")
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	})
+}
+
+func TestAnswerQuiz(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+	require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: testUserID, Word: "cat"}))
+	choices := []db.QuizItem{{Word: "car", Text: "машина"}, {Word: "cat", Text: "кошка", Correct: true}}
+	userQuiz := db.NewQuiz(testUserID, "cat", "cat", quiz.ChoiceLanguage, choices, db.QuizTypeTranslations)
+	require.NoError(t, storage.SaveQuiz(userQuiz))
+	otherQuiz := db.NewQuiz(testUserID+1, "cat", "cat", quiz.ChoiceLanguage, choices, db.QuizTypeTranslations)
+	require.NoError(t, storage.SaveQuiz(otherQuiz))
+	answerURL := func(id string) string { return ts.URL + "/api/v1/quiz/" + id + "/answer" }
+
+	t.Run("invalid choice", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, answerURL(userQuiz.ID), getTestJWT(), `{"choice": 2}`)
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"error":"invalid choice"}`, string(body))
+	})
+	t.Run("success", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, answerURL(userQuiz.ID), getTestJWT(), `{"choice": 0}`)
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		var res Quiz
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&res))
+		assert.Equal(t, &QuizResult{Choice: 0, Correct: false, CorrectChoice: 1, Word: "cat"}, res.Result)
+		item, err := storage.GetUserItem(testUserID, "cat")
+		require.NoError(t, err)
+		assert.Equal(t, 1, item.TotalStats().Total)
+	})
+	t.Run("already answered", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, answerURL(userQuiz.ID), getTestJWT(), `{"choice": 1}`)
+		assert.Equal(t, http.StatusConflict, r.StatusCode)
+	})
+	t.Run("not found", func(t *testing.T) {
+		for _, id := range []string{otherQuiz.ID, "unknown"} {
+			r := doRequest(t, http.MethodPost, answerURL(id), getTestJWT(), `{"choice": 1}`)
+			assert.Equal(t, http.StatusNotFound, r.StatusCode)
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"error":"quiz not found"}`, string(body))
+		}
+		saved, err := storage.GetQuiz(otherQuiz.ID)
+		require.NoError(t, err)
+		assert.Nil(t, saved.Result)
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		r := doRequest(t, http.MethodPost, answerURL(userQuiz.ID), getTestJWT(), "invalid")
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+	t.Run("deleted word", func(t *testing.T) {
+		require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: testUserID, Word: "dog"}))
+		dogChoices := []db.QuizItem{{Word: "dog", Text: "собака", Correct: true}, {Word: "cat", Text: "кошка"}}
+		correctQuiz := db.NewQuiz(testUserID, "dog", "dog", quiz.ChoiceLanguage, dogChoices, db.QuizTypeTranslations)
+		wrongQuiz := db.NewQuiz(testUserID, "dog", "dog", quiz.ChoiceLanguage, dogChoices, db.QuizTypeTranslations)
+		require.NoError(t, storage.SaveQuiz(correctQuiz))
+		require.NoError(t, storage.SaveQuiz(wrongQuiz))
+		r := doRequest(t, http.MethodDelete, ts.URL+"/api/v1/dictionary/items/dog", getTestJWT(), "")
+		require.Equal(t, http.StatusNoContent, r.StatusCode)
+
+		for id, body := range map[string]string{correctQuiz.ID: `{"choice": 0}`, wrongQuiz.ID: `{"choice": 1}`} {
+			r := doRequest(t, http.MethodPost, answerURL(id), getTestJWT(), body)
+			require.Equal(t, http.StatusOK, r.StatusCode)
+			var res Quiz
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&res))
+			require.NotNil(t, res.Result)
+			assert.Equal(t, id == correctQuiz.ID, res.Result.Correct)
+			saved, err := storage.GetQuiz(id)
+			require.NoError(t, err)
+			assert.NotNil(t, saved.Result)
+		}
+		_, err := storage.GetUserItem(testUserID, "dog")
+		assert.ErrorIs(t, err, db.ErrNotFound)
+	})
+}
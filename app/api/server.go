@@ -1,20 +1,43 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/lookup"
+	"github.com/rs/zerolog/log"
 )
 
 type ctxKey int
 
 const (
 	ctxUserIDKey ctxKey = iota
+	ctxClaimsKey
+	ctxScopeKey
 )
 
+// ErrorResponse is a body of API error responses
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeError writes JSON error response with the status
+func writeError(w http.ResponseWriter, status int, msg string) {
+	jdata, jerr := json.Marshal(ErrorResponse{Error: msg})
+	if jerr != nil {
+		log.Error().Err(jerr).Msg("failed to marshal error")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(jdata); err != nil {
+		log.Warn().Err(err).Msg("failed to write response")
+	}
+}
+
 // Server is the API main server struct
 type Server struct {
 	storage db.Storage
@@ -34,10 +57,12 @@ func (s *Server) setJSONContentType(next http.Handler) http.Handler {
 }
 
 // NewServer creates a new server
-func NewServer(storage db.Storage, tgToken string, jwtSecret string) *Server {
+func NewServer(storage db.Storage, lookuper lookup.Lookuper, tgToken string, jwtSecret string) *Server {
 	s := &Server{storage: storage}
-	dict := dictionaryService{storage: storage}
-	auth := authService{telegramToken: tgToken, jwtSecret: []byte(jwtSecret)}
+	dict := dictionaryService{storage: storage, lookup: lookuper}
+	auth := authService{telegramToken: tgToken, jwtSecret: []byte(jwtSecret), storage: storage}
+	quizzes := quizService{storage: storage}
+	admin := adminService{storage: storage}
 
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
@@ -46,14 +71,46 @@ func NewServer(storage db.Storage, tgToken string, jwtSecret string) *Server {
 
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Use(s.setJSONContentType)
+		r.Get("/openapi.json", OpenAPIHandler)
 		r.Route("/auth", func(r chi.Router) {
 			r.Get("/telegram", auth.TelegramRedirectHandler)
+			r.Post("/webapp", auth.WebAppHandler)
+			r.Post("/refresh", auth.RefreshHandler)
+			r.With(auth.UserCtx, auth.SessionOnly).Post("/logout", auth.LogoutHandler)
+		})
+		r.Route("/tokens", func(r chi.Router) {
+			r.Use(auth.UserCtx, auth.SessionOnly)
+			r.Get("/", auth.GetPersonalTokens)
+			r.Post("/", auth.CreatePersonalToken)
+			r.Delete("/{id}", auth.DeletePersonalToken)
 		})
 		r.Route("/dictionary", func(r chi.Router) {
 			r.Use(auth.UserCtx)
 			r.Get("/", dict.GetUserDictionary)
+			r.Get("/tags", dict.GetUserTags)
+			r.Get("/export", dict.ExportUserDictionary)
+			r.Post("/import", dict.ImportUserDictionary)
+			r.Post("/items", dict.AddUserItem)
+			r.Get("/items/{word}", dict.GetUserItem)
+			r.Patch("/items/{word}", dict.UpdateUserItem)
+			r.Delete("/items/{word}", dict.DeleteUserItem)
 			r.Get("/word/{word}", dict.GetWord)
-			r.Post("/word/{word}", dict.UpdateWord)
+			r.With(auth.SessionOnly).Post("/word/{word}", dict.UpdateWord)
+			r.With(auth.SessionOnly, auth.AdminOnly).Get("/word/{word}/history", dict.GetWordHistory)
+			r.With(auth.SessionOnly, auth.AdminOnly).Post("/word/{word}/revert/{rev}", dict.RevertWord)
+			r.With(auth.SessionOnly, auth.AdminOnly).Post("/word/{word}/refresh", dict.RefreshWord)
+		})
+		r.Route("/quiz", func(r chi.Router) {
+			r.Use(auth.UserCtx)
+			r.Post("/", quizzes.CreateQuiz)
+			r.Get("/history", quizzes.GetQuizHistory)
+			r.Post("/{id}/answer", quizzes.AnswerQuiz)
+		})
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(auth.UserCtx, auth.SessionOnly, auth.AdminOnly)
+			r.Get("/users", admin.GetUsers)
+			r.Get("/users/{id}", admin.GetUser)
+			r.Patch("/users/{id}", admin.UpdateUser)
 		})
 
 	})
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rs/zerolog/log"
+)
+
+// refreshSessionTTL is a lifetime of refresh session, it isn't prolonged by rotation
+const refreshSessionTTL = 30 * 24 * time.Hour
+
+// RefreshRequest is a request for tokens refresh
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// hashRefreshToken returns hash of refresh token kept in storage
+func hashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// startSession creates refresh session for the user and issues its first tokens
+func (s *authService) startSession(userID int64) (AuthResponse, error) {
+	now := time.Now().UTC()
+	session := db.RefreshSession{
+		ID:      db.GenerateID(),
+		User:    db.UserID(userID),
+		Created: now,
+		Expires: now.Add(refreshSessionTTL),
+	}
+	return s.rotateSession(session)
+}
+
+// rotateSession issues new refresh and access tokens of the session,
+// previous refresh token can't be used anymore
+func (s *authService) rotateSession(session db.RefreshSession) (AuthResponse, error) {
+	refreshToken := session.ID + "." + db.GenerateID()
+	token, claims, err := s.createToken(int64(session.User), session.ID)
+	if err != nil {
+		return AuthResponse{}, err
+	}
+	session.TokenHash = hashRefreshToken(refreshToken)
+	session.AccessTokenID = claims.Id
+	session.AccessExpires = time.Unix(claims.ExpiresAt, 0).UTC()
+	if err := s.storage.SaveRefreshSession(session); err != nil {
+		return AuthResponse{}, fmt.Errorf("save session: %w", err)
+	}
+	return AuthResponse{Token: token, RefreshToken: refreshToken}, nil
+}
+
+// revokeSession revokes refresh session and its latest access token
+func (s *authService) revokeSession(session db.RefreshSession) error {
+	session.Revoked = true
+	if err := s.storage.SaveRefreshSession(session); err != nil {
+		return fmt.Errorf("save session: %w", err)
+	}
+	if session.AccessTokenID != "" {
+		if err := s.storage.RevokeToken(session.AccessTokenID, session.AccessExpires); err != nil {
+			return fmt.Errorf("revoke access token: %w", err)
+		}
+	}
+	return nil
+}
+
+// RefreshHandler rotates refresh token and issues a new access token.
+// Reuse of already rotated refresh token revokes the whole session as the token is probably leaked
+func (s *authService) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	sessionID, _, ok := strings.Cut(req.RefreshToken, ".")
+	if !ok {
+		writeUnauthorized(w)
+		return
+	}
+	session, err := s.storage.GetRefreshSession(sessionID)
+	if err != nil {
+		if !errors.Is(err, db.ErrNotFound) {
+			log.Error().Err(err).Msg("failed to get refresh session")
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		writeUnauthorized(w)
+		return
+	}
+	if !session.IsActive(time.Now()) {
+		writeUnauthorized(w)
+		return
+	}
+	if !hmac.Equal([]byte(hashRefreshToken(req.RefreshToken)), []byte(session.TokenHash)) {
+		log.Warn().Int64("user", int64(session.User)).Msg("refresh token reuse detected, session revoked")
+		if err := s.revokeSession(session); err != nil {
+			log.Error().Err(err).Msg("failed to revoke session")
+		}
+		writeUnauthorized(w)
+		return
+	}
+	if !s.checkNotBlocked(w, session.User) {
+		return
+	}
+	resp, err := s.rotateSession(session)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to rotate session")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	s.writeAuthResponse(w, resp)
+}
+
+// LogoutHandler revokes access token of the request and its refresh session
+func (s *authService) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(ctxClaimsKey).(JWTClaims)
+	if err := s.storage.RevokeToken(claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+		log.Error().Err(err).Msg("failed to revoke token")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if claims.Session != "" {
+		session, err := s.storage.GetRefreshSession(claims.Session)
+		switch {
+		case errors.Is(err, db.ErrNotFound):
+		case err != nil:
+			log.Error().Err(err).Msg("failed to get refresh session")
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		default:
+			if err := s.revokeSession(session); err != nil {
+				log.Error().Err(err).Msg("failed to revoke session")
+				writeError(w, http.StatusInternalServerError, "internal error")
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
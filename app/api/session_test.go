@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshHandler(t *testing.T) {
+	const path = "/api/v1/auth/refresh"
+	refresh := func(t *testing.T, url, token string) *http.Response {
+		t.Helper()
+		body, err := json.Marshal(RefreshRequest{RefreshToken: token})
+		require.NoError(t, err)
+		r, err := http.Post(url+path, "application/json", strings.NewReader(string(body)))
+		require.NoError(t, err)
+		t.Cleanup(func() { r.Body.Close() })
+		return r
+	}
+	getDictionary := func(t *testing.T, url, token string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, url+"/api/v1/dictionary/", nil)
+		require.NoError(t, err)
+		req.Header.Add("Authorization", "Bearer "+token)
+		r, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		r.Body.Close()
+		return r.StatusCode
+	}
+
+	t.Run("rotation", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		auth := &authService{jwtSecret: []byte(testJWTSecret), storage: storage}
+		first, err := auth.startSession(testUserID)
+		require.NoError(t, err)
+
+		r := refresh(t, ts.URL, first.RefreshToken)
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		var second AuthResponse
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&second))
+		assert.NotEmpty(t, second.Token)
+		assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+		assert.Equal(t, http.StatusOK, getDictionary(t, ts.URL, second.Token))
+
+		r = refresh(t, ts.URL, second.RefreshToken)
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+	})
+	t.Run("reuse revokes session", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		auth := &authService{jwtSecret: []byte(testJWTSecret), storage: storage}
+		first, err := auth.startSession(testUserID)
+		require.NoError(t, err)
+
+		r := refresh(t, ts.URL, first.RefreshToken)
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		var second AuthResponse
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&second))
+
+		r = refresh(t, ts.URL, first.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+		r = refresh(t, ts.URL, second.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+		assert.Equal(t, http.StatusUnauthorized, getDictionary(t, ts.URL, second.Token))
+	})
+	t.Run("blocked user", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		auth := &authService{jwtSecret: []byte(testJWTSecret), storage: storage}
+		resp, err := auth.startSession(testUserID)
+		require.NoError(t, err)
+		require.NoError(t, storage.SaveUser(db.User{ID: testUserID, Blocked: true}))
+
+		r := refresh(t, ts.URL, resp.RefreshToken)
+		assert.Equal(t, http.StatusForbidden, r.StatusCode)
+	})
+	t.Run("expired session", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		ts, cancel := getTestServer(storage)
+		defer cancel()
+		auth := &authService{jwtSecret: []byte(testJWTSecret), storage: storage}
+		resp, err := auth.rotateSession(db.RefreshSession{
+			ID:      "test",
+			User:    testUserID,
+			Expires: time.Now().Add(-time.Minute),
+		})
+		require.NoError(t, err)
+		r := refresh(t, ts.URL, resp.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	})
+	t.Run("unknown token", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		for _, token := range []string{"", "invalid", "unknown.token"} {
+			r := refresh(t, ts.URL, token)
+			assert.Equal(t, http.StatusUnauthorized, r.StatusCode, token)
+		}
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		ts, cancel := getTestServer(nil)
+		defer cancel()
+		r, err := http.Post(ts.URL+path, "application/json", strings.NewReader("invalid"))
+		require.NoError(t, err)
+		defer r.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, r.StatusCode)
+	})
+}
+
+func TestLogoutHandler(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+	auth := &authService{jwtSecret: []byte(testJWTSecret), storage: storage}
+	tokens, err := auth.startSession(testUserID)
+	require.NoError(t, err)
+
+	logout := func(t *testing.T, token string) int {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/auth/logout", nil)
+		require.NoError(t, err)
+		req.Header.Add("Authorization", "Bearer "+token)
+		r, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		r.Body.Close()
+		return r.StatusCode
+	}
+	assert.Equal(t, http.StatusNoContent, logout(t, tokens.Token))
+	assert.Equal(t, http.StatusUnauthorized, logout(t, tokens.Token))
+
+	body, err := json.Marshal(RefreshRequest{RefreshToken: tokens.RefreshToken})
+	require.NoError(t, err)
+	r, err := http.Post(ts.URL+"/api/v1/auth/refresh", "application/json", strings.NewReader(string(body)))
+	require.NoError(t, err)
+	defer r.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+}
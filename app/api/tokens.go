@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rs/zerolog/log"
+)
+
+// PersonalTokenInfo represents personal access token in API response, token itself is never returned
+type PersonalTokenInfo struct {
+	ID      string        `json:"id"`
+	Name    string        `json:"name"`
+	Scope   db.TokenScope `json:"scope"`
+	Created time.Time     `json:"created"`
+}
+
+// CreatedPersonalToken represents just created personal access token with the token string
+type CreatedPersonalToken struct {
+	PersonalTokenInfo
+	Token string `json:"token"`
+}
+
+// PersonalTokenRequest holds data of personal access token to create, scope is read by default
+type PersonalTokenRequest struct {
+	Name  string        `json:"name"`
+	Scope db.TokenScope `json:"scope"`
+}
+
+func newPersonalTokenInfo(t db.PersonalToken) PersonalTokenInfo {
+	return PersonalTokenInfo{ID: t.ID, Name: t.Name, Scope: t.Scope, Created: t.Created}
+}
+
+// GetPersonalTokens returns personal access tokens of the user
+func (s *authService) GetPersonalTokens(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	tokens, err := s.storage.GetPersonalTokens(userID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to get personal tokens")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	res := make([]PersonalTokenInfo, 0, len(tokens))
+	for _, t := range tokens {
+		res = append(res, newPersonalTokenInfo(t))
+	}
+	response, jerr := json.Marshal(res)
+	if jerr != nil {
+		log.Error().Err(jerr).Int64("user", int64(userID)).Msg("failed to marshal personal tokens")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if _, err := w.Write(response); err != nil {
+		log.Warn().Err(err).Msg("failed to write response")
+	}
+}
+
+// CreatePersonalToken creates personal access token, the token string is returned only once
+func (s *authService) CreatePersonalToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	var req PersonalTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = db.ScopeRead
+	}
+	token, raw, err := db.CreatePersonalToken(s.storage, userID, req.Name, req.Scope)
+	if err != nil {
+		var msg string
+		switch {
+		case errors.Is(err, db.ErrInvalidTokenName):
+			msg = "invalid name"
+		case errors.Is(err, db.ErrInvalidScope):
+			msg = "invalid scope"
+		case errors.Is(err, db.ErrTooManyTokens):
+			msg = "too many tokens"
+		default:
+			log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to create personal token")
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		writeError(w, http.StatusBadRequest, msg)
+		return
+	}
+	response, jerr := json.Marshal(CreatedPersonalToken{PersonalTokenInfo: newPersonalTokenInfo(token), Token: raw})
+	if jerr != nil {
+		log.Error().Err(jerr).Int64("user", int64(userID)).Msg("failed to marshal personal token")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	if _, err := w.Write(response); err != nil {
+		log.Warn().Err(err).Msg("failed to write response")
+	}
+}
+
+// DeletePersonalToken revokes personal access token of the user
+func (s *authService) DeletePersonalToken(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(ctxUserIDKey).(db.UserID)
+	if !ok {
+		log.Error().Interface("user", r.Context().Value(ctxUserIDKey)).Msg("invalid user id in context")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if err := s.storage.DeletePersonalToken(userID, chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "token not found")
+			return
+		}
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to delete personal token")
+		writeError(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
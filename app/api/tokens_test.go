@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// doRequest makes request to the test server with authorization header
+func doRequest(t *testing.T, method, url, auth, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	if auth != "" {
+		req.Header.Add("Authorization", auth)
+	}
+	r, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { r.Body.Close() })
+	return r
+}
+
+func TestPersonalTokens(t *testing.T) {
+	const path = "/api/v1/tokens/"
+	storage := db.NewInMemoryStorage()
+	ts, cancel := getTestServer(storage)
+	defer cancel()
+
+	r := doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), `{"name": "script"}`)
+	require.Equal(t, http.StatusCreated, r.StatusCode)
+	var readToken CreatedPersonalToken
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&readToken))
+	assert.Equal(t, "script", readToken.Name)
+	assert.Equal(t, db.ScopeRead, readToken.Scope)
+	assert.True(t, strings.HasPrefix(readToken.Token, "tgd_"+readToken.ID+"_"))
+
+	r = doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), `{"name": "sync", "scope": "write"}`)
+	require.Equal(t, http.StatusCreated, r.StatusCode)
+	var writeToken CreatedPersonalToken
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&writeToken))
+	assert.Equal(t, db.ScopeWrite, writeToken.Scope)
+
+	t.Run("list", func(t *testing.T) {
+		r := doRequest(t, http.MethodGet, ts.URL+path, getTestJWT(), "")
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.NotContains(t, string(body), readToken.Token)
+		assert.NotContains(t, string(body), "Hash")
+		var tokens []PersonalTokenInfo
+		require.NoError(t, json.Unmarshal(body, &tokens))
+		assert.Equal(t, []PersonalTokenInfo{readToken.PersonalTokenInfo, writeToken.PersonalTokenInfo}, tokens)
+	})
+	t.Run("read scope", func(t *testing.T) {
+		r := doRequest(t, http.MethodGet, ts.URL+"/api/v1/dictionary/", "Bearer "+readToken.Token, "")
+		assert.Equal(t, http.StatusOK, r.StatusCode)
+		r = doRequest(t, http.MethodPatch, ts.URL+"/api/v1/dictionary/items/test", "Bearer "+readToken.Token, "{}")
+		assert.Equal(t, http.StatusForbidden, r.StatusCode)
+	})
+	t.Run("write scope", func(t *testing.T) {
+		r := doRequest(t, http.MethodPatch, ts.URL+"/api/v1/dictionary/items/test", "Bearer "+writeToken.Token, "{}")
+		assert.Equal(t, http.StatusNotFound, r.StatusCode)
+	})
+	t.Run("token management requires session", func(t *testing.T) {
+		r := doRequest(t, http.MethodGet, ts.URL+path, "Bearer "+writeToken.Token, "")
+		assert.Equal(t, http.StatusForbidden, r.StatusCode)
+		r = doRequest(t, http.MethodPost, ts.URL+path, "Bearer "+writeToken.Token, `{"name": "test"}`)
+		assert.Equal(t, http.StatusForbidden, r.StatusCode)
+		r = doRequest(t, http.MethodPost, ts.URL+"/api/v1/auth/logout", "Bearer "+writeToken.Token, "")
+		assert.Equal(t, http.StatusForbidden, r.StatusCode)
+	})
+	t.Run("blocked user", func(t *testing.T) {
+		require.NoError(t, storage.SaveUser(db.User{ID: testUserID, Blocked: true}))
+		defer func() { require.NoError(t, storage.SaveUser(db.User{ID: testUserID})) }()
+		r := doRequest(t, http.MethodGet, ts.URL+"/api/v1/dictionary/", "Bearer "+readToken.Token, "")
+		assert.Equal(t, http.StatusForbidden, r.StatusCode)
+	})
+	t.Run("invalid secret", func(t *testing.T) {
+		invalid := "tgd_" + readToken.ID + "_invalid"
+		r := doRequest(t, http.MethodGet, ts.URL+"/api/v1/dictionary/", "Bearer "+invalid, "")
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	})
+	t.Run("invalid request", func(t *testing.T) {
+		for body, msg := range map[string]string{
+			`{"name": ""}`:                     "invalid name",
+			`{"name": "test", "scope": "all"}`: "invalid scope",
+			`invalid`:                          "invalid JSON",
+		} {
+			r := doRequest(t, http.MethodPost, ts.URL+path, getTestJWT(), body)
+			assert.Equal(t, http.StatusBadRequest, r.StatusCode, body)
+			data, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"error":"`+msg+`"}`, string(data))
+		}
+	})
+	t.Run("delete", func(t *testing.T) {
+		r := doRequest(t, http.MethodDelete, ts.URL+path+readToken.ID, getTestJWT(), "")
+		assert.Equal(t, http.StatusNoContent, r.StatusCode)
+		r = doRequest(t, http.MethodDelete, ts.URL+path+readToken.ID, getTestJWT(), "")
+		assert.Equal(t, http.StatusNotFound, r.StatusCode)
+		r = doRequest(t, http.MethodGet, ts.URL+"/api/v1/dictionary/", "Bearer "+readToken.Token, "")
+		assert.Equal(t, http.StatusUnauthorized, r.StatusCode)
+	})
+}
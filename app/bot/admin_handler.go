@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/lookup"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// adminUsersLimit limits number of users in /admin list, the most recently seen users are shown
+const adminUsersLimit = 50
+
+const adminHelp = "/admin users lists users, /admin user id shows user activity, " +
+	"/admin grant id and /admin revoke id change admin role, " +
+	"/admin block id and /admin unblock id stop or resume handling of user messages, " +
+	"/admin refresh word fetches word data from dictionaries again"
+
+// AdminHandler handles /admin command managing users and dictionary, it's available only for admins
+type AdminHandler struct {
+	neverPassthorugh
+	lookup lookup.Lookuper
+}
+
+// NewAdminHandler creates AdminHandler
+func NewAdminHandler(lookup lookup.Lookuper) AdminHandler {
+	return AdminHandler{lookup: lookup}
+}
+
+// Match returns true if update is /admin command
+func (h AdminHandler) Match(u tgbotapi.Update) bool {
+	return u.Message != nil && u.Message.Command() == "admin"
+}
+
+// Handle lists users, shows user activity, changes user role, blocks user or refreshes word
+func (h AdminHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	chatID := u.Message.Chat.ID
+	if !user.IsAdmin {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, this command is available only for admins"))
+		return
+	}
+	action, args, _ := strings.Cut(strings.TrimSpace(u.Message.CommandArguments()), " ")
+	action = strings.ToLower(action)
+	if action == "" || action == "users" {
+		h.sendUsers(b, chatID)
+		return
+	}
+	if action == "refresh" && strings.TrimSpace(args) != "" {
+		h.refreshWord(ctx, b, chatID, args)
+		return
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, adminHelp))
+		return
+	}
+	var target db.User
+	switch action {
+	case "user":
+		h.sendUser(b, chatID, db.UserID(id))
+		return
+	case "grant", "revoke":
+		target, err = db.SetUserAdmin(b.DB(), user.ID, db.UserID(id), action == "grant")
+	case "block", "unblock":
+		target, err = db.SetUserBlocked(b.DB(), user.ID, db.UserID(id), action == "block")
+	default:
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, adminHelp))
+		return
+	}
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "User is not found"))
+	case errors.Is(err, db.ErrSelfUpdate):
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "You can't change yourself"))
+	case errors.Is(err, db.ErrAdminBlocked):
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Admins can't be blocked, revoke admin role first"))
+	case err != nil:
+		log.Error().Err(err).Int64("user", id).Str("action", action).Msg("failed to update user")
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, something went wrong"))
+	default:
+		msg := tgbotapi.NewMessage(chatID, "Done: "+userLine(target))
+		msg.ParseMode = "html"
+		_, _ = b.Send(msg)
+	}
+}
+
+// userLine returns short description of the user for admin messages
+func userLine(u db.User) string {
+	line := fmt.Sprintf("<code>%d</code>", u.ID)
+	if u.Username != "" {
+		line += " @" + html.EscapeString(u.Username)
+	}
+	if u.IsAdmin {
+		line += " admin"
+	}
+	if u.Blocked {
+		line += " blocked"
+	}
+	if u.LastSeen != nil {
+		line += ", seen " + u.LastSeen.Format("2006-01-02")
+	}
+	return line
+}
+
+// sendUsers sends list of the most recently seen users
+func (h AdminHandler) sendUsers(b Bot, chatID int64) {
+	users, err := b.DB().GetUsers()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get users")
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, something went wrong"))
+		return
+	}
+	sort.SliceStable(users, func(i, j int) bool {
+		if users[i].LastSeen == nil || users[j].LastSeen == nil {
+			return users[j].LastSeen == nil && users[i].LastSeen != nil
+		}
+		return users[i].LastSeen.After(*users[j].LastSeen)
+	})
+	lines := make([]string, 0, adminUsersLimit+3)
+	lines = append(lines, fmt.Sprintf("Users: %d", len(users)))
+	for idx, u := range users {
+		if idx == adminUsersLimit {
+			lines = append(lines, fmt.Sprintf("and %d more", len(users)-adminUsersLimit))
+			break
+		}
+		lines = append(lines, userLine(u))
+	}
+	lines = append(lines, "", html.EscapeString(adminHelp))
+	msg := tgbotapi.NewMessage(chatID, strings.Join(lines, "\n"))
+	msg.ParseMode = "html"
+	_, _ = b.Send(msg)
+}
+
+// sendUser sends user dictionary size and quiz activity
+func (h AdminHandler) sendUser(b Bot, chatID int64, id db.UserID) {
+	user, err := b.DB().GetUser(id)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			_, _ = b.Send(tgbotapi.NewMessage(chatID, "User is not found"))
+			return
+		}
+		log.Error().Err(err).Int64("user", int64(id)).Msg("failed to get user")
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, something went wrong"))
+		return
+	}
+	activity, err := db.GetUserActivity(b.DB(), user)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(id)).Msg("failed to get user activity")
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, something went wrong"))
+		return
+	}
+	lastQuiz := "never"
+	if activity.LastQuiz != nil {
+		lastQuiz = activity.LastQuiz.Format("2006-01-02")
+	}
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"%v\nWords: %d, mastered: %d\nQuiz answers: %d, last quiz: %v",
+		userLine(user), activity.Words, activity.Mastered, activity.Answers, lastQuiz,
+	))
+	msg.ParseMode = "html"
+	_, _ = b.Send(msg)
+}
+
+// refreshWord fetches word data from dictionary providers again and sends the updated card
+func (h AdminHandler) refreshWord(ctx context.Context, b Bot, chatID int64, word string) {
+	word = db.NormalizeWord(word)
+	item, err := h.lookup.Refresh(ctx, word, b.DB())
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			_, _ = b.Send(tgbotapi.NewMessage(chatID, "Word is not found"))
+			return
+		}
+		if errors.Is(err, lookup.ErrChanged) {
+			_, _ = b.Send(tgbotapi.NewMessage(chatID, "Word was changed while refreshing, try again"))
+			return
+		}
+		log.Error().Err(err).Str("word", word).Msg("failed to refresh word")
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, something went wrong"))
+		return
+	}
+	text := GetItemMessageText(*item, nil, 0)
+	if len(item.Incomplete) != 0 {
+		text += "\n<i>Failed providers</i>: " + html.EscapeString(strings.Join(item.Incomplete, ", "))
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "html"
+	_, _ = b.Send(msg)
+}
@@ -47,14 +47,26 @@ func (b *TelegramBot) processUpdate(u tgbotapi.Update) {
 					IsAdmin:  false,
 					Language: tgUser.LanguageCode,
 				}
-				if err := b.db.SaveUser(user); err != nil {
-					log.Error().Err(err).Int64("user", tgUser.ID).Msg("failed to save user")
-				}
 			} else {
 				log.Error().Err(err).Int64("user", tgUser.ID).Msg("failed to get user")
 				return
 			}
 		}
+		if user.Blocked {
+			log.Debug().Int64("user", tgUser.ID).Msg("update of blocked user ignored")
+			return
+		}
+		// new users are saved here too since they haven't been seen before
+		changed := user.Touch(time.Now())
+		if user.Username != tgUser.UserName {
+			user.Username = tgUser.UserName
+			changed = true
+		}
+		if changed {
+			if err := b.db.SaveUser(user); err != nil {
+				log.Error().Err(err).Int64("user", tgUser.ID).Msg("failed to save user")
+			}
+		}
 		ctx = context.WithValue(ctx, ctxUserKey, user)
 	}
 	for _, handler := range b.handlers {
@@ -96,6 +108,24 @@ func (b *TelegramBot) SendCallback(c tgbotapi.CallbackConfig) (*tgbotapi.APIResp
 	return response, err
 }
 
+// Request sends a request which doesn't return a message, e.g. inline query answer
+func (b *TelegramBot) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	response, err := b.api.Request(c)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to send request")
+	}
+	return response, err
+}
+
+// GetFileDirectURL returns URL to download a file sent to the bot
+func (b *TelegramBot) GetFileDirectURL(fileID string) (string, error) {
+	url, err := b.api.GetFileDirectURL(fileID)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get file url")
+	}
+	return url, err
+}
+
 // DB returns the bot's storage
 func (b *TelegramBot) DB() db.Storage {
 	return b.db
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// stubBot records sent messages and requests instead of calling Telegram API
+type stubBot struct {
+	mx       sync.Mutex
+	storage  db.Storage
+	sent     []tgbotapi.Chattable
+	requests []tgbotapi.Chattable
+}
+
+func newStubBot(storage db.Storage) *stubBot {
+	return &stubBot{storage: storage}
+}
+
+func (b *stubBot) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.sent = append(b.sent, c)
+	return tgbotapi.Message{MessageID: len(b.sent)}, nil
+}
+
+func (b *stubBot) SendCallback(c tgbotapi.CallbackConfig) (*tgbotapi.APIResponse, error) {
+	return b.Request(c)
+}
+
+func (b *stubBot) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.requests = append(b.requests, c)
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (b *stubBot) GetFileDirectURL(fileID string) (string, error) {
+	return "", nil
+}
+
+func (b *stubBot) DB() db.Storage {
+	return b.storage
+}
+
+// texts returns text of sent messages
+func (b *stubBot) texts() []string {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	var texts []string
+	for _, c := range b.sent {
+		switch m := c.(type) {
+		case tgbotapi.MessageConfig:
+			texts = append(texts, m.Text)
+		case tgbotapi.EditMessageTextConfig:
+			texts = append(texts, m.Text)
+		}
+	}
+	return texts
+}
+
+// stubLookuper knows words from items map, lookups are counted
+type stubLookuper struct {
+	mx      sync.Mutex
+	items   map[string]db.DictionaryItem
+	lookups int
+}
+
+func (l *stubLookuper) find(word string, storage db.Storage, save bool) (*db.DictionaryItem, error) {
+	if item, err := storage.Get(word); err == nil {
+		return &item, nil
+	}
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	l.lookups++
+	item, ok := l.items[word]
+	if !ok {
+		return nil, nil
+	}
+	if save {
+		return &item, storage.Save(item)
+	}
+	return &item, nil
+}
+
+func (l *stubLookuper) Get(_ context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	return l.find(word, storage, true)
+}
+
+func (l *stubLookuper) GetNormalized(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	return l.Get(ctx, word, storage)
+}
+
+func (l *stubLookuper) Preview(_ context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	return l.find(word, storage, false)
+}
+
+func (l *stubLookuper) Refresh(_ context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	item, err := storage.Get(word)
+	return &item, err
+}
+
+// requested returns sent requests count
+func (b *stubBot) requested() int {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return len(b.requests)
+}
@@ -0,0 +1,266 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/wordlist"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	courseWordsPerDay  = 5
+	courseSeedTimeout  = time.Minute
+	courseStopCallback = "stop"
+)
+
+// CourseHandler handles /course command
+type CourseHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if update is /course command
+func (h CourseHandler) Match(u tgbotapi.Update) bool {
+	return u.Message != nil && u.Message.Command() == "course"
+}
+
+// Handle sends course levels keyboard
+func (h CourseHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	text := "Pick a level to get new words to your dictionary every day:"
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(wordlist.Levels)+1)
+	for _, level := range wordlist.Levels {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(string(level), fmt.Sprintf("%v|%v", callbackIDCourse, level)),
+		))
+	}
+	if course := user.Config.Course; course != nil {
+		text = fmt.Sprintf(
+			"Current course: %v, %d words per day\n%v", course.Level, course.WordsPerDay, text,
+		)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Stop", fmt.Sprintf("%v|%v", callbackIDCourse, courseStopCallback)),
+		))
+	}
+	msg := tgbotapi.NewMessage(u.Message.Chat.ID, text)
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, _ = b.Send(msg)
+}
+
+// SetCourseHandler subscribes user to a course level
+type SetCourseHandler struct {
+	seeder *CourseSeeder
+	neverPassthorugh
+}
+
+// Match returns true if update is course level callback
+func (h SetCourseHandler) Match(u tgbotapi.Update) bool {
+	return u.CallbackQuery != nil && strings.HasPrefix(u.CallbackQuery.Data, callbackIDCourse+"|")
+}
+
+// Handle saves course to user config and adds first words from it
+func (h SetCourseHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	value := strings.TrimPrefix(u.CallbackQuery.Data, callbackIDCourse+"|")
+	if value == courseStopCallback {
+		user.Config.Course = nil
+		if err := b.DB().SaveUser(user); err != nil {
+			log.Error().Err(err).Msg("failed to save user")
+			return
+		}
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Course stopped"))
+		return
+	}
+	level, err := wordlist.ParseLevel(value)
+	if err != nil {
+		log.Error().Str("level", value).Msg("invalid course level")
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Unknown level"))
+		return
+	}
+	user.Config.Course = &db.CourseConfig{Level: string(level), WordsPerDay: courseWordsPerDay}
+	if err := b.DB().SaveUser(user); err != nil {
+		log.Error().Err(err).Msg("failed to save user")
+		return
+	}
+	_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, fmt.Sprintf("Subscribed to %v", level)))
+	h.seeder.seed(b, user.ID, *user.Config.Course)
+}
+
+// NewSetCourseHandler creates SetCourseHandler
+func NewSetCourseHandler(seeder *CourseSeeder) SetCourseHandler {
+	return SetCourseHandler{seeder: seeder}
+}
+
+// CourseSeedHandler adds daily course words to user dictionary,
+// it's checked for every message so words are added on the first interaction of the day
+type CourseSeedHandler struct {
+	seeder *CourseSeeder
+}
+
+// Match returns true for every message
+func (h CourseSeedHandler) Match(u tgbotapi.Update) bool {
+	return u.Message != nil
+}
+
+// Passthrough always returns true, so message is handled by other handlers
+func (h CourseSeedHandler) Passthrough(u tgbotapi.Update) bool {
+	return true
+}
+
+// Handle starts adding course words if user didn't get them today
+func (h CourseSeedHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		return
+	}
+	if user.Config.Course == nil || !user.Config.Course.IsDue(time.Now()) {
+		return
+	}
+	h.seeder.seed(b, user.ID, *user.Config.Course)
+}
+
+// NewCourseSeedHandler creates CourseSeedHandler
+func NewCourseSeedHandler(seeder *CourseSeeder) CourseSeedHandler {
+	return CourseSeedHandler{seeder: seeder}
+}
+
+// CourseSeeder adds course words to user dictionaries in background,
+// only one seeding runs for a user at a time
+type CourseSeeder struct {
+	words   WordHandler
+	mx      sync.Mutex
+	running map[db.UserID]struct{}
+}
+
+// NewCourseSeeder creates CourseSeeder
+func NewCourseSeeder(words WordHandler) *CourseSeeder {
+	return &CourseSeeder{words: words, running: make(map[db.UserID]struct{})}
+}
+
+// start marks seeding as running for the user, returns false if it's already running
+func (s *CourseSeeder) start(userID db.UserID) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if _, ok := s.running[userID]; ok {
+		return false
+	}
+	s.running[userID] = struct{}{}
+	return true
+}
+
+// finish marks seeding for the user as finished
+func (s *CourseSeeder) finish(userID db.UserID) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	delete(s.running, userID)
+}
+
+// seed adds next course words to user dictionary in background
+func (s *CourseSeeder) seed(b Bot, userID db.UserID, course db.CourseConfig) {
+	if !s.start(userID) {
+		return
+	}
+	go func() {
+		defer s.finish(userID)
+		s.addWords(b, userID, course)
+	}()
+}
+
+// addWords adds next course words to user dictionary, then course progress is saved,
+// only course fields of the user are updated since the user might be changed meanwhile
+func (s *CourseSeeder) addWords(b Bot, userID db.UserID, course db.CourseConfig) {
+	ctx, cancel := context.WithTimeout(context.Background(), courseSeedTimeout)
+	defer cancel()
+	added, offset, seedErr := addCourseWords(ctx, b.DB(), s.words, userID, course)
+	if seedErr != nil {
+		log.Error().Err(seedErr).Int64("user", int64(userID)).Msg("failed to add course words")
+		if len(added) == 0 {
+			return
+		}
+	}
+
+	user, err := b.DB().GetUser(userID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to get user")
+		return
+	}
+	if user.Config.Course == nil || user.Config.Course.Level != course.Level {
+		return
+	}
+	now := time.Now().UTC()
+	user.Config.Course.Offset = offset
+	user.Config.Course.LastSeeded = &now
+	completed := seedErr == nil && len(added) == 0
+	if completed {
+		user.Config.Course = nil
+	}
+	if err := b.DB().SaveUser(user); err != nil {
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to save user")
+		return
+	}
+	if completed {
+		_, _ = b.Send(tgbotapi.NewMessage(int64(userID), fmt.Sprintf(
+			"You've got all words from %v course, pick the next level with /course", course.Level,
+		)))
+	} else if len(added) != 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(int64(userID), fmt.Sprintf(
+			"New words from %v course were added to your dictionary: %v\nUse /quiz to practice them",
+			course.Level, strings.Join(added, ", "),
+		)))
+	}
+}
+
+// addCourseWords adds next course words missing in user dictionary,
+// returns added words and offset of the next word in the list
+func addCourseWords(
+	ctx context.Context,
+	storage db.Storage,
+	words WordHandler,
+	userID db.UserID,
+	course db.CourseConfig,
+) ([]string, int, error) {
+	list, err := wordlist.Words(wordlist.Level(course.Level))
+	if err != nil {
+		return nil, course.Offset, fmt.Errorf("get word list: %w", err)
+	}
+	var added []string
+	offset := course.Offset
+	for ; offset < len(list) && len(added) < course.WordsPerDay; offset++ {
+		word := list[offset]
+		_, err := storage.GetUserItem(userID, word)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, db.ErrNotFound) {
+			return added, offset, fmt.Errorf("get user item: %w", err)
+		}
+		item, err := words.lookup.Get(ctx, word, storage)
+		if err != nil {
+			return added, offset, fmt.Errorf("get word data: %w", err)
+		}
+		if item == nil {
+			continue
+		}
+		err = storage.SaveUserItem(db.UserDictionaryItem{User: userID, Word: item.Word, Created: time.Now().UTC()})
+		if err != nil {
+			return added, offset, fmt.Errorf("save user item: %w", err)
+		}
+		added = append(added, item.Word)
+	}
+	return added, offset, nil
+}
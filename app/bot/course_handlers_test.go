@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/wordlist"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getCourseLookuper returns lookuper which knows first words of A1 list except the third one
+func getCourseLookuper(t *testing.T) (*stubLookuper, []string) {
+	list, err := wordlist.Words(wordlist.LevelA1)
+	require.NoError(t, err)
+	lookuper := &stubLookuper{items: map[string]db.DictionaryItem{}}
+	for i, word := range list[:10] {
+		if i != 2 {
+			lookuper.items[word] = db.DictionaryItem{Word: word}
+		}
+	}
+	return lookuper, list
+}
+
+type userItemErrorStorage struct {
+	*db.InMemoryStorage
+}
+
+func (s userItemErrorStorage) GetUserItem(db.UserID, string) (db.UserDictionaryItem, error) {
+	return db.UserDictionaryItem{}, errors.New("test")
+}
+
+func TestAddCourseWords(t *testing.T) {
+	lookuper, list := getCourseLookuper(t)
+	words := NewWordHandler(lookuper)
+	course := db.CourseConfig{Level: string(wordlist.LevelA1), WordsPerDay: 3}
+
+	t.Run("first words", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: 1, Word: list[1]}))
+		added, offset, err := addCourseWords(context.Background(), storage, words, 1, course)
+		require.NoError(t, err)
+		assert.Equal(t, []string{list[0], list[3], list[4]}, added)
+		assert.Equal(t, 5, offset)
+		for _, word := range added {
+			_, err := storage.GetUserItem(1, word)
+			assert.NoError(t, err)
+		}
+		_, err = storage.GetUserItem(1, list[2])
+		assert.ErrorIs(t, err, db.ErrNotFound)
+	})
+	t.Run("offset", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		course := course
+		course.Offset = 5
+		course.WordsPerDay = 1
+		added, offset, err := addCourseWords(context.Background(), storage, words, 1, course)
+		require.NoError(t, err)
+		assert.Equal(t, []string{list[5]}, added)
+		assert.Equal(t, 6, offset)
+	})
+	t.Run("end of list", func(t *testing.T) {
+		course := course
+		course.Offset = len(list)
+		added, offset, err := addCourseWords(context.Background(), db.NewInMemoryStorage(), words, 1, course)
+		require.NoError(t, err)
+		assert.Empty(t, added)
+		assert.Equal(t, len(list), offset)
+	})
+	t.Run("unknown level", func(t *testing.T) {
+		course := course
+		course.Level = "Z1"
+		_, _, err := addCourseWords(context.Background(), db.NewInMemoryStorage(), words, 1, course)
+		assert.Error(t, err)
+	})
+	t.Run("storage error", func(t *testing.T) {
+		storage := userItemErrorStorage{db.NewInMemoryStorage()}
+		added, offset, err := addCourseWords(context.Background(), storage, words, 1, course)
+		assert.Error(t, err)
+		assert.Empty(t, added)
+		assert.Equal(t, 0, offset)
+	})
+}
+
+func TestCourseSeederAddWords(t *testing.T) {
+	lookuper, list := getCourseLookuper(t)
+	seeder := NewCourseSeeder(NewWordHandler(lookuper))
+	course := db.CourseConfig{Level: string(wordlist.LevelA1), WordsPerDay: 2}
+
+	t.Run("success", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		// user is changed by another handler while words are added
+		saved := course
+		require.NoError(t, storage.SaveUser(db.User{
+			ID: 1, Config: db.UserConfig{MasteryThreshold: 3, Course: &saved},
+		}))
+		b := newStubBot(storage)
+
+		seeder.addWords(b, 1, course)
+		user, err := storage.GetUser(1)
+		require.NoError(t, err)
+		assert.Equal(t, 3, user.Config.MasteryThreshold)
+		require.NotNil(t, user.Config.Course)
+		assert.Equal(t, 2, user.Config.Course.Offset)
+		require.NotNil(t, user.Config.Course.LastSeeded)
+		assert.Equal(t, []string{
+			"New words from A1 course were added to your dictionary: " + list[0] + ", " + list[1] +
+				"\nUse /quiz to practice them",
+		}, b.texts())
+	})
+	t.Run("course changed", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		require.NoError(t, storage.SaveUser(db.User{
+			ID: 1, Config: db.UserConfig{Course: &db.CourseConfig{Level: string(wordlist.LevelA2)}},
+		}))
+		b := newStubBot(storage)
+
+		seeder.addWords(b, 1, course)
+		user, err := storage.GetUser(1)
+		require.NoError(t, err)
+		assert.Equal(t, &db.CourseConfig{Level: string(wordlist.LevelA2)}, user.Config.Course)
+		assert.Empty(t, b.texts())
+	})
+	t.Run("completed", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		course := course
+		course.Offset = len(list)
+		require.NoError(t, storage.SaveUser(db.User{ID: 1, Config: db.UserConfig{Course: &course}}))
+		b := newStubBot(storage)
+
+		seeder.addWords(b, 1, course)
+		user, err := storage.GetUser(1)
+		require.NoError(t, err)
+		assert.Nil(t, user.Config.Course)
+		assert.Equal(t, []string{"You've got all words from A1 course, pick the next level with /course"}, b.texts())
+	})
+	t.Run("failed", func(t *testing.T) {
+		storage := userItemErrorStorage{db.NewInMemoryStorage()}
+		saved := course
+		require.NoError(t, storage.SaveUser(db.User{ID: 1, Config: db.UserConfig{Course: &saved}}))
+		b := newStubBot(storage)
+
+		seeder.addWords(b, 1, course)
+		user, err := storage.GetUser(1)
+		require.NoError(t, err)
+		assert.Nil(t, user.Config.Course.LastSeeded)
+		assert.Empty(t, b.texts())
+	})
+}
+
+func TestCourseSeederStart(t *testing.T) {
+	seeder := NewCourseSeeder(WordHandler{})
+	assert.True(t, seeder.start(1))
+	assert.False(t, seeder.start(1))
+	assert.True(t, seeder.start(2))
+	seeder.finish(1)
+	assert.True(t, seeder.start(1))
+}
@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// user specific fields of dictionary items
+const (
+	customFieldTranslations = "t"
+	customFieldNotes        = "n"
+	customFieldExample      = "e"
+	customFieldTags         = "g"
+)
+
+// customClearValue is a reply removing field value
+const customClearValue = "-"
+
+// customMaxExamples limits number of user examples per item
+const customMaxExamples = 10
+
+// customFieldButtons holds edit buttons text by field
+var customFieldButtons = []struct {
+	field string
+	text  string
+}{
+	{customFieldTranslations, "✏️ Translations"},
+	{customFieldNotes, "📝 Notes"},
+	{customFieldExample, "➕ Example"},
+	{customFieldTags, "🏷 Tags"},
+}
+
+// customFieldPrompts holds reply prompts by field, the word is passed in quotes
+// so it can be parsed back from the prompt message
+var customFieldPrompts = map[string]string{
+	customFieldTranslations: "Reply with your translations for «%v» separated by commas, send - to remove them",
+	customFieldNotes:        "Reply with your notes for «%v», send - to remove them",
+	customFieldExample:      "Reply with an example sentence for «%v», send - to remove all your examples",
+	customFieldTags:         "Reply with tags for «%v» separated by spaces, send - to remove them",
+}
+
+var promptWordRe = regexp.MustCompile(`«(.+)»`)
+
+// getItemKeyboard returns keyboard with user data edit buttons for the word card,
+// false is returned if the word is too long for callback data
+func getItemKeyboard(word string) (tgbotapi.InlineKeyboardMarkup, bool) {
+	buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(customFieldButtons))
+	for _, b := range customFieldButtons {
+		data := fmt.Sprintf("%v|%v|%v", callbackIDEditItem, b.field, word)
+		if len(data) > callbackDataLimit {
+			return tgbotapi.InlineKeyboardMarkup{}, false
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(b.text, data))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(buttons), true
+}
+
+// EditItemHandler asks user for a new value of user specific item data
+type EditItemHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if update is item edit callback
+func (h EditItemHandler) Match(u tgbotapi.Update) bool {
+	return u.CallbackQuery != nil && strings.HasPrefix(u.CallbackQuery.Data, callbackIDEditItem+"|")
+}
+
+// Handle sends prompt which should be replied with a new value
+func (h EditItemHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	parts := strings.SplitN(u.CallbackQuery.Data, "|", 3)
+	if len(parts) != 3 {
+		log.Error().Str("data", u.CallbackQuery.Data).Msg("invalid edit callback")
+		return
+	}
+	prompt, ok := customFieldPrompts[parts[1]]
+	if !ok {
+		log.Error().Str("field", parts[1]).Msg("invalid edit field")
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Unknown field"))
+		return
+	}
+	_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, ""))
+	msg := tgbotapi.NewMessage(u.CallbackQuery.From.ID, fmt.Sprintf(prompt, parts[2]))
+	msg.ReplyMarkup = tgbotapi.ForceReply{ForceReply: true, InputFieldPlaceholder: parts[2]}
+	_, _ = b.Send(msg)
+}
+
+// EditItemReplyHandler saves user specific item data replied to edit prompt
+type EditItemReplyHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if message is a reply to edit prompt
+func (h EditItemReplyHandler) Match(u tgbotapi.Update) bool {
+	if u.Message == nil || u.Message.Text == "" || u.Message.ReplyToMessage == nil {
+		return false
+	}
+	field, word := parseEditPrompt(u.Message.ReplyToMessage.Text)
+	return field != "" && word != ""
+}
+
+// Handle updates user item and sends updated word card
+func (h EditItemReplyHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	field, word := parseEditPrompt(u.Message.ReplyToMessage.Text)
+	userID := db.UserID(u.Message.From.ID)
+	userItem, err := b.DB().GetUserItem(userID, word)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, "This word is not in your dictionary"))
+			return
+		}
+		log.Error().Err(err).Str("word", word).Msg("failed to get user item")
+		return
+	}
+	value := strings.TrimSpace(u.Message.Text)
+	custom := userItem.CustomData()
+	switch field {
+	case customFieldTranslations:
+		custom.Translations = nil
+		if value != customClearValue {
+			custom.Translations = splitCustomTranslations(value)
+		}
+	case customFieldNotes:
+		custom.Notes = ""
+		if value != customClearValue {
+			custom.Notes = value
+		}
+	case customFieldExample:
+		if value == customClearValue {
+			custom.Examples = nil
+		} else if len(custom.Examples) >= customMaxExamples {
+			_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, fmt.Sprintf(
+				"Sorry, only up to %d examples can be added", customMaxExamples,
+			)))
+			return
+		} else {
+			custom.Examples = append(append([]string{}, custom.Examples...), value)
+		}
+	case customFieldTags:
+		var tags []string
+		if value != customClearValue {
+			if tags, err = parseTags(strings.Fields(value)); err != nil {
+				_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, getInvalidTagText(err)))
+				return
+			}
+		}
+		userItem.SetTags(tags)
+		custom = userItem.CustomData()
+	}
+	userItem.SetCustom(custom)
+	if err := b.DB().SaveUserItem(userItem); err != nil {
+		log.Error().Err(err).Str("word", word).Msg("failed to save user item")
+		return
+	}
+	item, err := b.DB().Get(word)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Error().Err(err).Str("word", word).Msg("failed to get item")
+	}
+	msg := tgbotapi.NewMessage(u.Message.Chat.ID, GetItemMessageText(item, &userItem, getMasteryThreshold(ctx)))
+	msg.ParseMode = "html"
+	if keyboard, ok := getItemKeyboard(userItem.Word); ok {
+		msg.ReplyMarkup = keyboard
+	}
+	_, _ = b.Send(msg)
+}
+
+// parseEditPrompt returns field and word of edit prompt, empty strings are returned for other messages
+func parseEditPrompt(text string) (field, word string) {
+	match := promptWordRe.FindStringSubmatch(text)
+	if match == nil {
+		return "", ""
+	}
+	for f, prompt := range customFieldPrompts {
+		if fmt.Sprintf(prompt, match[1]) == text {
+			return f, match[1]
+		}
+	}
+	return "", ""
+}
+
+// splitCustomTranslations splits comma separated translations
+func splitCustomTranslations(text string) []string {
+	var translations []string
+	for _, t := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ';' || r == '\n' }) {
+		if t = strings.Join(strings.Fields(t), " "); t != "" {
+			translations = append(translations, t)
+		}
+	}
+	return translations
+}
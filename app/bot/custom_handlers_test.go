@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEditItemReplyHandlerTags(t *testing.T) {
+	reply := func(text string) tgbotapi.Update {
+		return tgbotapi.Update{Message: &tgbotapi.Message{
+			Text:           text,
+			From:           &tgbotapi.User{ID: 1},
+			Chat:           &tgbotapi.Chat{ID: 1},
+			ReplyToMessage: &tgbotapi.Message{Text: fmt.Sprintf(customFieldPrompts[customFieldTags], "cat")},
+		}}
+	}
+	storage := db.NewInMemoryStorage()
+	require.NoError(t, storage.Save(db.DictionaryItem{Word: "cat"}))
+	require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: 1, Word: "cat"}))
+	h := EditItemReplyHandler{}
+	require.True(t, h.Match(reply("#pets")))
+
+	t.Run("set", func(t *testing.T) {
+		b := newStubBot(storage)
+		h.Handle(context.Background(), b, reply("#pets animals #pets"))
+		item, err := storage.GetUserItem(1, "cat")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"animals", "pets"}, item.CustomData().Tags)
+		require.Len(t, b.texts(), 1)
+		assert.Contains(t, b.texts()[0], "#animals #pets")
+	})
+	t.Run("invalid", func(t *testing.T) {
+		b := newStubBot(storage)
+		h.Handle(context.Background(), b, reply("#pets bad!tag"))
+		assert.Equal(t, []string{getInvalidTagText(fmt.Errorf("%q: %w", "bad!tag", db.ErrInvalidTag))}, b.texts())
+		item, err := storage.GetUserItem(1, "cat")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"animals", "pets"}, item.CustomData().Tags)
+	})
+	t.Run("clear", func(t *testing.T) {
+		b := newStubBot(storage)
+		h.Handle(context.Background(), b, reply(customClearValue))
+		item, err := storage.GetUserItem(1, "cat")
+		require.NoError(t, err)
+		assert.Empty(t, item.CustomData().Tags)
+	})
+}
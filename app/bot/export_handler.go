@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/export"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// ExportHandler handles /export command
+type ExportHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if update is /export command
+func (h ExportHandler) Match(u tgbotapi.Update) bool {
+	return u.Message != nil && u.Message.Command() == "export"
+}
+
+// Handle sends user dictionary as a document, format is passed as command argument
+func (h ExportHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	format, err := export.ParseFormat(u.Message.CommandArguments())
+	if err != nil {
+		_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, fmt.Sprintf(
+			"Unknown format, use one of: %v, %v (Anki), %v", export.FormatCSV, export.FormatTSV, export.FormatJSON,
+		)))
+		return
+	}
+	dictionary, err := b.DB().GetUserDictionary(user.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(user.ID)).Msg("failed to get user dictionary")
+		return
+	}
+	if len(dictionary) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, "Your dictionary is empty"))
+		return
+	}
+	buf := &bytes.Buffer{}
+	if err := export.Write(buf, format, dictionary); err != nil {
+		log.Error().Err(err).Int64("user", int64(user.ID)).Msg("failed to export user dictionary")
+		return
+	}
+	doc := tgbotapi.NewDocument(u.Message.Chat.ID, tgbotapi.FileBytes{Name: format.FileName(), Bytes: buf.Bytes()})
+	doc.Caption = fmt.Sprintf("%d words", len(dictionary))
+	_, _ = b.Send(doc)
+}
@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/vocab"
+	"github.com/rbhz/tg-dictionary/app/wordlist"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	extractMaxWords     = 20
+	extractButtonsInRow = 2
+	extractActionWord   = "w"
+	extractActionAdd    = "add"
+	extractActionCancel = "cancel"
+)
+
+// extractCommonLevels are levels with words too common to suggest them from a text
+var extractCommonLevels = []wordlist.Level{wordlist.LevelA1, wordlist.LevelA2}
+
+// ExtractHandler suggests words to learn from a long text
+type ExtractHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if message is a text longer than a phrase
+func (h ExtractHandler) Match(u tgbotapi.Update) bool {
+	return u.Message != nil && !u.Message.IsCommand() &&
+		len(strings.Fields(u.Message.Text)) > db.MaxPhraseWords && !isWordList(u.Message.Text)
+}
+
+// Handle extracts candidate words from the text and sends them as a checklist
+func (h ExtractHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	userDict, err := b.DB().GetUserDictionary(user.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(user.ID)).Msg("failed to get user dictionary")
+		return
+	}
+	skip := make([][]string, 0, len(extractCommonLevels)+1)
+	known := make([]string, 0, len(userDict))
+	for item := range userDict {
+		known = append(known, item.Word)
+	}
+	skip = append(skip, known)
+	for _, level := range extractCommonLevels {
+		words, err := wordlist.Words(level)
+		if err != nil {
+			log.Error().Err(err).Str("level", string(level)).Msg("failed to get word list")
+			continue
+		}
+		skip = append(skip, words)
+	}
+
+	words := vocab.Extract(u.Message.Text, extractMaxWords, skip...)
+	if len(words) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, "I didn't find new words in this text"))
+		return
+	}
+	msg := tgbotapi.NewMessage(u.Message.Chat.ID, "Pick words to add to your dictionary:")
+	msg.ReplyMarkup = getExtractKeyboard(words)
+	_, _ = b.Send(msg)
+}
+
+// getExtractKeyboard returns checklist keyboard with unchecked words
+func getExtractKeyboard(words []string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, word := range words {
+		data := fmt.Sprintf("%v|%v|%v", callbackIDExtract, extractActionWord, word)
+		if len(data) > callbackDataLimit {
+			continue
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%v %v", markUnchecked, word), data))
+		if len(row) == extractButtonsInRow {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) != 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(
+			"Add selected", fmt.Sprintf("%v|%v", callbackIDExtract, extractActionAdd),
+		),
+		tgbotapi.NewInlineKeyboardButtonData(
+			"Cancel", fmt.Sprintf("%v|%v", callbackIDExtract, extractActionCancel),
+		),
+	))
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// ExtractReplyHandler handles checklist of words extracted from a text,
+// selection is kept in the keyboard itself
+type ExtractReplyHandler struct {
+	imports BulkImportHandler
+	neverPassthorugh
+}
+
+// Match returns true if update is extracted words checklist callback
+func (h ExtractReplyHandler) Match(u tgbotapi.Update) bool {
+	return u.CallbackQuery != nil && u.CallbackQuery.Message != nil &&
+		strings.HasPrefix(u.CallbackQuery.Data, callbackIDExtract+"|")
+}
+
+// Handle toggles words in checklist or adds selected ones
+func (h ExtractReplyHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	message := u.CallbackQuery.Message
+	markup := message.ReplyMarkup
+	if markup == nil {
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, ""))
+		return
+	}
+	action := strings.SplitN(strings.TrimPrefix(u.CallbackQuery.Data, callbackIDExtract+"|"), "|", 2)[0]
+	switch action {
+	case extractActionWord:
+		toggleChecklistButton(markup, u.CallbackQuery.Data)
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, ""))
+		_, _ = b.Send(tgbotapi.NewEditMessageReplyMarkup(message.Chat.ID, message.MessageID, *markup))
+	case extractActionAdd:
+		words := checkedChecklistWords(markup)
+		if len(words) == 0 {
+			_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Select words first"))
+			return
+		}
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, ""))
+		_, _ = b.Send(tgbotapi.NewEditMessageText(
+			message.Chat.ID, message.MessageID, fmt.Sprintf("Selected words: %v", strings.Join(words, ", ")),
+		))
+		h.imports.startImport(b, user.ID, message.Chat.ID, words)
+	case extractActionCancel:
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, ""))
+		_, _ = b.Send(tgbotapi.NewDeleteMessage(message.Chat.ID, message.MessageID))
+	default:
+		log.Error().Str("data", u.CallbackQuery.Data).Msg("invalid extract callback")
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, ""))
+	}
+}
+
+// NewExtractReplyHandler creates ExtractReplyHandler
+func NewExtractReplyHandler(imports BulkImportHandler) ExtractReplyHandler {
+	return ExtractReplyHandler{imports: imports}
+}
+
+// toggleChecklistButton switches mark of the button with the data
+func toggleChecklistButton(markup *tgbotapi.InlineKeyboardMarkup, data string) {
+	for _, row := range markup.InlineKeyboard {
+		for i, button := range row {
+			if button.CallbackData == nil || *button.CallbackData != data {
+				continue
+			}
+			if strings.HasPrefix(button.Text, markChecked) {
+				row[i].Text = markUnchecked + strings.TrimPrefix(button.Text, markChecked)
+			} else {
+				row[i].Text = markChecked + strings.TrimPrefix(button.Text, markUnchecked)
+			}
+		}
+	}
+}
+
+// checkedChecklistWords returns words of checked buttons
+func checkedChecklistWords(markup *tgbotapi.InlineKeyboardMarkup) []string {
+	prefix := fmt.Sprintf("%v|%v|", callbackIDExtract, extractActionWord)
+	var words []string
+	for _, row := range markup.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData == nil || !strings.HasPrefix(*button.CallbackData, prefix) {
+				continue
+			}
+			if strings.HasPrefix(button.Text, markChecked) {
+				words = append(words, strings.TrimPrefix(*button.CallbackData, prefix))
+			}
+		}
+	}
+	return words
+}
@@ -0,0 +1,143 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/importer"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// fileImportCommand is a document caption starting import of words with translations
+const fileImportCommand = "/import"
+
+// fileImportConflictsLimit limits number of conflicts listed in the report
+const fileImportConflictsLimit = 20
+
+const fileImportHelp = "Send a CSV/TSV file (e.g. exported from Anki) with caption:\n" +
+	"/import word=1 translation=2 sep=tab header dry_run overwrite\n" +
+	"word, translation — column numbers, translation=0 if there are no translations\n" +
+	"sep — tab, comma or semicolon, detected by default\n" +
+	"header — skip the first line\n" +
+	"dry_run — only show what would be changed\n" +
+	"overwrite — replace your translations of existing words"
+
+// FileImportHandler imports words with translations from documents sent with /import caption
+type FileImportHandler struct {
+	words WordHandler
+	neverPassthorugh
+}
+
+// Match returns true if update is a document with /import caption or /import command
+func (h FileImportHandler) Match(u tgbotapi.Update) bool {
+	if u.Message == nil {
+		return false
+	}
+	if u.Message.Document != nil {
+		return strings.HasPrefix(u.Message.Caption, fileImportCommand)
+	}
+	return u.Message.Command() == "import"
+}
+
+// Handle parses the document and imports it in background
+func (h FileImportHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	chatID := u.Message.Chat.ID
+	if u.Message.Document == nil {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, fileImportHelp))
+		return
+	}
+	opts, err := importer.ParseOptions(strings.TrimPrefix(u.Message.Caption, fileImportCommand))
+	if err != nil {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("%v\n\n%v", err, fileImportHelp)))
+		return
+	}
+	data, err := downloadDocument(ctx, b, u.Message.Document)
+	if err != nil {
+		log.Error().Err(err).Str("file", u.Message.Document.FileName).Msg("failed to read document")
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, I can't read this file"))
+		return
+	}
+	records, err := importer.Parse(bytes.NewReader(data), opts)
+	if err != nil {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Sorry, I can't parse this file: %v", err)))
+		return
+	}
+	if len(records) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, I didn't find any words"))
+		return
+	}
+	if !opts.DryRun {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Importing %d words...", len(records))))
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), importTimeout)
+		defer cancel()
+		report, err := importer.Import(ctx, b.DB(), h.words.lookup, user.ID, records, opts)
+		if err != nil {
+			log.Error().Err(err).Int64("user", int64(user.ID)).Msg("failed to import file")
+			_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, import failed"))
+			return
+		}
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, getFileImportReportText(report)))
+	}()
+}
+
+// getFileImportReportText returns import report message text
+func getFileImportReportText(report importer.Report) string {
+	lines := []string{"Import finished"}
+	if report.DryRun {
+		lines = []string{"Dry run, nothing is changed"}
+	}
+	for _, group := range []struct {
+		title  string
+		status importer.Status
+	}{
+		{"Added", importer.StatusAdded},
+		{"Translations updated", importer.StatusUpdated},
+		{"Already in your dictionary", importer.StatusExists},
+		{"Unknown", importer.StatusUnknown},
+		{"Failed", importer.StatusFailed},
+		{"Not processed, import timed out", importer.StatusSkipped},
+	} {
+		words := report.Words(group.status)
+		if len(words) == 0 {
+			continue
+		}
+		if len(words) > importReportListLimit {
+			words = append(words[:importReportListLimit:importReportListLimit], "...")
+		}
+		lines = append(lines, fmt.Sprintf(
+			"%v (%d): %v", group.title, report.Count(group.status), strings.Join(words, ", "),
+		))
+	}
+	var conflicts []string
+	for _, res := range report.Results {
+		if res.Status == importer.StatusConflict && len(conflicts) < fileImportConflictsLimit {
+			conflicts = append(conflicts, fmt.Sprintf(
+				"%v: %v → %v", res.Word, strings.Join(res.Existing, ", "), strings.Join(res.Translations, ", "),
+			))
+		}
+	}
+	if len(conflicts) != 0 {
+		lines = append(lines, fmt.Sprintf(
+			"Conflicts, your translations are kept, use overwrite to replace them (%d):\n%v",
+			report.Count(importer.StatusConflict), strings.Join(conflicts, "\n"),
+		))
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// NewFileImportHandler creates FileImportHandler
+func NewFileImportHandler(words WordHandler) FileImportHandler {
+	return FileImportHandler{words: words}
+}
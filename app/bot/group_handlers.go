@@ -0,0 +1,320 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/quiz"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// groupAddWordsLimit limits number of words added to group list by a single command
+	groupAddWordsLimit = 20
+	// groupAddTimeout limits time spent on looking up words added to group list
+	groupAddTimeout = 2 * time.Minute
+	// groupWordsListLimit limits number of words shown by /words
+	groupWordsListLimit = 100
+	// groupLeaderboardSize is a number of members shown in leaderboard
+	groupLeaderboardSize = 10
+)
+
+const groupHelp = "I can run vocabulary quizzes for the whole group:\n" +
+	"/addwords word1, word2 — add words to the group list\n" +
+	"/removeword word — remove a word from the list\n" +
+	"/words — show the group list\n" +
+	"/quiz — post a question, the first correct answer scores a point\n" +
+	"/leaderboard — show this week rankings"
+
+// GroupHandler handles messages and callbacks in group chats,
+// it should be placed before private chat handlers so they never get group updates
+type GroupHandler struct {
+	words WordHandler
+	neverPassthorugh
+}
+
+// Match returns true if update is a message or a callback from a group chat
+func (h GroupHandler) Match(u tgbotapi.Update) bool {
+	if u.Message != nil {
+		return !u.Message.Chat.IsPrivate()
+	}
+	return u.CallbackQuery != nil && u.CallbackQuery.Message != nil && !u.CallbackQuery.Message.Chat.IsPrivate()
+}
+
+// Handle runs group command, other messages are ignored
+func (h GroupHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	if u.CallbackQuery != nil {
+		if strings.HasPrefix(u.CallbackQuery.Data, callbackIDGroupQuiz+"|") {
+			h.handleAnswer(b, u.CallbackQuery)
+		}
+		return
+	}
+	chat := u.Message.Chat
+	switch u.Message.Command() {
+	case "start", "help":
+		_, _ = b.Send(tgbotapi.NewMessage(chat.ID, groupHelp))
+	case "addwords":
+		h.addWords(b, chat, u.Message.CommandArguments())
+	case "removeword":
+		h.removeWord(b, chat, u.Message.CommandArguments())
+	case "words":
+		h.sendWords(b, chat)
+	case "quiz":
+		h.sendQuiz(b, chat)
+	case "leaderboard":
+		h.sendLeaderboard(b, chat)
+	}
+}
+
+// getGroup returns group data, new group is returned if it's not saved yet
+func getGroup(b Bot, chat *tgbotapi.Chat) (db.Group, error) {
+	group, err := b.DB().GetGroup(db.ChatID(chat.ID))
+	if errors.Is(err, db.ErrNotFound) {
+		return db.Group{ID: db.ChatID(chat.ID), Title: chat.Title}, nil
+	}
+	return group, err
+}
+
+// addWords looks words up in background and adds known ones to group list
+func (h GroupHandler) addWords(b Bot, chat *tgbotapi.Chat, args string) {
+	words := tokenizeWordList(args)
+	if len(words) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(chat.ID, "Usage: /addwords word1, word2"))
+		return
+	}
+	if len(words) > groupAddWordsLimit {
+		_, _ = b.Send(tgbotapi.NewMessage(chat.ID, fmt.Sprintf(
+			"Sorry, up to %d words can be added at once", groupAddWordsLimit,
+		)))
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), groupAddTimeout)
+		defer cancel()
+		var known, unknown []string
+		for _, word := range words {
+			item, err := h.words.lookup.GetNormalized(ctx, word, b.DB())
+			if err != nil {
+				log.Error().Err(err).Str("word", word).Msg("failed to get word data")
+			}
+			if item == nil {
+				unknown = append(unknown, word)
+				continue
+			}
+			known = append(known, item.Word)
+		}
+		// group is fetched after lookups so concurrent changes aren't lost
+		group, err := getGroup(b, chat)
+		if err != nil {
+			log.Error().Err(err).Int64("chat", chat.ID).Msg("failed to get group")
+			return
+		}
+		var added, skipped []string
+		for _, word := range known {
+			if group.AddWord(word) {
+				added = append(added, word)
+			} else {
+				skipped = append(skipped, word)
+			}
+		}
+		if len(added) != 0 {
+			if err := b.DB().SaveGroup(group); err != nil {
+				log.Error().Err(err).Int64("chat", chat.ID).Msg("failed to save group")
+				_, _ = b.Send(tgbotapi.NewMessage(chat.ID, "Sorry, words were not saved"))
+				return
+			}
+		}
+		lines := make([]string, 0, 3)
+		if len(added) != 0 {
+			lines = append(lines, fmt.Sprintf("Added (%d): %v", len(added), strings.Join(added, ", ")))
+		}
+		if len(skipped) != 0 {
+			lines = append(lines, fmt.Sprintf(
+				"Already in the list or the list is full (%d): %v", len(skipped), strings.Join(skipped, ", "),
+			))
+		}
+		if len(unknown) != 0 {
+			lines = append(lines, fmt.Sprintf("Unknown (%d): %v", len(unknown), strings.Join(unknown, ", ")))
+		}
+		_, _ = b.Send(tgbotapi.NewMessage(chat.ID, strings.Join(lines, "\n")))
+	}()
+}
+
+// removeWord removes word from group list
+func (h GroupHandler) removeWord(b Bot, chat *tgbotapi.Chat, word string) {
+	word = db.NormalizeWord(word)
+	if word == "" {
+		_, _ = b.Send(tgbotapi.NewMessage(chat.ID, "Usage: /removeword word"))
+		return
+	}
+	group, err := getGroup(b, chat)
+	if err != nil {
+		log.Error().Err(err).Int64("chat", chat.ID).Msg("failed to get group")
+		return
+	}
+	if !group.RemoveWord(word) {
+		_, _ = b.Send(tgbotapi.NewMessage(chat.ID, fmt.Sprintf("«%v» is not in the group list", word)))
+		return
+	}
+	if err := b.DB().SaveGroup(group); err != nil {
+		log.Error().Err(err).Int64("chat", chat.ID).Msg("failed to save group")
+		return
+	}
+	_, _ = b.Send(tgbotapi.NewMessage(chat.ID, fmt.Sprintf("«%v» is removed from the group list", word)))
+}
+
+// sendWords sends group word list
+func (h GroupHandler) sendWords(b Bot, chat *tgbotapi.Chat) {
+	group, err := getGroup(b, chat)
+	if err != nil {
+		log.Error().Err(err).Int64("chat", chat.ID).Msg("failed to get group")
+		return
+	}
+	if len(group.Words) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(chat.ID, "The group list is empty, add words with /addwords"))
+		return
+	}
+	words := append([]string{}, group.Words...)
+	sort.Strings(words)
+	text := fmt.Sprintf("Group words (%d):\n", len(words))
+	if len(words) > groupWordsListLimit {
+		words = append(words[:groupWordsListLimit:groupWordsListLimit], "...")
+	}
+	_, _ = b.Send(tgbotapi.NewMessage(chat.ID, text+strings.Join(words, ", ")))
+}
+
+// sendQuiz posts quiz for a random word from group list
+func (h GroupHandler) sendQuiz(b Bot, chat *tgbotapi.Chat) {
+	group, err := getGroup(b, chat)
+	if err != nil {
+		log.Error().Err(err).Int64("chat", chat.ID).Msg("failed to get group")
+		return
+	}
+	q, err := quiz.NewGroup(b.DB(), group)
+	if err != nil {
+		if errors.Is(err, quiz.ErrEmptyDictionary) || errors.Is(err, quiz.ErrNotEnoughWords) {
+			_, _ = b.Send(tgbotapi.NewMessage(chat.ID, "Add some words to the group list with /addwords first"))
+			return
+		}
+		log.Error().Err(err).Int64("chat", chat.ID).Msg("failed to generate group quiz")
+		return
+	}
+	msg := tgbotapi.NewMessage(chat.ID, getGroupQuizText(q, ""))
+	msg.ParseMode = "html"
+	msg.ReplyMarkup = getGroupQuizKeyboard(q)
+	_, _ = b.Send(msg)
+}
+
+// getGroupQuizText returns group quiz message text, winner name is shown for closed quizzes
+func getGroupQuizText(quiz db.GroupQuiz, winner string) string {
+	view := db.Quiz{DisplayWord: quiz.DisplayWord, Choices: quiz.Choices}
+	if quiz.IsClosed() {
+		view.Result = &db.QuizResult{Choice: quiz.Answers[*quiz.Winner], Correct: true}
+	}
+	text, err := GetQuizMessageText(view)
+	if err != nil {
+		log.Error().Err(err).Str("quiz", quiz.ID).Msg("failed to get text for message")
+	}
+	if quiz.IsClosed() {
+		return text + fmt.Sprintf("\n🏆 %v answered first", html.EscapeString(winner))
+	}
+	return text + "\nEveryone can answer once, the first correct answer scores a point"
+}
+
+// getGroupQuizKeyboard returns keyboard with group quiz choices
+func getGroupQuizKeyboard(quiz db.GroupQuiz) tgbotapi.InlineKeyboardMarkup {
+	buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(quiz.Choices))
+	for idx := range quiz.Choices {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%d", idx+1),
+			fmt.Sprintf("%v|%v|%d", callbackIDGroupQuiz, quiz.ID, idx)),
+		)
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(buttons...))
+}
+
+// getMemberName returns name of a group member shown in messages
+func getMemberName(u *tgbotapi.User) string {
+	if u.UserName != "" {
+		return "@" + u.UserName
+	}
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
+}
+
+// handleAnswer saves group quiz answer and closes the quiz if it's correct
+func (h GroupHandler) handleAnswer(b Bot, query *tgbotapi.CallbackQuery) {
+	quizID, choice, err := QuizReplyHandler{}.parseQuery(tgbotapi.Update{CallbackQuery: query})
+	if err != nil {
+		log.Error().Err(err).Str("query", query.Data).Msg("failed to parse callback query")
+		return
+	}
+	quiz, err := b.DB().GetGroupQuiz(quizID)
+	if err != nil || quiz.Chat != db.ChatID(query.Message.Chat.ID) {
+		log.Error().Err(err).Str("quiz", quizID).Msg("failed to get group quiz")
+		_, _ = b.SendCallback(tgbotapi.NewCallback(query.ID, "Unknown quiz"))
+		return
+	}
+	name := getMemberName(query.From)
+	correct, err := quiz.SetAnswer(db.UserID(query.From.ID), name, choice, b.DB())
+	switch {
+	case errors.Is(err, db.ErrAlreadyAnswered):
+		_, _ = b.SendCallback(tgbotapi.NewCallback(query.ID, "You have already answered"))
+		return
+	case errors.Is(err, db.ErrQuizClosed):
+		_, _ = b.SendCallback(tgbotapi.NewCallback(query.ID, "This quiz is already over"))
+		return
+	case err != nil:
+		log.Error().Err(err).Str("quiz", quiz.ID).Int("choice", choice).Msg("failed to set group quiz answer")
+		_, _ = b.SendCallback(tgbotapi.NewCallback(query.ID, "Error happened"))
+		return
+	}
+	if !correct {
+		_, _ = b.SendCallback(tgbotapi.NewCallback(query.ID, "Wrong!"))
+		return
+	}
+	_, _ = b.SendCallback(tgbotapi.NewCallback(query.ID, "Correct! +1 point"))
+	edit := tgbotapi.NewEditMessageText(query.Message.Chat.ID, query.Message.MessageID, getGroupQuizText(quiz, name))
+	edit.ParseMode = "html"
+	_, _ = b.Send(edit)
+}
+
+// sendLeaderboard sends current week group rankings
+func (h GroupHandler) sendLeaderboard(b Bot, chat *tgbotapi.Chat) {
+	week := db.Week(time.Now())
+	scores, err := b.DB().GetGroupScores(db.ChatID(chat.ID), week)
+	if err != nil {
+		log.Error().Err(err).Int64("chat", chat.ID).Msg("failed to get group scores")
+		return
+	}
+	if len(scores) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(chat.ID, "Nobody scored this week yet, start with /quiz"))
+		return
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Points != scores[j].Points {
+			return scores[i].Points > scores[j].Points
+		}
+		return scores[i].Username < scores[j].Username
+	})
+	if len(scores) > groupLeaderboardSize {
+		scores = scores[:groupLeaderboardSize]
+	}
+	lines := []string{fmt.Sprintf("Leaderboard for week %v:", week)}
+	for idx, s := range scores {
+		lines = append(lines, fmt.Sprintf("%d. %v — %d", idx+1, s.Username, s.Points))
+	}
+	_, _ = b.Send(tgbotapi.NewMessage(chat.ID, strings.Join(lines, "\n")))
+}
+
+// NewGroupHandler creates GroupHandler
+func NewGroupHandler(words WordHandler) GroupHandler {
+	return GroupHandler{words: words}
+}
@@ -9,12 +9,29 @@ import (
 const (
 	callbackIDQuizReply = "qr"
 	callbackIDSettings  = "st"
+	callbackIDCourse    = "cr"
+	callbackIDLookup    = "lk"
+	callbackIDExtract   = "ex"
+	callbackIDEditItem  = "ed"
+	callbackIDAddWord   = "ad"
+	callbackIDGroupQuiz = "gq"
 )
 
+// checklist keyboard marks
+const (
+	markChecked   = "✅"
+	markUnchecked = "⬜️"
+)
+
+// callbackDataLimit is a maximum size of callback data allowed by telegram
+const callbackDataLimit = 64
+
 // Bot describes bot for handlers
 type Bot interface {
 	Send(tgbotapi.Chattable) (tgbotapi.Message, error)
 	SendCallback(tgbotapi.CallbackConfig) (*tgbotapi.APIResponse, error)
+	Request(tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	GetFileDirectURL(fileID string) (string, error)
 	DB() db.Storage
 }
 
@@ -0,0 +1,350 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	importMaxWords        = 200
+	importMaxFileSize     = 1 << 20
+	importWorkers         = 3
+	importRateInterval    = 300 * time.Millisecond
+	importProgressEvery   = 2 * time.Second
+	importTimeout         = 15 * time.Minute
+	importReportListLimit = 50
+)
+
+// BulkImportHandler adds words from multi-line messages and uploaded .txt/.csv documents
+type BulkImportHandler struct {
+	words WordHandler
+	neverPassthorugh
+}
+
+// Match returns true if update is a list of words or a text document
+func (h BulkImportHandler) Match(u tgbotapi.Update) bool {
+	if u.Message == nil || u.Message.IsCommand() {
+		return false
+	}
+	if doc := u.Message.Document; doc != nil {
+		ext := strings.ToLower(path.Ext(doc.FileName))
+		return ext == ".txt" || ext == ".csv"
+	}
+	return isWordList(u.Message.Text)
+}
+
+// Handle collects words from the update and imports them in background
+func (h BulkImportHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	chatID := u.Message.Chat.ID
+	var words []string
+	if doc := u.Message.Document; doc != nil {
+		var err error
+		words, err = h.readDocument(ctx, b, doc)
+		if err != nil {
+			log.Error().Err(err).Str("file", doc.FileName).Msg("failed to read document")
+			_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, I can't read this file"))
+			return
+		}
+	} else {
+		words = tokenizeWordList(u.Message.Text)
+	}
+	if len(words) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, I didn't find any words"))
+		return
+	}
+	if len(words) > importMaxWords {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"Sorry, only up to %d words can be imported at once", importMaxWords,
+		)))
+		return
+	}
+	h.startImport(b, user.ID, chatID, words)
+}
+
+// startImport imports words in background, import is limited by importTimeout
+func (h BulkImportHandler) startImport(b Bot, userID db.UserID, chatID int64, words []string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), importTimeout)
+		defer cancel()
+		h.importWords(ctx, b, userID, chatID, words)
+	}()
+}
+
+// readDocument downloads document and returns words from it
+func (h BulkImportHandler) readDocument(ctx context.Context, b Bot, doc *tgbotapi.Document) ([]string, error) {
+	data, err := downloadDocument(ctx, b, doc)
+	if err != nil {
+		return nil, err
+	}
+	if strings.ToLower(path.Ext(doc.FileName)) == ".csv" {
+		return tokenizeCSV(data)
+	}
+	return tokenizeWordList(string(data)), nil
+}
+
+// downloadDocument returns content of the document sent to the bot
+func downloadDocument(ctx context.Context, b Bot, doc *tgbotapi.Document) ([]byte, error) {
+	if doc.FileSize > importMaxFileSize {
+		return nil, errors.New("file is too big")
+	}
+	url, err := b.GetFileDirectURL(doc.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("get file url: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, importMaxFileSize))
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	return data, nil
+}
+
+// importReport holds results of words import
+type importReport struct {
+	added   []string
+	known   []string
+	unknown []string
+	failed  []string
+	skipped []string
+}
+
+// importWords looks words up concurrently and adds them to user dictionary,
+// progress message is updated while words are processed, words left when context is done are reported as skipped
+func (h BulkImportHandler) importWords(ctx context.Context, b Bot, userID db.UserID, chatID int64, words []string) {
+	report := importReport{}
+	userDict, err := b.DB().GetUserDictionary(userID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to get user dictionary")
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, something went wrong"))
+		return
+	}
+	existing := make(map[string]struct{}, len(userDict))
+	for item := range userDict {
+		existing[db.NormalizeWord(item.Word)] = struct{}{}
+	}
+	toLookup := make([]string, 0, len(words))
+	for _, word := range words {
+		if _, ok := existing[word]; ok {
+			report.known = append(report.known, word)
+			continue
+		}
+		toLookup = append(toLookup, word)
+	}
+
+	progress, err := b.Send(tgbotapi.NewMessage(chatID, importProgressText(0, len(toLookup))))
+	if err != nil {
+		return
+	}
+
+	var mx sync.Mutex
+	var done int
+	lastProgress := time.Now()
+	ticker := time.NewTicker(importRateInterval)
+	defer ticker.Stop()
+	jobs := make(chan string)
+	wg := sync.WaitGroup{}
+	for i := 0; i < importWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for word := range jobs {
+				added, item, err := h.importWord(ctx, b.DB(), userID, word)
+				mx.Lock()
+				switch {
+				case err != nil:
+					log.Error().Err(err).Str("word", word).Msg("failed to import word")
+					report.failed = append(report.failed, word)
+				case item == nil:
+					report.unknown = append(report.unknown, word)
+				case added:
+					report.added = append(report.added, item.Word)
+				default:
+					report.known = append(report.known, item.Word)
+				}
+				done++
+				current, notify := done, time.Since(lastProgress) > importProgressEvery
+				if notify {
+					lastProgress = time.Now()
+				}
+				mx.Unlock()
+				if notify {
+					_, _ = b.Send(tgbotapi.NewEditMessageText(
+						chatID, progress.MessageID, importProgressText(current, len(toLookup)),
+					))
+				}
+			}
+		}()
+	}
+	skipped := feedWords(ctx, jobs, ticker.C, toLookup)
+	close(jobs)
+	wg.Wait()
+	report.skipped = skipped
+
+	_, _ = b.Send(tgbotapi.NewEditMessageText(chatID, progress.MessageID, importProgressText(done, len(toLookup))))
+	_, _ = b.Send(tgbotapi.NewMessage(chatID, report.text()))
+}
+
+// feedWords sends words to jobs at the ticker rate, returns words left unsent when context is done
+func feedWords(ctx context.Context, jobs chan<- string, tick <-chan time.Time, words []string) []string {
+	for i, word := range words {
+		select {
+		case <-tick:
+			jobs <- word
+		case <-ctx.Done():
+			return words[i:]
+		}
+	}
+	return nil
+}
+
+// importWord adds word to user dictionary, returns false if it was already there
+// and nil item if the word is unknown
+func (h BulkImportHandler) importWord(
+	ctx context.Context,
+	storage db.Storage,
+	userID db.UserID,
+	word string,
+) (bool, *db.DictionaryItem, error) {
+	item, err := h.words.lookup.GetNormalized(ctx, word, storage)
+	if err != nil || item == nil {
+		return false, item, err
+	}
+	_, added, err := db.AddUserItem(storage, userID, item.Word)
+	return added, item, err
+}
+
+// text returns report message text
+func (r importReport) text() string {
+	lines := []string{fmt.Sprintf("Import finished, %d words added", len(r.added))}
+	for _, group := range []struct {
+		title string
+		words []string
+	}{
+		{"Added", r.added},
+		{"Already in your dictionary", r.known},
+		{"Unknown", r.unknown},
+		{"Failed", r.failed},
+		{"Not processed, import timed out", r.skipped},
+	} {
+		if len(group.words) == 0 {
+			continue
+		}
+		words := group.words
+		if len(words) > importReportListLimit {
+			words = append(words[:importReportListLimit:importReportListLimit], "...")
+		}
+		lines = append(lines, fmt.Sprintf("%v (%d): %v", group.title, len(group.words), strings.Join(words, ", ")))
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+func importProgressText(done, total int) string {
+	return fmt.Sprintf("Importing words: %d/%d", done, total)
+}
+
+// NewBulkImportHandler creates BulkImportHandler
+func NewBulkImportHandler(words WordHandler) BulkImportHandler {
+	return BulkImportHandler{words: words}
+}
+
+// isWordList returns true if text has several lines and every line is a short list of words or phrases
+func isWordList(text string) bool {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	if len(lines) < 2 {
+		return false
+	}
+	for _, line := range lines {
+		for _, token := range splitListLine(line) {
+			if len(strings.Fields(token)) > db.MaxPhraseWords {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// tokenizeWordList splits text by lines and list separators,
+// returns unique normalized words and phrases in order of appearance
+func tokenizeWordList(text string) []string {
+	var tokens []string
+	for _, line := range strings.Split(text, "\n") {
+		tokens = append(tokens, splitListLine(line)...)
+	}
+	return uniqueWords(tokens)
+}
+
+// tokenizeCSV returns words from the first column of CSV data
+func tokenizeCSV(data []byte) ([]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var tokens []string
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse csv: %w", err)
+		}
+		if len(record) != 0 {
+			tokens = append(tokens, record[0])
+		}
+	}
+	return uniqueWords(tokens), nil
+}
+
+func splitListLine(line string) []string {
+	return strings.FieldsFunc(line, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\t'
+	})
+}
+
+// uniqueWords normalizes tokens and skips duplicates, long phrases and tokens without letters
+func uniqueWords(tokens []string) []string {
+	seen := make(map[string]struct{}, len(tokens))
+	result := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		word := db.NormalizeWord(strings.Trim(token, "\"'.!?()[]-•*"))
+		if word == "" || len(strings.Fields(word)) > db.MaxPhraseWords || strings.IndexFunc(word, unicode.IsLetter) == -1 {
+			continue
+		}
+		if _, ok := seen[word]; ok {
+			continue
+		}
+		seen[word] = struct{}{}
+		result = append(result, word)
+	}
+	return result
+}
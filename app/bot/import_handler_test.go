@@ -0,0 +1,129 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsWordList(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"single line", "cat, dog", false},
+		{"lines", "cat\ndog", true},
+		{"separators", "cat; dog\nfox,\tbird", true},
+		{"phrases", "look after\ngive up", true},
+		{"long phrase", "cat\nthis line is a sentence and not a phrase", false},
+		{"surrounding spaces", "\n  cat\ndog\n\n", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, isWordList(tc.text))
+		})
+	}
+}
+
+func TestTokenizeWordList(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"lines", "cat\ndog", []string{"cat", "dog"}},
+		{"separators", "cat, dog;fox\tbird", []string{"cat", "dog", "fox", "bird"}},
+		{"duplicates", "Cat\ncat\nCAT, dog", []string{"cat", "dog"}},
+		{"punctuation", "- cat\n• dog!\n\"fox\"", []string{"cat", "dog", "fox"}},
+		{"phrases", "Look  After\ngive up", []string{"look after", "give up"}},
+		{"no letters", "123\n---\ncat", []string{"cat"}},
+		{"long phrase", "this line is a sentence and not a phrase\ncat", []string{"cat"}},
+		{"empty", "\n\n", []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tokenizeWordList(tc.text))
+		})
+	}
+}
+
+func TestTokenizeCSV(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"first column", "cat,кот\ndog,собака", []string{"cat", "dog"}},
+		{"uneven rows", "cat\ndog,собака,пёс\n", []string{"cat", "dog"}},
+		{"quoted", "\"look after\",\"присматривать, заботиться\"\nCat", []string{"look after", "cat"}},
+		{"lazy quotes", "ca\"t,кот", []string{"ca\"t"}},
+		{"empty", "", []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			words, err := tokenizeCSV([]byte(tc.data))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, words)
+		})
+	}
+}
+
+func TestImportReportText(t *testing.T) {
+	report := importReport{added: []string{"cat", "dog"}, unknown: []string{"zzz"}, skipped: []string{"fox"}}
+	assert.Equal(t, "Import finished, 2 words added\n\n"+
+		"Added (2): cat, dog\n\n"+
+		"Unknown (1): zzz\n\n"+
+		"Not processed, import timed out (1): fox", report.text())
+
+	long := importReport{known: strings.Fields(strings.Repeat("cat ", importReportListLimit+1))}
+	assert.True(t, strings.HasSuffix(long.text(), "cat, ..."))
+}
+
+func TestImportWords(t *testing.T) {
+	lookuper := &stubLookuper{items: map[string]db.DictionaryItem{"cat": {Word: "cat"}}}
+	h := NewBulkImportHandler(NewWordHandler(lookuper))
+
+	t.Run("success", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		require.NoError(t, storage.Save(db.DictionaryItem{Word: "dog"}))
+		require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: 1, Word: "dog"}))
+		b := newStubBot(storage)
+
+		h.importWords(context.Background(), b, 1, 1, []string{"cat", "dog", "zzz"})
+		_, err := storage.GetUserItem(1, "cat")
+		assert.NoError(t, err)
+		texts := b.texts()
+		require.NotEmpty(t, texts)
+		assert.Equal(t, "Import finished, 1 words added\n\n"+
+			"Added (1): cat\n\n"+
+			"Already in your dictionary (1): dog\n\n"+
+			"Unknown (1): zzz", texts[len(texts)-1])
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		b := newStubBot(storage)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		done := make(chan struct{})
+		go func() {
+			h.importWords(ctx, b, 1, 1, []string{"cat", "zzz"})
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("import is not stopped")
+		}
+		_, err := storage.GetUserItem(1, "cat")
+		assert.ErrorIs(t, err, db.ErrNotFound)
+		texts := b.texts()
+		require.NotEmpty(t, texts)
+		assert.Equal(t, "Import finished, 0 words added\n\n"+
+			"Not processed, import timed out (2): cat, zzz", texts[len(texts)-1])
+	})
+}
@@ -0,0 +1,262 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/lookup"
+	"github.com/rbhz/tg-dictionary/app/quiz"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// inlineDebounce is a delay before answering inline query,
+	// queries replaced by newer ones from the same user during it are dropped
+	inlineDebounce = 500 * time.Millisecond
+	// inlineTimeout limits time spent on answering inline query
+	inlineTimeout = 10 * time.Second
+	// inlineMinQueryLength is a minimal length of looked up query
+	inlineMinQueryLength = 2
+	// inlineResultsLimit limits number of results for a single query
+	inlineResultsLimit = 5
+	// inlineCacheTTL is a time lookup results are kept in cache
+	inlineCacheTTL = 10 * time.Minute
+	// inlineCacheSize limits number of cached lookup results
+	inlineCacheSize = 1000
+	// inlineResultCacheTime is a time telegram may cache query results, in seconds
+	inlineResultCacheTime = 300
+	// inlineDescriptionTranslations limits number of translations in result description
+	inlineDescriptionTranslations = 3
+)
+
+// inlineCacheEntry is a cached lookup result, nil item means the word is unknown
+type inlineCacheEntry struct {
+	item    *db.DictionaryItem
+	expires time.Time
+}
+
+// inlineState holds lookup results cache and latest inline queries of users
+type inlineState struct {
+	mu     sync.Mutex
+	cache  map[string]inlineCacheEntry
+	latest map[int64]string
+}
+
+// setLatest saves query as the latest one from the user
+func (s *inlineState) setLatest(user int64, queryID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latest[user] = queryID
+}
+
+// isLatest returns true if there were no newer queries from the user, the query is forgotten then
+func (s *inlineState) isLatest(user int64, queryID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.latest[user] != queryID {
+		return false
+	}
+	delete(s.latest, user)
+	return true
+}
+
+// get returns cached lookup result
+func (s *inlineState) get(word string, now time.Time) (*db.DictionaryItem, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.cache[word]
+	if !ok || now.After(entry.expires) {
+		return nil, false
+	}
+	return entry.item, true
+}
+
+// put caches lookup result, expired entries are removed when cache is full
+func (s *inlineState) put(word string, item *db.DictionaryItem, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.cache) >= inlineCacheSize {
+		for w, entry := range s.cache {
+			if now.After(entry.expires) {
+				delete(s.cache, w)
+			}
+		}
+		if len(s.cache) >= inlineCacheSize {
+			s.cache = make(map[string]inlineCacheEntry, inlineCacheSize)
+		}
+	}
+	s.cache[word] = inlineCacheEntry{item: item, expires: now.Add(inlineCacheTTL)}
+}
+
+// InlineHandler answers inline queries with word cards, so words can be looked up in any chat
+type InlineHandler struct {
+	lookup lookup.Lookuper
+	state  *inlineState
+	neverPassthorugh
+}
+
+// Match returns true if update is inline query
+func (h InlineHandler) Match(u tgbotapi.Update) bool {
+	return u.InlineQuery != nil
+}
+
+// Handle answers inline query in background after debounce delay
+func (h InlineHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	query := u.InlineQuery
+	word := db.NormalizeWord(query.Query)
+	if len([]rune(word)) < inlineMinQueryLength || len(strings.Fields(word)) > db.MaxPhraseWords {
+		return
+	}
+	h.state.setLatest(query.From.ID, query.ID)
+	go func() {
+		time.Sleep(inlineDebounce)
+		if !h.state.isLatest(query.From.ID, query.ID) {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), inlineTimeout)
+		defer cancel()
+		results := make([]interface{}, 0, inlineResultsLimit)
+		for idx, item := range h.getItems(ctx, b.DB(), word) {
+			results = append(results, h.getResult(strconv.Itoa(idx), item))
+		}
+		_, _ = b.Request(tgbotapi.InlineConfig{
+			InlineQueryID: query.ID,
+			Results:       results,
+			CacheTime:     inlineResultCacheTime,
+		})
+	}()
+}
+
+// getItems returns looked up item for the query followed by saved words starting with it
+func (h InlineHandler) getItems(ctx context.Context, storage db.Storage, word string) []db.DictionaryItem {
+	items := make([]db.DictionaryItem, 0, inlineResultsLimit)
+	found := make(map[string]struct{}, inlineResultsLimit)
+	if item := h.lookupWord(ctx, storage, word); item != nil {
+		items = append(items, *item)
+		found[item.Word] = struct{}{}
+	}
+	// one more word is requested in case the looked up one is among them
+	known, err := storage.GetWordsByPrefix(word, inlineResultsLimit+1)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get dictionary words")
+		return items
+	}
+	for _, w := range known {
+		if len(items) == inlineResultsLimit {
+			break
+		}
+		if _, ok := found[w]; ok {
+			continue
+		}
+		item, err := storage.Get(w)
+		if err != nil {
+			log.Error().Err(err).Str("word", w).Msg("failed to get item")
+			continue
+		}
+		items = append(items, item)
+		found[w] = struct{}{}
+	}
+	return items
+}
+
+// lookupWord returns cached lookup result or looks the word up,
+// found data isn't saved until the word is added to a dictionary
+func (h InlineHandler) lookupWord(ctx context.Context, storage db.Storage, word string) *db.DictionaryItem {
+	if item, ok := h.state.get(word, time.Now()); ok {
+		return item
+	}
+	item, err := h.lookup.Preview(ctx, word, storage)
+	if err != nil {
+		log.Error().Err(err).Str("word", word).Msg("failed to get word data")
+		return nil
+	}
+	h.state.put(word, item, time.Now())
+	return item
+}
+
+// getResult returns inline query result with word card
+func (h InlineHandler) getResult(id string, item db.DictionaryItem) tgbotapi.InlineQueryResultArticle {
+	result := tgbotapi.NewInlineQueryResultArticleHTML(id, item.Word, GetItemMessageText(item, nil, 0))
+	translations := make([]string, 0, inlineDescriptionTranslations)
+	for _, t := range item.Translations {
+		if len(translations) == inlineDescriptionTranslations {
+			break
+		}
+		if t.Language == quiz.ChoiceLanguage {
+			translations = append(translations, t.Text)
+		}
+	}
+	result.Description = strings.Join(translations, ", ")
+	if result.Description == "" && len(item.Meanings) != 0 {
+		result.Description = item.Meanings[0].Definition
+	}
+	data := fmt.Sprintf("%v|%v", callbackIDAddWord, item.Word)
+	if len(data) <= callbackDataLimit {
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("➕ Add to my dictionary", data),
+		))
+		result.ReplyMarkup = &keyboard
+	}
+	return result
+}
+
+// NewInlineHandler creates InlineHandler
+func NewInlineHandler(words WordHandler) InlineHandler {
+	return InlineHandler{
+		lookup: words.lookup,
+		state: &inlineState{
+			cache:  make(map[string]inlineCacheEntry),
+			latest: make(map[int64]string),
+		},
+	}
+}
+
+// AddWordHandler adds word from a message sent in inline mode to dictionary of the user pressed the button
+type AddWordHandler struct {
+	lookup lookup.Lookuper
+	neverPassthorugh
+}
+
+// NewAddWordHandler creates AddWordHandler
+func NewAddWordHandler(words WordHandler) AddWordHandler {
+	return AddWordHandler{lookup: words.lookup}
+}
+
+// Match returns true if update is add word callback
+func (h AddWordHandler) Match(u tgbotapi.Update) bool {
+	return u.CallbackQuery != nil && strings.HasPrefix(u.CallbackQuery.Data, callbackIDAddWord+"|")
+}
+
+// Handle adds word to user dictionary, word data shown in inline mode is saved to storage now
+func (h AddWordHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	word := strings.TrimPrefix(u.CallbackQuery.Data, callbackIDAddWord+"|")
+	item, err := h.lookup.Get(ctx, word, b.DB())
+	if err != nil {
+		log.Error().Err(err).Str("word", word).Msg("failed to get word data")
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Error happened"))
+		return
+	}
+	// callback data comes from the client and providers may not know the word anymore
+	if item == nil {
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Unknown word"))
+		return
+	}
+	_, added, err := db.AddUserItem(b.DB(), db.UserID(u.CallbackQuery.From.ID), item.Word)
+	if err != nil {
+		log.Error().Err(err).Str("word", item.Word).Int64("user", u.CallbackQuery.From.ID).Msg("failed to save user item")
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Error happened"))
+		return
+	}
+	text := fmt.Sprintf("«%v» is already in your dictionary", item.Word)
+	if added {
+		text = fmt.Sprintf("«%v» is added to your dictionary", item.Word)
+	}
+	_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, text))
+}
@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInlineStateCache(t *testing.T) {
+	state := NewInlineHandler(WordHandler{}).state
+	now := time.Now()
+	item := &db.DictionaryItem{Word: "cat"}
+
+	_, ok := state.get("cat", now)
+	assert.False(t, ok)
+	state.put("cat", item, now)
+	state.put("zzz", nil, now)
+	cached, ok := state.get("cat", now.Add(time.Minute))
+	assert.True(t, ok)
+	assert.Equal(t, item, cached)
+	cached, ok = state.get("zzz", now)
+	assert.True(t, ok)
+	assert.Nil(t, cached)
+	_, ok = state.get("cat", now.Add(inlineCacheTTL+time.Second))
+	assert.False(t, ok)
+
+	t.Run("full", func(t *testing.T) {
+		state := NewInlineHandler(WordHandler{}).state
+		state.put("expired", nil, now.Add(-inlineCacheTTL-time.Second))
+		for i := 1; i < inlineCacheSize; i++ {
+			state.put(fmt.Sprintf("word%d", i), nil, now)
+		}
+		require.Len(t, state.cache, inlineCacheSize)
+		// expired entries are removed first
+		state.put("new", item, now)
+		assert.Len(t, state.cache, inlineCacheSize)
+		_, ok := state.cache["expired"]
+		assert.False(t, ok)
+		// cache is reset if all entries are fresh
+		state.put("newer", item, now)
+		assert.Len(t, state.cache, 1)
+		cached, ok := state.get("newer", now)
+		assert.True(t, ok)
+		assert.Equal(t, item, cached)
+	})
+}
+
+func TestInlineStateLatest(t *testing.T) {
+	state := NewInlineHandler(WordHandler{}).state
+	state.setLatest(1, "q1")
+	state.setLatest(1, "q2")
+	state.setLatest(2, "q3")
+	assert.False(t, state.isLatest(1, "q1"))
+	assert.True(t, state.isLatest(1, "q2"))
+	// the latest query is forgotten once answered
+	assert.False(t, state.isLatest(1, "q2"))
+	assert.True(t, state.isLatest(2, "q3"))
+}
+
+func TestInlineHandlerGetItems(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	for _, w := range []string{"apple", "apply", "application", "appoint", "approve", "approach", "banana"} {
+		require.NoError(t, storage.Save(db.DictionaryItem{Word: w}))
+	}
+	lookuper := &stubLookuper{items: map[string]db.DictionaryItem{"app": {Word: "app"}}}
+	h := NewInlineHandler(NewWordHandler(lookuper))
+
+	items := h.getItems(context.Background(), storage, "app")
+	words := make([]string, 0, len(items))
+	for _, item := range items {
+		words = append(words, item.Word)
+	}
+	assert.Equal(t, []string{"app", "apple", "application", "apply", "appoint"}, words)
+	// looked up word isn't saved
+	_, err := storage.Get("app")
+	assert.ErrorIs(t, err, db.ErrNotFound)
+
+	// lookup results are cached
+	h.getItems(context.Background(), storage, "app")
+	assert.Equal(t, 1, lookuper.lookups)
+
+	items = h.getItems(context.Background(), storage, "apple")
+	require.Len(t, items, 1)
+	assert.Equal(t, "apple", items[0].Word)
+	assert.Empty(t, h.getItems(context.Background(), storage, "zzz"))
+}
+
+func TestInlineHandlerDebounce(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	require.NoError(t, storage.Save(db.DictionaryItem{Word: "apple"}))
+	h := NewInlineHandler(NewWordHandler(&stubLookuper{}))
+	b := newStubBot(storage)
+	query := func(id, text string) tgbotapi.Update {
+		return tgbotapi.Update{InlineQuery: &tgbotapi.InlineQuery{ID: id, Query: text, From: &tgbotapi.User{ID: 1}}}
+	}
+
+	h.Handle(context.Background(), b, query("1", "a"))
+	h.Handle(context.Background(), b, query("2", "ap"))
+	h.Handle(context.Background(), b, query("3", "app"))
+	require.Eventually(t, func() bool { return b.requested() == 1 }, 5*inlineDebounce, 10*time.Millisecond)
+	time.Sleep(inlineDebounce)
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	require.Len(t, b.requests, 1)
+	answer, ok := b.requests[0].(tgbotapi.InlineConfig)
+	require.True(t, ok)
+	assert.Equal(t, "3", answer.InlineQueryID)
+	assert.Len(t, answer.Results, 1)
+}
+
+func TestInlineHandlerGetResult(t *testing.T) {
+	h := NewInlineHandler(WordHandler{})
+	item := db.DictionaryItem{
+		Word:     "cat",
+		Meanings: []db.Meaning{{PartOfSpeech: "noun", Definition: "a small animal"}},
+		Translations: []db.Translation{
+			{Text: "кот", Language: "ru"}, {Text: "Katze", Language: "de"},
+			{Text: "кошка", Language: "ru"}, {Text: "котик", Language: "ru"}, {Text: "киса", Language: "ru"},
+		},
+	}
+	result := h.getResult("0", item)
+	assert.Equal(t, "cat", result.Title)
+	assert.Equal(t, "кот, кошка, котик", result.Description)
+	require.NotNil(t, result.ReplyMarkup)
+	assert.Equal(t, callbackIDAddWord+"|cat", *result.ReplyMarkup.InlineKeyboard[0][0].CallbackData)
+
+	item.Translations = nil
+	assert.Equal(t, "a small animal", h.getResult("0", item).Description)
+}
+
+func TestAddWordHandler(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	lookuper := &stubLookuper{items: map[string]db.DictionaryItem{"cat": {Word: "cat"}}}
+	h := NewAddWordHandler(NewWordHandler(lookuper))
+	b := newStubBot(storage)
+	u := tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID: "1", Data: callbackIDAddWord + "|cat", From: &tgbotapi.User{ID: 1},
+	}}
+	require.True(t, h.Match(u))
+
+	h.Handle(context.Background(), b, u)
+	_, err := storage.Get("cat")
+	assert.NoError(t, err)
+	_, err = storage.GetUserItem(1, "cat")
+	assert.NoError(t, err)
+	require.Len(t, b.requests, 1)
+	assert.Equal(t, "«cat» is added to your dictionary", b.requests[0].(tgbotapi.CallbackConfig).Text)
+
+	t.Run("unknown word", func(t *testing.T) {
+		b := newStubBot(storage)
+		h.Handle(context.Background(), b, tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+			ID: "2", Data: callbackIDAddWord + "|zzz", From: &tgbotapi.User{ID: 1},
+		}})
+		_, err := storage.GetUserItem(1, "zzz")
+		assert.ErrorIs(t, err, db.ErrNotFound)
+		require.Len(t, b.requests, 1)
+		assert.Equal(t, "Unknown word", b.requests[0].(tgbotapi.CallbackConfig).Text)
+	})
+	t.Run("lemma", func(t *testing.T) {
+		lookuper := &stubLookuper{items: map[string]db.DictionaryItem{"cats": {Word: "cat"}}}
+		h := NewAddWordHandler(NewWordHandler(lookuper))
+		storage := db.NewInMemoryStorage()
+		b := newStubBot(storage)
+		h.Handle(context.Background(), b, tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+			ID: "3", Data: callbackIDAddWord + "|cats", From: &tgbotapi.User{ID: 1},
+		}})
+		_, err := storage.GetUserItem(1, "cat")
+		assert.NoError(t, err)
+		_, err = storage.GetUserItem(1, "cats")
+		assert.ErrorIs(t, err, db.ErrNotFound)
+		require.Len(t, b.requests, 1)
+		assert.Equal(t, "«cat» is added to your dictionary", b.requests[0].(tgbotapi.CallbackConfig).Text)
+	})
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/quiz"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// listWordsLimit limits number of words listed in a single message
+const listWordsLimit = 80
+
+// ListHandler handles /list command
+type ListHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if update is /list command
+func (h ListHandler) Match(u tgbotapi.Update) bool {
+	return u.Message != nil && u.Message.Command() == "list"
+}
+
+// Handle sends user dictionary words with their mastery, tags may be passed as arguments
+func (h ListHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	chatID := u.Message.Chat.ID
+	tags, err := parseTags(strings.Fields(u.Message.CommandArguments()))
+	if err != nil {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, getInvalidTagText(err)))
+		return
+	}
+	dictionary, err := b.DB().GetUserDictionary(user.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(user.ID)).Msg("failed to get user dictionary")
+		return
+	}
+	dictionary = db.FilterByTags(dictionary, tags)
+	if len(dictionary) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "You don't have any words in your dictionary"))
+		return
+	}
+	_, _ = b.Send(tgbotapi.NewMessage(chatID, getListText(dictionary, user.Config.EffectiveMasteryThreshold())))
+}
+
+// getListText returns text with words being learned sorted by mastery level and mastered words
+func getListText(dictionary map[db.UserDictionaryItem]db.DictionaryItem, threshold int) string {
+	learningDict, masteredDict := quiz.SplitMastered(dictionary, threshold)
+	learning := make([]db.UserDictionaryItem, 0, len(learningDict))
+	for userItem := range learningDict {
+		learning = append(learning, userItem)
+	}
+	sort.Slice(learning, func(i, j int) bool {
+		if learning[i].Streak() != learning[j].Streak() {
+			return learning[i].Streak() < learning[j].Streak()
+		}
+		return learning[i].Word < learning[j].Word
+	})
+	mastered := make([]string, 0, len(masteredDict))
+	for userItem := range masteredDict {
+		mastered = append(mastered, userItem.Word)
+	}
+	sort.Strings(mastered)
+
+	var lines []string
+	listed := 0
+	if len(learning) != 0 {
+		lines = append(lines, fmt.Sprintf("Learning (%d):", len(learning)))
+		for _, item := range learning {
+			if listed == listWordsLimit {
+				break
+			}
+			lines = append(lines, fmt.Sprintf("%v — %d/%d", item.Word, item.MasteryLevel(threshold), threshold))
+			listed++
+		}
+	}
+	if len(mastered) != 0 && listed < listWordsLimit {
+		if len(lines) != 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, fmt.Sprintf("Mastered (%d):", len(mastered)))
+		for _, word := range mastered {
+			if listed == listWordsLimit {
+				break
+			}
+			lines = append(lines, word)
+			listed++
+		}
+	}
+	if left := len(dictionary) - listed; left > 0 {
+		lines = append(lines, fmt.Sprintf("...and %d more, use /export to get all words", left))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,40 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+)
+
+// mastery progress marks
+const (
+	masteryMarkDone = "🟩"
+	masteryMarkLeft = "⬜️"
+)
+
+// masteryBarLimit limits number of marks in mastery progress bar
+const masteryBarLimit = 10
+
+// getMasteryThreshold returns mastery threshold of the user from context
+func getMasteryThreshold(ctx context.Context) int {
+	user, _ := ctx.Value(ctxUserKey).(db.User)
+	return user.Config.EffectiveMasteryThreshold()
+}
+
+// formatMastery returns mastery progress of the item, empty string is returned for items never quizzed
+func formatMastery(item db.UserDictionaryItem, threshold int) string {
+	if item.Stats == nil {
+		return ""
+	}
+	if item.IsMastered(threshold) {
+		return "mastered ✅, reviewed occasionally"
+	}
+	level := item.MasteryLevel(threshold)
+	var bar string
+	if threshold <= masteryBarLimit {
+		bar = strings.Repeat(masteryMarkDone, level) + strings.Repeat(masteryMarkLeft, threshold-level) + " "
+	}
+	return fmt.Sprintf("%v%d/%d", bar, level, threshold)
+}
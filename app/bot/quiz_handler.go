@@ -5,27 +5,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
-	"sort"
 	"strconv"
 	"strings"
 	"text/template"
-	"time"
 
 	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/quiz"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/rs/zerolog/log"
 )
 
-const quizChoiceLanguage = "ru"
-const quizChoicesCount = 4
 const quizMessageTemplate = `
-<i>Word</i>: <b>{{ .quiz.DisplayWord }}</b>
+<i>Word</i>: <b>{{ html .quiz.DisplayWord }}</b>
 <i>Choices</i>:
 {{- range $choiceIdx, $choice := .quiz.Choices }}
 
-<b>{{- if $.quiz.Result }}{{- if eq $.quiz.Result.Choice $choiceIdx }}☑️ {{- end }}{{- if $choice.Correct }}✅ {{- end }}{{- end }}{{inc $choiceIdx }}</b>: {{ $choice.Text }}
+<b>{{- if $.quiz.Result }}{{- if eq $.quiz.Result.Choice $choiceIdx }}☑️ {{- end }}{{- if $choice.Correct }}✅ {{- end }}{{- end }}{{inc $choiceIdx }}</b>: {{ html $choice.Text }}
 {{- end }}
 `
 
@@ -48,9 +44,6 @@ func GetQuizMessageText(quiz db.Quiz) (string, error) {
 	return buf.String(), nil
 }
 
-// ErrNotEnoughWords is returned when there are not enough words in dictionary
-var ErrNotEnoughWords = errors.New("not enough words")
-
 // QuizHandler handles quiz command
 type QuizHandler struct {
 	neverPassthorugh
@@ -61,192 +54,49 @@ func (h QuizHandler) Match(u tgbotapi.Update) bool {
 	return u.Message != nil && u.Message.Command() == "quiz"
 }
 
-// Handle generates new quiz and sends it to user
+// Handle generates new quiz and sends it to user,
+// quizzed words are restricted to tags passed as arguments or picked in settings
 func (h QuizHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
 	user, ok := ctx.Value(ctxUserKey).(db.User)
 	if !ok {
 		log.Error().Msg("invalid user in context")
 		return
 	}
-	quizType := db.QuizTypeDefault
-	if user.Config.QuizType != nil {
-		quizType = *user.Config.QuizType
-	}
-	dictionary, err := b.DB().GetUserDictionary(db.UserID(u.Message.From.ID))
-	if err != nil {
-		log.Error().Err(err).Int64("user", u.Message.From.ID).Msg("failed to get user dictionary")
-	}
-	if len(dictionary) == 0 {
-		_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, "You don't have any words in your dictionary"))
-		return
-	}
-	quizWord, err := h.getRandomWord(dictionary)
-	if err != nil {
-		log.Error().Err(err).Str("word", quizWord.Word).Msg("failed to get random word")
-		return
-	}
-	choices, err := h.getChoices(quizWord, quizType, dictionary, quizChoicesCount)
-	if err != nil {
-		if errors.Is(err, ErrNotEnoughWords) {
-			_, _ = b.Send(tgbotapi.NewMessage(u.Message.From.ID, "Add more words to your dictionary"))
+	tags := user.Config.QuizTags
+	if args := strings.Fields(u.Message.CommandArguments()); len(args) != 0 {
+		var err error
+		if tags, err = parseTags(args); err != nil {
+			_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, getInvalidTagText(err)))
 			return
 		}
 	}
-
-	displayWord := quizWord.Word
-	if quizType == db.QuizTypeReverseTranslations {
-		for _, tr := range dictionary[quizWord].Translations {
-			if tr.Language == quizChoiceLanguage {
-				displayWord = tr.Text
-				break
-			}
+	q, err := quiz.New(b.DB(), user, tags)
+	if err != nil {
+		switch {
+		case errors.Is(err, quiz.ErrEmptyDictionary):
+			_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, "You don't have any words in your dictionary"))
+		case errors.Is(err, quiz.ErrNoTaggedWords):
+			_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, fmt.Sprintf(
+				"You don't have words tagged %v, see /tags", formatTags(tags),
+			)))
+		case errors.Is(err, quiz.ErrNotEnoughWords):
+			_, _ = b.Send(tgbotapi.NewMessage(u.Message.From.ID, "Add more words to your dictionary"))
+		default:
+			log.Error().Err(err).Int64("user", u.Message.From.ID).Msg("failed to generate quiz")
 		}
-	}
-	quiz := db.NewQuiz(db.UserID(u.Message.From.ID), quizWord.Word, displayWord, quizChoiceLanguage, choices, quizType)
-	if err := b.DB().SaveQuiz(quiz); err != nil {
-		log.Error().Err(err).Int64("user", u.Message.From.ID).Msg("failed to save quiz")
 		return
 	}
-	text, err := GetQuizMessageText(quiz)
+	text, err := GetQuizMessageText(q)
 	if err != nil {
-		log.Error().Err(err).Str("quiz", quiz.ID).Msg("failed to get text for message")
+		log.Error().Err(err).Str("quiz", q.ID).Msg("failed to get text for message")
 		return
 	}
 	message := tgbotapi.NewMessage(u.Message.Chat.ID, text)
 	message.ParseMode = "html"
-	message.ReplyMarkup = h.getMessageKeyboard(quiz)
+	message.ReplyMarkup = h.getMessageKeyboard(q)
 	_, _ = b.Send(message)
 }
 
-// getRandomWord returns random word from dictionary based on last quiz time
-func (h QuizHandler) getRandomWord(dict map[db.UserDictionaryItem]db.DictionaryItem) (db.UserDictionaryItem, error) {
-	if len(dict) == 0 {
-		return db.UserDictionaryItem{}, errors.New("empty dictionary")
-	}
-	var minTime, maxTime *time.Time
-	items := make([]db.UserDictionaryItem, 0, len(dict))
-
-	for item, word := range dict {
-		var hasTranslation bool
-		for _, t := range word.Translations {
-			if t.Language == quizChoiceLanguage {
-				hasTranslation = true
-				break
-			}
-		}
-		if !hasTranslation {
-			continue
-		}
-		if len(dict[item].Translations) == 0 {
-			continue
-		}
-		if item.LastQuiz != nil {
-			if minTime == nil || item.LastQuiz.Before(*minTime) {
-				minTime = item.LastQuiz
-			}
-			if maxTime == nil || item.LastQuiz.After(*maxTime) {
-				maxTime = item.LastQuiz
-			}
-		}
-		items = append(items, item)
-	}
-	if len(items) == 0 {
-		return db.UserDictionaryItem{}, ErrNotEnoughWords
-	}
-
-	if minTime == nil || maxTime == nil {
-		return items[rand.Intn(len(items))], nil
-	}
-	weights := make(map[db.UserDictionaryItem]int, len(dict))
-	timeRange := maxTime.Sub(*minTime).Seconds()
-	var totalWeight uint
-	for _, item := range items {
-		weight := 100
-		if item.LastQuiz != nil {
-			if timeRange > 0 {
-				weight = int(maxTime.Sub(*item.LastQuiz).Seconds() / timeRange * 100)
-			} else {
-				weight = 0
-			}
-		}
-		if weight == 0 {
-			weight = 1
-		}
-		totalWeight += uint(weight)
-		weights[item] = weight
-	}
-	value := rand.Intn(int(totalWeight))
-	result := db.UserDictionaryItem{}
-	for _, item := range items {
-		value -= weights[item]
-		result = item
-		if value <= 0 {
-			break
-		}
-	}
-	return result, nil
-}
-
-// getChoices returns random words from dictionary with same part of speech
-func (h QuizHandler) getChoices(
-	item db.UserDictionaryItem,
-	qType string,
-	dict map[db.UserDictionaryItem]db.DictionaryItem,
-	count int,
-) ([]db.QuizItem, error) {
-	choices := make([]db.QuizItem, 0, len(dict))
-	correctWord, ok := dict[item]
-	if !ok {
-		return choices, errors.New("item not in dictionary")
-	}
-	for _, word := range dict {
-		if word.Word == item.Word {
-			continue
-		}
-		// skip unsuitable words
-		if qType == db.QuizTypeTranslations && len(word.Translations) == 0 {
-			continue
-		}
-		if qType == db.QuizTypeMeanings && len(word.Meanings) == 0 {
-			continue
-		}
-
-		choices = append(choices, db.QuizItem{
-			Word:    word.Word,
-			Text:    h.getWordChoiceText(word, qType),
-			Correct: false})
-	}
-	if len(choices) < count {
-		return choices, ErrNotEnoughWords
-	}
-	rand.Shuffle(len(choices), func(i, j int) { choices[i], choices[j] = choices[j], choices[i] })
-	randomChoices := choices[:count-1]
-	correctChoice := db.QuizItem{Word: correctWord.Word, Text: h.getWordChoiceText(correctWord, qType), Correct: true}
-	randomChoices = append(randomChoices, correctChoice)
-
-	sort.Slice(randomChoices, func(i, j int) bool { return randomChoices[i].Word < randomChoices[j].Word })
-	return randomChoices, nil
-}
-
-// getWordChoiceText returns choice text based on quiz type
-func (h QuizHandler) getWordChoiceText(word db.DictionaryItem, qType string) (text string) {
-	switch qType {
-	case db.QuizTypeTranslations:
-		translations := make([]string, 0, len(word.Translations))
-		for _, translation := range word.Translations {
-			if translation.Language == quizChoiceLanguage {
-				translations = append(translations, translation.Text)
-			}
-		}
-		text = strings.Join(translations, ", ")
-	case db.QuizTypeReverseTranslations:
-		text = word.Word
-	case db.QuizTypeMeanings:
-		text = word.Meanings[0].Definition
-	}
-	return
-}
-
 // getMessageKeyboard returns keyboard with quiz choices
 func (h QuizHandler) getMessageKeyboard(quiz db.Quiz) tgbotapi.InlineKeyboardMarkup {
 	buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(quiz.Choices))
@@ -291,10 +141,15 @@ func (h QuizReplyHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update)
 		return
 	}
 	if quiz.Result.Correct {
-		_, _ = b.Send(tgbotapi.NewMessage(u.CallbackQuery.From.ID, "Correct!"))
+		text := "Correct!"
+		threshold := getMasteryThreshold(ctx)
+		if item, err := b.DB().GetUserItem(quiz.User, quiz.Word); err == nil && item.Streak() == threshold {
+			text = fmt.Sprintf("Correct! «%v» is mastered, it will be quizzed only occasionally now", quiz.Word)
+		}
+		_, _ = b.Send(tgbotapi.NewMessage(u.CallbackQuery.From.ID, text))
 	} else {
 		_, _ = b.Send(tgbotapi.NewMessage(u.CallbackQuery.From.ID, "Wrong!"))
-		h.sendItemMessage(quiz.Word, u.CallbackQuery.From.ID, b)
+		h.sendItemMessage(quiz.Word, u.CallbackQuery.From.ID, getMasteryThreshold(ctx), b)
 	}
 	quizText, err := GetQuizMessageText(quiz)
 	if err != nil {
@@ -309,14 +164,25 @@ func (h QuizReplyHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update)
 	_, _ = b.Send(edit)
 }
 
-func (h QuizReplyHandler) sendItemMessage(word string, user int64, b Bot) {
+func (h QuizReplyHandler) sendItemMessage(word string, user int64, masteryThreshold int, b Bot) {
 	item, err := b.DB().Get(word)
-	if err != nil {
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
 		log.Error().Err(err).Str("word", word).Msg("failed to get item")
 	}
-	text := GetItemMessageText(item)
+	if item.Word == "" {
+		// the word might be deleted since the quiz was created
+		item.Word = word
+	}
+	var userItem *db.UserDictionaryItem
+	if ui, err := b.DB().GetUserItem(db.UserID(user), word); err == nil {
+		userItem = &ui
+	}
+	text := GetItemMessageText(item, userItem, masteryThreshold)
 	msg := tgbotapi.NewMessage(user, text)
 	msg.ParseMode = "html"
+	if keyboard, ok := getItemKeyboard(word); ok {
+		msg.ReplyMarkup = keyboard
+	}
 	_, _ = b.Send(msg)
 }
 
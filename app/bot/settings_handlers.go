@@ -3,6 +3,8 @@ package bot
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -12,8 +14,16 @@ import (
 
 const (
 	settingQuizType = "quiz_type"
+	settingQuizTags = "quiz_tags"
+	settingMastery  = "mastery"
 )
 
+// masteryThresholdChoices lists mastery thresholds user can pick
+var masteryThresholdChoices = []int{3, 5, 7, 10}
+
+// quizTagsButtonsLimit limits number of tags in quiz tags settings keyboard
+const quizTagsButtonsLimit = 50
+
 // ListSettingsHandler handles /settings command
 type ListSettingsHandler struct {
 	neverPassthorugh
@@ -29,12 +39,52 @@ func (h ListSettingsHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Updat
 	msg := tgbotapi.NewMessage(u.Message.From.ID, "Choose what do you want to change:")
 	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData("Quiz type", fmt.Sprintf("%v|%v", callbackIDSettings, settingQuizType)),
+			tgbotapi.NewInlineKeyboardButtonData("Quiz types", fmt.Sprintf("%v|%v", callbackIDSettings, settingQuizType)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Quiz tags", fmt.Sprintf("%v|%v", callbackIDSettings, settingQuizTags)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Mastery", fmt.Sprintf("%v|%v", callbackIDSettings, settingMastery)),
 		),
 	)
 	_, _ = b.Send(msg)
 }
 
+// quizTypeNames holds human readable quiz types names
+var quizTypeNames = map[string]string{
+	db.QuizTypeTranslations:        "Translations",
+	db.QuizTypeReverseTranslations: "Reverse translations",
+	db.QuizTypeMeanings:            "Meanings",
+}
+
+// getQuizTypesMessage returns text and keyboard for quiz types settings
+func getQuizTypesMessage(user db.User) (string, tgbotapi.InlineKeyboardMarkup) {
+	enabled := user.Config.EnabledQuizTypes()
+	names := make([]string, 0, len(enabled))
+	for _, t := range enabled {
+		names = append(names, quizTypeNames[t])
+	}
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(db.QuizTypes))
+	for _, t := range db.QuizTypes {
+		mark := markUnchecked
+		for _, e := range enabled {
+			if e == t {
+				mark = markChecked
+				break
+			}
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("%v %v", mark, quizTypeNames[t]),
+				fmt.Sprintf("%v|%v|%v", callbackIDSettings, settingQuizType, t),
+			),
+		))
+	}
+	text := fmt.Sprintf("Enabled types: %v\nToggle quiz types:", strings.Join(names, ", "))
+	return text, tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
 // SendQuizTypesHandler sends available quiz types
 type SendQuizTypesHandler struct {
 	neverPassthorugh
@@ -53,42 +103,14 @@ func (h SendQuizTypesHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Upda
 		log.Error().Msg("invalid user in context")
 		return
 	}
-	quizType := db.QuizTypeDefault
-	if user.Config.QuizType != nil {
-		quizType = *user.Config.QuizType
-	}
-	var quizTypeText string
-	switch quizType {
-	case db.QuizTypeTranslations:
-		quizTypeText = "Translations"
-	case db.QuizTypeReverseTranslations:
-		quizTypeText = "Reverse translations"
-	case db.QuizTypeMeanings:
-		quizTypeText = "Meanings"
-	}
-	msg := tgbotapi.NewMessage(u.CallbackQuery.From.ID, fmt.Sprintf("Current type: %v\nPick quiz type:", quizTypeText))
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(
-				"Translations", fmt.Sprintf("%v|%v|%v", callbackIDSettings, settingQuizType, db.QuizTypeTranslations),
-			),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(
-				"Reverse translations", fmt.Sprintf("%v|%v|%v", callbackIDSettings, settingQuizType, db.QuizTypeReverseTranslations),
-			),
-		),
-		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(
-				"Meanings", fmt.Sprintf("%v|%v|%v", callbackIDSettings, settingQuizType, db.QuizTypeMeanings),
-			),
-		),
-	)
+	text, keyboard := getQuizTypesMessage(user)
+	msg := tgbotapi.NewMessage(u.CallbackQuery.From.ID, text)
+	msg.ReplyMarkup = keyboard
 	_, _ = b.Send(msg)
 
 }
 
-// SetQuizTypesHandler saves quiz type to user config
+// SetQuizTypesHandler toggles quiz type in user config
 type SetQuizTypesHandler struct {
 	neverPassthorugh
 }
@@ -99,7 +121,7 @@ func (h SetQuizTypesHandler) Match(u tgbotapi.Update) bool {
 		strings.HasPrefix(u.CallbackQuery.Data, fmt.Sprintf("%v|%v|", callbackIDSettings, settingQuizType))
 }
 
-// Handle saves quiz type to user config
+// Handle toggles quiz type in user config and updates settings message
 func (h SetQuizTypesHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
 	user, ok := ctx.Value(ctxUserKey).(db.User)
 	if !ok {
@@ -107,18 +129,255 @@ func (h SetQuizTypesHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Updat
 		return
 	}
 	quizType := strings.Split(u.CallbackQuery.Data, "|")[2]
-	user.Config.QuizType = &quizType
-	switch quizType {
-	case db.QuizTypeTranslations:
-	case db.QuizTypeMeanings:
-	case db.QuizTypeReverseTranslations:
-	default:
+	if _, ok := quizTypeNames[quizType]; !ok {
 		log.Error().Str("type", quizType).Msg("invalid quiz type")
 		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Unknown type"))
+		return
+	}
+	enabled := user.Config.EnabledQuizTypes()
+	types := make([]string, 0, len(db.QuizTypes))
+	for _, t := range db.QuizTypes {
+		var isEnabled bool
+		for _, e := range enabled {
+			if e == t {
+				isEnabled = true
+				break
+			}
+		}
+		if isEnabled != (t == quizType) {
+			types = append(types, t)
+		}
+	}
+	if len(types) == 0 {
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "At least one type should be enabled"))
+		return
+	}
+	user.Config.QuizTypes = types
+	user.Config.QuizType = nil
+	if err := b.DB().SaveUser(user); err != nil {
+		log.Error().Err(err).Msg("failed to save user")
+		return
+	}
+	_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Quiz types updated"))
+	if u.CallbackQuery.Message == nil {
+		return
+	}
+	text, keyboard := getQuizTypesMessage(user)
+	_, _ = b.Send(tgbotapi.NewEditMessageTextAndMarkup(
+		u.CallbackQuery.Message.Chat.ID, u.CallbackQuery.Message.MessageID, text, keyboard,
+	))
+}
+
+// getQuizTagsMessage returns text and keyboard for quiz tags settings,
+// tags picked before are listed even if no words have them anymore
+func getQuizTagsMessage(user db.User, counts map[string]int) (string, tgbotapi.InlineKeyboardMarkup) {
+	picked := make(map[string]struct{}, len(user.Config.QuizTags))
+	for _, t := range user.Config.QuizTags {
+		picked[t] = struct{}{}
+	}
+	tags := make([]string, 0, len(counts)+len(picked))
+	for t := range counts {
+		tags = append(tags, t)
+	}
+	for t := range picked {
+		if _, ok := counts[t]; !ok {
+			tags = append(tags, t)
+		}
+	}
+	sort.Strings(tags)
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(tags))
+	for _, t := range tags {
+		if len(rows) == quizTagsButtonsLimit {
+			break
+		}
+		data := fmt.Sprintf("%v|%v|%v", callbackIDSettings, settingQuizTags, t)
+		if len(data) > callbackDataLimit {
+			continue
+		}
+		mark := markUnchecked
+		if _, ok := picked[t]; ok {
+			mark = markChecked
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%v #%v (%d)", mark, t, counts[t]), data),
+		))
+	}
+	text := "Quizzes use all words"
+	if len(user.Config.QuizTags) != 0 {
+		text = fmt.Sprintf("Quizzes use words tagged %v", formatTags(user.Config.QuizTags))
+	}
+	return text + "\nToggle tags, all words are used if none is picked:", tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// SendQuizTagsHandler sends user tags which can be picked for quizzes
+type SendQuizTagsHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if update is quiz tags settings callback
+func (h SendQuizTagsHandler) Match(u tgbotapi.Update) bool {
+	return u.CallbackQuery != nil &&
+		u.CallbackQuery.Data == fmt.Sprintf("%v|%v", callbackIDSettings, settingQuizTags)
+}
+
+// Handle sends quiz tags keyboard
+func (h SendQuizTagsHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	dictionary, err := b.DB().GetUserDictionary(user.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(user.ID)).Msg("failed to get user dictionary")
+		return
 	}
+	counts := db.TagCounts(dictionary)
+	if len(counts) == 0 && len(user.Config.QuizTags) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(u.CallbackQuery.From.ID, "You don't have any tags yet.\n"+tagHelp))
+		return
+	}
+	text, keyboard := getQuizTagsMessage(user, counts)
+	msg := tgbotapi.NewMessage(u.CallbackQuery.From.ID, text)
+	msg.ReplyMarkup = keyboard
+	_, _ = b.Send(msg)
+}
+
+// SetQuizTagsHandler toggles quiz tag in user config
+type SetQuizTagsHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if update is quiz tags settings callback with picked tag
+func (h SetQuizTagsHandler) Match(u tgbotapi.Update) bool {
+	return u.CallbackQuery != nil &&
+		strings.HasPrefix(u.CallbackQuery.Data, fmt.Sprintf("%v|%v|", callbackIDSettings, settingQuizTags))
+}
+
+// Handle toggles quiz tag in user config and updates settings message
+func (h SetQuizTagsHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	tag := strings.SplitN(u.CallbackQuery.Data, "|", 3)[2]
+	tags := make([]string, 0, len(user.Config.QuizTags)+1)
+	var removed bool
+	for _, t := range user.Config.QuizTags {
+		if t == tag {
+			removed = true
+			continue
+		}
+		tags = append(tags, t)
+	}
+	if !removed {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	if len(tags) == 0 {
+		tags = nil
+	}
+	user.Config.QuizTags = tags
+	if err := b.DB().SaveUser(user); err != nil {
+		log.Error().Err(err).Msg("failed to save user")
+		return
+	}
+	_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Quiz tags updated"))
+	if u.CallbackQuery.Message == nil {
+		return
+	}
+	dictionary, err := b.DB().GetUserDictionary(user.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(user.ID)).Msg("failed to get user dictionary")
+		return
+	}
+	text, keyboard := getQuizTagsMessage(user, db.TagCounts(dictionary))
+	_, _ = b.Send(tgbotapi.NewEditMessageTextAndMarkup(
+		u.CallbackQuery.Message.Chat.ID, u.CallbackQuery.Message.MessageID, text, keyboard,
+	))
+}
+
+// getMasteryMessage returns text and keyboard for mastery threshold settings
+func getMasteryMessage(user db.User) (string, tgbotapi.InlineKeyboardMarkup) {
+	current := user.Config.EffectiveMasteryThreshold()
+	buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(masteryThresholdChoices))
+	for _, t := range masteryThresholdChoices {
+		text := strconv.Itoa(t)
+		if t == current {
+			text = fmt.Sprintf("%v %d", markChecked, t)
+		}
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(
+			text, fmt.Sprintf("%v|%v|%d", callbackIDSettings, settingMastery, t),
+		))
+	}
+	text := fmt.Sprintf(
+		"A word is mastered after %d correct answers in a row, "+
+			"mastered words are quizzed only occasionally until you make a mistake.\n"+
+			"Pick how many correct answers are needed:", current,
+	)
+	return text, tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(buttons...))
+}
+
+// SendMasteryHandler sends mastery threshold choices
+type SendMasteryHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if update is mastery settings callback
+func (h SendMasteryHandler) Match(u tgbotapi.Update) bool {
+	return u.CallbackQuery != nil &&
+		u.CallbackQuery.Data == fmt.Sprintf("%v|%v", callbackIDSettings, settingMastery)
+}
+
+// Handle sends mastery threshold keyboard
+func (h SendMasteryHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	text, keyboard := getMasteryMessage(user)
+	msg := tgbotapi.NewMessage(u.CallbackQuery.From.ID, text)
+	msg.ReplyMarkup = keyboard
+	_, _ = b.Send(msg)
+}
+
+// SetMasteryHandler sets mastery threshold in user config
+type SetMasteryHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if update is mastery settings callback with picked threshold
+func (h SetMasteryHandler) Match(u tgbotapi.Update) bool {
+	return u.CallbackQuery != nil &&
+		strings.HasPrefix(u.CallbackQuery.Data, fmt.Sprintf("%v|%v|", callbackIDSettings, settingMastery))
+}
+
+// Handle saves picked mastery threshold and updates settings message
+func (h SetMasteryHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	threshold, err := strconv.Atoi(strings.Split(u.CallbackQuery.Data, "|")[2])
+	if err != nil || threshold < db.MinMasteryThreshold || threshold > db.MaxMasteryThreshold {
+		log.Error().Str("data", u.CallbackQuery.Data).Msg("invalid mastery threshold")
+		_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Invalid value"))
+		return
+	}
+	user.Config.MasteryThreshold = threshold
 	if err := b.DB().SaveUser(user); err != nil {
 		log.Error().Err(err).Msg("failed to save user")
 		return
 	}
-	_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Quiz type set"))
+	_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, "Mastery updated"))
+	if u.CallbackQuery.Message == nil {
+		return
+	}
+	text, keyboard := getMasteryMessage(user)
+	_, _ = b.Send(tgbotapi.NewEditMessageTextAndMarkup(
+		u.CallbackQuery.Message.Chat.ID, u.CallbackQuery.Message.MessageID, text, keyboard,
+	))
 }
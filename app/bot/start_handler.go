@@ -18,5 +18,13 @@ func (h StartHandler) Match(u tgbotapi.Update) bool {
 
 // Handle sends start message
 func (h StartHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
-	_, _ = b.Send(tgbotapi.NewMessage(u.Message.From.ID, "Hi! Just send me a word!"))
+	_, _ = b.Send(tgbotapi.NewMessage(
+		u.Message.From.ID,
+		"Hi! Just send me a word or a phrase, a list of words one per line or a .txt/.csv file with them.\n"+
+			"Send me a longer text and I'll suggest new words from it.\n"+
+			"You can also pick a built-in word list with /course.\n"+
+			"Group words with /tag and practice them with /quiz #tag.\n"+
+			"In any other chat type my username and a word to share its card.\n"+
+			"Add me to a group to run quizzes with friends",
+	))
 }
@@ -0,0 +1,155 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const tagHelp = "Use /tag word #tag1 #tag2 to tag a word and /untag word #tag to remove tags.\n" +
+	"Tags may contain letters, digits, _ and -, /tags lists all your tags"
+
+// parseTags normalizes tags passed by user
+func parseTags(tokens []string) ([]string, error) {
+	tags := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		tag, err := db.NormalizeTag(t)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", t, err)
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// getInvalidTagText returns message text for tags parsing error
+func getInvalidTagText(err error) string {
+	return fmt.Sprintf("Sorry, %v. Tags may contain only letters, digits, _ and -", err)
+}
+
+// formatTags returns tags as hashtags
+func formatTags(tags []string) string {
+	hashtags := make([]string, 0, len(tags))
+	for _, t := range tags {
+		hashtags = append(hashtags, "#"+t)
+	}
+	return strings.Join(hashtags, " ")
+}
+
+// splitTagArgs splits command arguments to a word and tags, tags start with #
+func splitTagArgs(args string) (word string, tags []string) {
+	var words []string
+	for _, token := range strings.Fields(args) {
+		if strings.HasPrefix(token, "#") {
+			tags = append(tags, token)
+		} else {
+			words = append(words, token)
+		}
+	}
+	return db.NormalizeWord(strings.Join(words, " ")), tags
+}
+
+// TagHandler handles /tag and /untag commands
+type TagHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if update is /tag or /untag command
+func (h TagHandler) Match(u tgbotapi.Update) bool {
+	if u.Message == nil {
+		return false
+	}
+	cmd := u.Message.Command()
+	return cmd == "tag" || cmd == "untag"
+}
+
+// Handle adds or removes tags of a word from user dictionary
+func (h TagHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	chatID := u.Message.Chat.ID
+	word, tokens := splitTagArgs(u.Message.CommandArguments())
+	if word == "" || len(tokens) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, tagHelp))
+		return
+	}
+	tags, err := parseTags(tokens)
+	if err != nil {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, getInvalidTagText(err)))
+		return
+	}
+	userItem, err := b.DB().GetUserItem(user.ID, word)
+	if err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			_, _ = b.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("«%v» is not in your dictionary", word)))
+			return
+		}
+		log.Error().Err(err).Str("word", word).Msg("failed to get user item")
+		return
+	}
+	if u.Message.Command() == "tag" {
+		userItem.AddTags(tags...)
+	} else {
+		userItem.RemoveTags(tags...)
+	}
+	if err := b.DB().SaveUserItem(userItem); err != nil {
+		log.Error().Err(err).Str("word", word).Msg("failed to save user item")
+		return
+	}
+	text := fmt.Sprintf("«%v» has no tags", word)
+	if len(userItem.Tags()) != 0 {
+		text = fmt.Sprintf("Tags of «%v»: %v", word, formatTags(userItem.Tags()))
+	}
+	_, _ = b.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// TagsHandler handles /tags command
+type TagsHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if update is /tags command
+func (h TagsHandler) Match(u tgbotapi.Update) bool {
+	return u.Message != nil && u.Message.Command() == "tags"
+}
+
+// Handle sends user tags with number of tagged words
+func (h TagsHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	dictionary, err := b.DB().GetUserDictionary(user.ID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(user.ID)).Msg("failed to get user dictionary")
+		return
+	}
+	counts := db.TagCounts(dictionary)
+	if len(counts) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, "You don't have any tags yet.\n"+tagHelp))
+		return
+	}
+	tags := make([]string, 0, len(counts))
+	for t := range counts {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	lines := make([]string, 0, len(tags)+2)
+	lines = append(lines, "Your tags:")
+	for _, t := range tags {
+		lines = append(lines, fmt.Sprintf("#%v — %d", t, counts[t]))
+	}
+	lines = append(lines, "\nUse /quiz #tag to practice tagged words")
+	_, _ = b.Send(tgbotapi.NewMessage(u.Message.Chat.ID, strings.Join(lines, "\n")))
+}
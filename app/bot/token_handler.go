@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const tokenHelp = "Personal access tokens let your scripts use the API.\n" +
+	"Use /token read name or /token write name to create a read-only or a read-write token, " +
+	"/token revoke id to revoke it and /token to list your tokens"
+
+// TokenHandler handles /token command managing personal access tokens
+type TokenHandler struct {
+	neverPassthorugh
+}
+
+// Match returns true if update is /token command
+func (h TokenHandler) Match(u tgbotapi.Update) bool {
+	return u.Message != nil && u.Message.Command() == "token"
+}
+
+// Handle lists, creates or revokes personal access tokens of the user
+func (h TokenHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	user, ok := ctx.Value(ctxUserKey).(db.User)
+	if !ok {
+		log.Error().Msg("invalid user in context")
+		return
+	}
+	chatID := u.Message.Chat.ID
+	action, args, _ := strings.Cut(strings.TrimSpace(u.Message.CommandArguments()), " ")
+	switch action = strings.ToLower(action); action {
+	case "":
+		h.sendTokens(b, user.ID, chatID)
+	case "revoke":
+		h.revoke(b, user.ID, chatID, strings.TrimSpace(args))
+	default:
+		scope, err := db.ParseTokenScope(action)
+		if err != nil {
+			_, _ = b.Send(tgbotapi.NewMessage(chatID, tokenHelp))
+			return
+		}
+		h.create(b, user.ID, chatID, args, scope)
+	}
+}
+
+// sendTokens sends list of user tokens
+func (h TokenHandler) sendTokens(b Bot, userID db.UserID, chatID int64) {
+	tokens, err := b.DB().GetPersonalTokens(userID)
+	if err != nil {
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to get personal tokens")
+		return
+	}
+	if len(tokens) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "You don't have any tokens yet.\n"+tokenHelp))
+		return
+	}
+	lines := make([]string, 0, len(tokens)+2)
+	lines = append(lines, "Your tokens:")
+	for _, t := range tokens {
+		lines = append(lines, fmt.Sprintf(
+			"<code>%v</code> %v (%v), created %v",
+			t.ID, html.EscapeString(t.Name), t.Scope, t.Created.Format("2006-01-02"),
+		))
+	}
+	lines = append(lines, "", html.EscapeString(tokenHelp))
+	msg := tgbotapi.NewMessage(chatID, strings.Join(lines, "\n"))
+	msg.ParseMode = "html"
+	_, _ = b.Send(msg)
+}
+
+// create creates token and sends it to user
+func (h TokenHandler) create(b Bot, userID db.UserID, chatID int64, name string, scope db.TokenScope) {
+	token, raw, err := db.CreatePersonalToken(b.DB(), userID, name, scope)
+	switch {
+	case errors.Is(err, db.ErrInvalidTokenName):
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Please name the token, e.g. /token "+string(scope)+" my script"))
+		return
+	case errors.Is(err, db.ErrTooManyTokens):
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(
+			"Sorry, only up to %d tokens can be created, revoke some of them first", db.PersonalTokensMax,
+		)))
+		return
+	case err != nil:
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to create personal token")
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, "Sorry, something went wrong"))
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(
+		"Token «%v» (%v) is created:\n<code>%v</code>\n"+
+			"Pass it as <code>Authorization: Bearer</code> header. Save it now, it won't be shown again",
+		html.EscapeString(token.Name), token.Scope, raw,
+	))
+	msg.ParseMode = "html"
+	_, _ = b.Send(msg)
+}
+
+// revoke deletes user token by ID
+func (h TokenHandler) revoke(b Bot, userID db.UserID, chatID int64, id string) {
+	if id == "" {
+		_, _ = b.Send(tgbotapi.NewMessage(chatID, tokenHelp))
+		return
+	}
+	if err := b.DB().DeletePersonalToken(userID, id); err != nil {
+		if errors.Is(err, db.ErrNotFound) {
+			_, _ = b.Send(tgbotapi.NewMessage(chatID, "Token is not found"))
+			return
+		}
+		log.Error().Err(err).Int64("user", int64(userID)).Msg("failed to delete personal token")
+		return
+	}
+	_, _ = b.Send(tgbotapi.NewMessage(chatID, "Token is revoked"))
+}
@@ -4,50 +4,88 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"html"
 	"strings"
 	"text/template"
-	"time"
 
-	"github.com/rbhz/tg-dictionary/app/clients/dictionaryapi"
-	yandexdictionary "github.com/rbhz/tg-dictionary/app/clients/yandexdictionary"
 	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/lookup"
+	"github.com/rbhz/tg-dictionary/app/suggest"
+	"github.com/rbhz/tg-dictionary/app/wordlist"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
-	"github.com/pkg/errors"
 	"github.com/rs/zerolog/log"
 )
 
-const (
-	fromLanguage = "en"
-	toLanguage   = "ru"
-)
+// suggestionsCount limits number of suggested words for unknown ones
+const suggestionsCount = 3
 
-const dictionaryItemTemplate = `<b>{{ .Item.Word }}</b>
+const dictionaryItemTemplate = `<b>{{ html .Item.Word }}</b>
+{{- if .Mastery }}
+<i>Mastery</i>: {{ .Mastery }}
+{{- end }}
+{{- with .UserItem }}{{- with .Custom }}
+{{- if .Translations }}
+<b>Your translations</b>:
+{{- range $t := .Translations }} <code>{{ html $t }}</code>{{- end }}
+{{- end }}
+{{- if .Notes }}
+<b>Notes</b>: {{ html .Notes }}
+{{- end }}
+{{- if .Examples }}
+<b>Your examples</b>:
+{{- range $e := .Examples }}
+{{ html $e }}
+{{- end }}
+{{- end }}
+{{- if .Tags }}
+<b>Tags</b>:
+{{- range $t := .Tags }} #{{ html $t }}{{- end }}
+{{- end }}
+___
+{{- end }}{{- end }}
 {{- if .Item.Translations }}
 <b>Translations</b>:
-{{- range $t := .Item.Translations }} <code>{{ $t.Text }}</code>({{ $t.Language }}){{- end }}
+{{- range $t := .Item.Translations }} <code>{{ html $t.Text }}</code>({{ $t.Language }}){{- end }}
 ___
 {{- end }}
+{{- if .Item.Meanings }}
 <b>Meanings:</b>
 {{- range  $m := .Item.Meanings }}
-<code>{{ $m.Definition }}</code> ({{ $m.PartOfSpeech }})
+<code>{{ html $m.Definition }}</code> ({{ $m.PartOfSpeech }})
 {{- range $e := $m.Examples }}
-{{ $e }}
+{{ html $e }}
 {{- end }}
 ___
 {{- end }}
-<u>Phonetics</u>: {{ .Item.Phonetics.Text }}
+{{- end }}
+{{- if .Item.Phonetics.Text }}
+<u>Phonetics</u>: {{ html .Item.Phonetics.Text }}
+{{- end }}
+{{- if .Item.EditedBy }}
+<i>Edited by admin</i>
+{{- end }}
 `
 
-// GetItemMessageText executes template with dictionary item data
-func GetItemMessageText(item db.DictionaryItem) string {
+// GetItemMessageText executes template with dictionary item data,
+// user specific data and mastery are shown if user item is passed
+func GetItemMessageText(item db.DictionaryItem, userItem *db.UserDictionaryItem, masteryThreshold int) string {
 	tmpl, err := template.New("template").Parse(dictionaryItemTemplate)
 	if err != nil {
 		log.Error().Err(err).Str("word", item.Word).Msg("failed to parse item template")
 		return ""
 	}
+	var mastery string
+	if userItem != nil {
+		// words imported with user translations may be missing in the shared dictionary
+		if item.Word == "" {
+			item.Word = userItem.Word
+		}
+		mastery = formatMastery(*userItem, masteryThreshold)
+	}
 	buf := &bytes.Buffer{}
-	if err := tmpl.Execute(buf, map[string]interface{}{"Item": item}); err != nil {
+	data := map[string]interface{}{"Item": item, "UserItem": userItem, "Mastery": mastery}
+	if err := tmpl.Execute(buf, data); err != nil {
 		log.Error().Err(err).Str("word", item.Word).Msg("failed to format item template")
 	}
 	return buf.String()
@@ -55,7 +93,7 @@ func GetItemMessageText(item db.DictionaryItem) string {
 
 // WordHandler handles word requests
 type WordHandler struct {
-	tranlationsToken string
+	lookup lookup.Lookuper
 	neverPassthorugh
 }
 
@@ -64,96 +102,115 @@ func (h WordHandler) Match(u tgbotapi.Update) bool {
 	return u.Message != nil && u.Message.Text != "" && !u.Message.IsCommand()
 }
 
-// Handle collects word data and sends it to user
+// Handle collects word or phrase data and sends it to user
 func (h WordHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
-	word := strings.ToLower(u.Message.Text)
-	if strings.Contains(word, " ") {
-		_, _ = b.Send(tgbotapi.NewMessage(u.Message.From.ID, "Sorry only single words are supported"))
+	word := db.NormalizeWord(u.Message.Text)
+	if len(strings.Fields(word)) > db.MaxPhraseWords {
+		_, _ = b.Send(tgbotapi.NewMessage(
+			u.Message.From.ID,
+			fmt.Sprintf("Sorry only words and phrases up to %d words are supported", db.MaxPhraseWords),
+		))
 		return
 	}
-	userID := db.UserID(u.Message.From.ID)
-	item, err := h.getItemData(ctx, word, b.DB())
+	h.sendWord(ctx, b, u.Message.From.ID, word)
+}
+
+// sendWord adds word to user dictionary and sends its data,
+// similar known words are suggested if the word is unknown
+func (h WordHandler) sendWord(ctx context.Context, b Bot, user int64, word string) {
+	userID := db.UserID(user)
+	item, err := h.lookup.GetNormalized(ctx, word, b.DB())
 	if err != nil {
 		log.Error().Err(err).Str("word", word).Msg("failed to get word data")
 		return
 	}
 	if item == nil {
-		_, _ = b.Send(tgbotapi.NewMessage(u.Message.From.ID, "Sorry, I don't know this word"))
+		h.sendSuggestions(b, user, word)
 		return
 	}
 
-	if _, err := b.DB().GetUserItem(userID, item.Word); err != nil && errors.Is(err, db.ErrNotFound) {
-		err = b.DB().SaveUserItem(db.UserDictionaryItem{
-			User:    userID,
-			Word:    item.Word,
-			Created: time.Now().UTC(),
-		})
-		if err != nil {
-			log.Error().
-				Err(err).
-				Str("word", item.Word).
-				Int64("user", int64(userID)).
-				Msg("failed to save user item")
-			return
-		}
+	userItem, _, err := db.AddUserItem(b.DB(), userID, item.Word)
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("word", item.Word).
+			Int64("user", int64(userID)).
+			Msg("failed to save user item")
+		return
 	}
 
-	text := tgbotapi.NewMessage(u.Message.From.ID, GetItemMessageText(*item))
+	messageText := GetItemMessageText(*item, &userItem, getMasteryThreshold(ctx))
+	if item.Word != word {
+		messageText = fmt.Sprintf("<i>%v → %v</i>\n%v", html.EscapeString(word), html.EscapeString(item.Word), messageText)
+	}
+	text := tgbotapi.NewMessage(user, messageText)
 	text.ParseMode = "html"
+	if keyboard, ok := getItemKeyboard(item.Word); ok {
+		text.ReplyMarkup = keyboard
+	}
 	if _, err := b.Send(text); err == nil && item.Phonetics.Audio != "" {
-		audio := tgbotapi.NewAudio(u.Message.From.ID, tgbotapi.FileURL(item.Phonetics.Audio))
+		audio := tgbotapi.NewAudio(user, tgbotapi.FileURL(item.Phonetics.Audio))
 		_, _ = b.Send(audio)
 	}
 }
 
-func (h WordHandler) getItemData(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
-	dbItem, err := storage.Get(word)
-	if err != nil && !errors.Is(err, db.ErrNotFound) {
-		return nil, fmt.Errorf("fetch from db: %w", err)
+// sendSuggestions sends known words similar to the unknown one as lookup buttons
+func (h WordHandler) sendSuggestions(b Bot, user int64, word string) {
+	// saved words are narrowed down to ones with the same first letter, so the whole dictionary isn't loaded
+	var prefix string
+	if letters := []rune(db.NormalizeWord(word)); len(letters) != 0 {
+		prefix = string(letters[0])
 	}
-
-	var item db.DictionaryItem
-	if err == nil {
-		return &dbItem, nil
+	known, err := b.DB().GetWordsByPrefix(prefix, 0)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get dictionary words")
 	}
-	dictOut, dictErrChan := make(chan []dictionaryapi.WordResponse), make(chan error)
-	translationOut, translationErrChar := make(chan yandexdictionary.TranslationResponse), make(chan error)
-	go func() {
-		client := dictionaryapi.NewClient(ctx)
-		dictionary, err := client.Get(word)
-		dictOut <- dictionary
-		dictErrChan <- err
-	}()
-	go func() {
-		client := yandexdictionary.NewClient(ctx, h.tranlationsToken)
-		translation, err := client.Translate(word, fromLanguage, toLanguage)
-		translationOut <- translation
-		translationErrChar <- err
-	}()
-	translation, translationErr := <-translationOut, <-translationErrChar
-	dictionary, dictErr := <-dictOut, <-dictErrChan
-	if dictErr != nil {
-		if errors.Is(dictErr, dictionaryapi.ErrNotFound) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("get dictionary info: %w", dictErr)
+	listWords, err := wordlist.All()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to get word lists")
 	}
-	translations := make(map[string]yandexdictionary.TranslationResponse, 1)
-	if translationErr != nil {
-		if !errors.Is(translationErr, yandexdictionary.ErrUnknown) {
-			log.Error().Err(err).Str("word", word).Msg("failed to get translation info")
+	suggestions := suggest.New(known, listWords).Suggest(word, suggestionsCount)
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(suggestions))
+	for _, s := range suggestions {
+		data := fmt.Sprintf("%v|%v", callbackIDLookup, s)
+		if len(data) > callbackDataLimit {
+			continue
 		}
-	} else {
-		translations[toLanguage] = translation
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(s, data)))
 	}
-	item = db.NewDictionaryItem(word, dictionary, translations)
-	if err := storage.Save(item); err != nil {
-		return nil, fmt.Errorf("save to db: %w", err)
+	if len(rows) == 0 {
+		_, _ = b.Send(tgbotapi.NewMessage(user, "Sorry, I don't know this word"))
+		return
 	}
-	return &item, nil
+	msg := tgbotapi.NewMessage(user, "Sorry, I don't know this word. Did you mean:")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(rows...)
+	_, _ = b.Send(msg)
+}
+
+// LookupHandler handles lookup of a word picked from suggestions
+type LookupHandler struct {
+	words WordHandler
+	neverPassthorugh
+}
+
+// Match returns true if update is lookup callback
+func (h LookupHandler) Match(u tgbotapi.Update) bool {
+	return u.CallbackQuery != nil && strings.HasPrefix(u.CallbackQuery.Data, callbackIDLookup+"|")
+}
+
+// Handle sends picked word data to user
+func (h LookupHandler) Handle(ctx context.Context, b Bot, u tgbotapi.Update) {
+	word := strings.TrimPrefix(u.CallbackQuery.Data, callbackIDLookup+"|")
+	_, _ = b.SendCallback(tgbotapi.NewCallback(u.CallbackQuery.ID, ""))
+	h.words.sendWord(ctx, b, u.CallbackQuery.From.ID, word)
+}
+
+// NewLookupHandler creates LookupHandler
+func NewLookupHandler(words WordHandler) LookupHandler {
+	return LookupHandler{words: words}
 }
 
 // NewWordHandler creates new word handler
-func NewWordHandler(tranlationsToken string) WordHandler {
-	return WordHandler{tranlationsToken: tranlationsToken}
+func NewWordHandler(lookup lookup.Lookuper) WordHandler {
+	return WordHandler{lookup: lookup}
 }
@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWordHandlerSendSuggestions(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	for _, word := range []string{"catamaran", "batamaran"} {
+		require.NoError(t, storage.Save(db.DictionaryItem{Word: word}))
+	}
+	h := NewWordHandler(&stubLookuper{})
+
+	t.Run("suggestions", func(t *testing.T) {
+		b := newStubBot(storage)
+		h.sendSuggestions(b, 1, "Catamarab")
+		require.Len(t, b.sent, 1)
+		msg := b.sent[0].(tgbotapi.MessageConfig)
+		assert.Equal(t, "Sorry, I don't know this word. Did you mean:", msg.Text)
+		// saved words starting with other letters aren't suggested
+		assert.Equal(t, tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("catamaran", callbackIDLookup+"|catamaran"),
+		)), msg.ReplyMarkup)
+	})
+	t.Run("no suggestions", func(t *testing.T) {
+		b := newStubBot(storage)
+		h.sendSuggestions(b, 1, "zzzzzzzzzz")
+		assert.Equal(t, []string{"Sorry, I don't know this word"}, b.texts())
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 
 	"github.com/rs/zerolog/log"
 )
@@ -24,7 +25,7 @@ type Client struct {
 // Get returns dictionary item by word
 func (c *Client) Get(word string) (items []WordResponse, err error) {
 	req, err := http.NewRequest(
-		http.MethodGet, "https://api.dictionaryapi.dev/api/v2/entries/en/"+word, nil,
+		http.MethodGet, "https://api.dictionaryapi.dev/api/v2/entries/en/"+url.PathEscape(word), nil,
 	)
 	if err != nil {
 		return items, fmt.Errorf("create request: %w", err)
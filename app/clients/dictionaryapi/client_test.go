@@ -179,4 +179,20 @@ func TestGet(t *testing.T) {
 		assert.ErrorIs(t, err, ErrNotFound)
 		assert.Nil(t, items)
 	})
+	t.Run("phrase", func(t *testing.T) {
+		httpClient := &http.Client{
+			Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, "https://api.dictionaryapi.dev/api/v2/entries/en/give%20up%2Fin", req.URL.String())
+				return &http.Response{
+					StatusCode: 200,
+					Body:       ioutil.NopCloser(bytes.NewBufferString("[]")),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		}
+		client := Client{client: httpClient, context: context.TODO()}
+		items, err := client.Get("give up/in")
+		assert.NoError(t, err)
+		assert.Empty(t, items)
+	})
 }
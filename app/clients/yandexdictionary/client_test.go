@@ -187,4 +187,23 @@ func TestTranslate(t *testing.T) {
 		assert.ErrorIs(t, err, ErrUnknown)
 
 	})
+	t.Run("phrase", func(t *testing.T) {
+		httpClient := &http.Client{
+			Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(
+					t,
+					"https://dictionary.yandex.net/api/v1/dicservice.json/lookup?key=test&lang=en-ru&text=give+up",
+					req.URL.String(),
+				)
+				return &http.Response{
+					StatusCode: 200,
+					Body:       ioutil.NopCloser(bytes.NewBufferString(exampleResponse)),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		}
+		client := Client{client: httpClient, apiToken: APItoken, context: context.TODO()}
+		_, err := client.Translate("give up", "en", "ru")
+		assert.NoError(t, err)
+	})
 }
@@ -1,10 +1,14 @@
 package db
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"time"
 
 	bolt "go.etcd.io/bbolt"
 )
@@ -14,6 +18,14 @@ const (
 	bucketUsers             = "Users"
 	bucketUsersDictionaries = "UsersDictionaries"
 	bucketQuizzes           = "Quizzes"
+	bucketUserQuizzes       = "UserQuizzes"
+	bucketGroups            = "Groups"
+	bucketGroupQuizzes      = "GroupQuizzes"
+	bucketGroupScores       = "GroupScores"
+	bucketRefreshSessions   = "RefreshSessions"
+	bucketRevokedTokens     = "RevokedTokens"
+	bucketPersonalTokens    = "PersonalTokens"
+	bucketWordRevisions     = "WordRevisions"
 )
 
 // BoltStorage is a storage implementation using BoltDB
@@ -39,7 +51,7 @@ func (b *BoltStorage) Get(word string) (DictionaryItem, error) {
 
 func (b *BoltStorage) getItem(word string, tx *bolt.Tx) (*DictionaryItem, error) {
 	bucket := tx.Bucket([]byte(bucketDictionary))
-	jdata := bucket.Get([]byte(word))
+	jdata := bucket.Get([]byte(NormalizeWord(word)))
 	if len(jdata) == 0 {
 		return nil, nil
 	}
@@ -58,13 +70,101 @@ func (b *BoltStorage) Save(item DictionaryItem) error {
 		if err != nil {
 			return fmt.Errorf("marshal word: %w", err)
 		}
-		if err := bucket.Put([]byte(item.Word), jdata); err != nil {
+		if err := bucket.Put([]byte(NormalizeWord(item.Word)), jdata); err != nil {
 			return fmt.Errorf("put word: %w", err)
 		}
 		return nil
 	})
 }
 
+// GetWords returns all dictionary words
+func (b *BoltStorage) GetWords() ([]string, error) {
+	var res []string
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketDictionary))
+		res = make([]string, 0, bucket.Stats().KeyN)
+		return bucket.ForEach(func(k, v []byte) error {
+			res = append(res, string(k))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetWordsByPrefix returns up to limit words starting with prefix in lexicographical order
+func (b *BoltStorage) GetWordsByPrefix(prefix string, limit int) ([]string, error) {
+	res := make([]string, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket([]byte(bucketDictionary)).Cursor()
+		for k, _ := c.Seek([]byte(prefix)); k != nil && bytes.HasPrefix(k, []byte(prefix)); k, _ = c.Next() {
+			if limit > 0 && len(res) == limit {
+				break
+			}
+			res = append(res, string(k))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetWordRevisions returns edit history of dictionary item, the oldest revision first
+func (b *BoltStorage) GetWordRevisions(word string) ([]WordRevision, error) {
+	res := make([]WordRevision, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		wordBucket := tx.Bucket([]byte(bucketWordRevisions)).Bucket([]byte(NormalizeWord(word)))
+		if wordBucket == nil {
+			return nil
+		}
+		return wordBucket.ForEach(func(k, v []byte) error {
+			var rev WordRevision
+			if err := json.Unmarshal(v, &rev); err != nil {
+				return fmt.Errorf("unmarshal revision: %w", err)
+			}
+			res = append(res, rev)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SaveWordRevision saves revision of dictionary item, revision number is taken from the word bucket sequence
+func (b *BoltStorage) SaveWordRevision(rev WordRevision) (int, error) {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		wordBucket, err := tx.Bucket([]byte(bucketWordRevisions)).CreateBucketIfNotExists([]byte(NormalizeWord(rev.Word)))
+		if err != nil {
+			return fmt.Errorf("create word revisions bucket: %w", err)
+		}
+		seq, err := wordBucket.NextSequence()
+		if err != nil {
+			return fmt.Errorf("get revision number: %w", err)
+		}
+		rev.Rev = int(seq)
+		jdata, err := json.Marshal(rev)
+		if err != nil {
+			return fmt.Errorf("marshal revision: %w", err)
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, uint64(rev.Rev))
+		if err := wordBucket.Put(key, jdata); err != nil {
+			return fmt.Errorf("put revision: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rev.Rev, nil
+}
+
 // GetUser returns user by id
 func (b *BoltStorage) GetUser(user UserID) (User, error) {
 	var res User
@@ -100,6 +200,27 @@ func (b *BoltStorage) SaveUser(user User) error {
 	})
 }
 
+// GetUsers returns all users sorted by ID
+func (b *BoltStorage) GetUsers() ([]User, error) {
+	var res []User
+	if err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketUsers))
+		res = make([]User, 0, bucket.Stats().KeyN)
+		return bucket.ForEach(func(k, v []byte) error {
+			var user User
+			if err := json.Unmarshal(v, &user); err != nil {
+				return fmt.Errorf("unmarshal user: %w", err)
+			}
+			res = append(res, user)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
 // GetUserItem returns user dictionary item
 func (b *BoltStorage) GetUserItem(user UserID, word string) (UserDictionaryItem, error) {
 	var res UserDictionaryItem
@@ -109,7 +230,7 @@ func (b *BoltStorage) GetUserItem(user UserID, word string) (UserDictionaryItem,
 		if userBucket == nil {
 			return ErrNotFound
 		}
-		jdata := userBucket.Get([]byte(word))
+		jdata := userBucket.Get([]byte(NormalizeWord(word)))
 		if len(jdata) == 0 {
 			return ErrNotFound
 		}
@@ -135,13 +256,59 @@ func (b *BoltStorage) SaveUserItem(item UserDictionaryItem) error {
 		if err != nil {
 			return fmt.Errorf("marshal user dictionary item: %w", err)
 		}
-		if err := userBucket.Put([]byte(item.Word), jdata); err != nil {
+		if err := userBucket.Put([]byte(NormalizeWord(item.Word)), jdata); err != nil {
 			return fmt.Errorf("put item: %w", err)
 		}
 		return nil
 	})
 }
 
+// IterateUserDictionary calls function for every item of user dictionary within a read transaction
+func (b *BoltStorage) IterateUserDictionary(user UserID, fn func(UserDictionaryItem, DictionaryItem) error) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketUsersDictionaries))
+		userBucket := bucket.Bucket([]byte(strconv.FormatInt(int64(user), 10)))
+		if userBucket == nil {
+			return nil
+		}
+		c := userBucket.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var item UserDictionaryItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				return fmt.Errorf("unmarshal user dictionary item: %w", err)
+			}
+			dicItem, err := b.getItem(item.Word, tx)
+			if err != nil {
+				return fmt.Errorf("get dictionary item: %w", err)
+			}
+			if dicItem == nil {
+				// item might be missing in shared dictionary, e.g. imported unknown word with user translations
+				dicItem = &DictionaryItem{Word: item.Word}
+			}
+			if err := fn(item, *dicItem); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DeleteUserItem removes item from user dictionary
+func (b *BoltStorage) DeleteUserItem(user UserID, word string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketUsersDictionaries))
+		userBucket := bucket.Bucket([]byte(strconv.FormatInt(int64(user), 10)))
+		key := []byte(NormalizeWord(word))
+		if userBucket == nil || userBucket.Get(key) == nil {
+			return ErrNotFound
+		}
+		if err := userBucket.Delete(key); err != nil {
+			return fmt.Errorf("delete item: %w", err)
+		}
+		return nil
+	})
+}
+
 // GetUserDictionary returns dictionary items for user
 func (b *BoltStorage) GetUserDictionary(user UserID) (map[UserDictionaryItem]DictionaryItem, error) {
 	res := make(map[UserDictionaryItem]DictionaryItem)
@@ -164,6 +331,9 @@ func (b *BoltStorage) GetUserDictionary(user UserID) (map[UserDictionaryItem]Dic
 			if err != nil {
 				return fmt.Errorf("get dictionary item: %w", err)
 			}
+			if dicItem == nil {
+				dicItem = &DictionaryItem{Word: item.Word}
+			}
 			res[item] = *dicItem
 		}
 		return nil
@@ -185,8 +355,52 @@ func (b *BoltStorage) SaveQuiz(quiz Quiz) error {
 		if err := bucket.Put([]byte(quiz.ID), jdata); err != nil {
 			return fmt.Errorf("put quiz: %w", err)
 		}
+		userBucket, err := tx.Bucket([]byte(bucketUserQuizzes)).
+			CreateBucketIfNotExists([]byte(strconv.FormatInt(int64(quiz.User), 10)))
+		if err != nil {
+			return fmt.Errorf("create user quizzes bucket: %w", err)
+		}
+		if err := userBucket.Put(userQuizKey(quiz), []byte(quiz.ID)); err != nil {
+			return fmt.Errorf("put user quiz: %w", err)
+		}
+		return nil
+	})
+}
+
+// userQuizKey returns key of quiz in user quizzes index, keys are ordered by quiz creation time
+func userQuizKey(quiz Quiz) []byte {
+	key := make([]byte, 8, 8+len(quiz.ID))
+	binary.BigEndian.PutUint64(key, uint64(quiz.Created.UnixNano()))
+	return append(key, quiz.ID...)
+}
+
+// GetUserQuizzes returns latest quizzes of the user
+func (b *BoltStorage) GetUserQuizzes(user UserID, limit int) ([]Quiz, error) {
+	res := make([]Quiz, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		userBucket := tx.Bucket([]byte(bucketUserQuizzes)).Bucket([]byte(strconv.FormatInt(int64(user), 10)))
+		if userBucket == nil {
+			return nil
+		}
+		quizzes := tx.Bucket([]byte(bucketQuizzes))
+		c := userBucket.Cursor()
+		for k, id := c.Last(); k != nil && len(res) < limit; k, id = c.Prev() {
+			jdata := quizzes.Get(id)
+			if len(jdata) == 0 {
+				continue
+			}
+			var quiz Quiz
+			if err := json.Unmarshal(jdata, &quiz); err != nil {
+				return fmt.Errorf("unmarshal quiz: %w", err)
+			}
+			res = append(res, quiz)
+		}
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
 }
 
 // GetQuiz returns quiz by ID`
@@ -209,10 +423,295 @@ func (b *BoltStorage) GetQuiz(id string) (Quiz, error) {
 	return res, nil
 }
 
+// GetGroup returns group by ID
+func (b *BoltStorage) GetGroup(id ChatID) (Group, error) {
+	var res Group
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketGroups))
+		jdata := bucket.Get([]byte(strconv.FormatInt(int64(id), 10)))
+		if len(jdata) == 0 {
+			return ErrNotFound
+		}
+		if err := json.Unmarshal(jdata, &res); err != nil {
+			return fmt.Errorf("unmarshal group: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Group{}, err
+	}
+	return res, nil
+}
+
+// SaveGroup saves group to database
+func (b *BoltStorage) SaveGroup(group Group) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketGroups))
+		jdata, err := json.Marshal(group)
+		if err != nil {
+			return fmt.Errorf("marshal group: %w", err)
+		}
+		if err := bucket.Put([]byte(strconv.FormatInt(int64(group.ID), 10)), jdata); err != nil {
+			return fmt.Errorf("put group: %w", err)
+		}
+		return nil
+	})
+}
+
+// SaveGroupQuiz saves group quiz to database
+func (b *BoltStorage) SaveGroupQuiz(quiz GroupQuiz) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketGroupQuizzes))
+		jdata, err := json.Marshal(quiz)
+		if err != nil {
+			return fmt.Errorf("marshal group quiz: %w", err)
+		}
+		if err := bucket.Put([]byte(quiz.ID), jdata); err != nil {
+			return fmt.Errorf("put group quiz: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetGroupQuiz returns group quiz by ID
+func (b *BoltStorage) GetGroupQuiz(id string) (GroupQuiz, error) {
+	var res GroupQuiz
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketGroupQuizzes))
+		jdata := bucket.Get([]byte(id))
+		if len(jdata) == 0 {
+			return ErrNotFound
+		}
+		if err := json.Unmarshal(jdata, &res); err != nil {
+			return fmt.Errorf("unmarshal group quiz: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return GroupQuiz{}, err
+	}
+	return res, nil
+}
+
+// GetGroupScores returns scores of group members for the week,
+// scores are kept in chat buckets with week|user keys
+func (b *BoltStorage) GetGroupScores(chat ChatID, week string) ([]GroupScore, error) {
+	var res []GroupScore
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketGroupScores))
+		chatBucket := bucket.Bucket([]byte(strconv.FormatInt(int64(chat), 10)))
+		if chatBucket == nil {
+			return nil
+		}
+		prefix := []byte(week + "|")
+		c := chatBucket.Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var score GroupScore
+			if err := json.Unmarshal(v, &score); err != nil {
+				return fmt.Errorf("unmarshal group score: %w", err)
+			}
+			res = append(res, score)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SaveGroupScore saves score of group member to database
+func (b *BoltStorage) SaveGroupScore(score GroupScore) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketGroupScores))
+		chatBucket, err := bucket.CreateBucketIfNotExists([]byte(strconv.FormatInt(int64(score.Chat), 10)))
+		if err != nil {
+			return fmt.Errorf("create chat bucket: %w", err)
+		}
+		jdata, err := json.Marshal(score)
+		if err != nil {
+			return fmt.Errorf("marshal group score: %w", err)
+		}
+		key := score.Week + "|" + strconv.FormatInt(int64(score.User), 10)
+		if err := chatBucket.Put([]byte(key), jdata); err != nil {
+			return fmt.Errorf("put group score: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetRefreshSession returns refresh session by ID
+func (b *BoltStorage) GetRefreshSession(id string) (RefreshSession, error) {
+	var res RefreshSession
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketRefreshSessions))
+		jdata := bucket.Get([]byte(id))
+		if len(jdata) == 0 {
+			return ErrNotFound
+		}
+		if err := json.Unmarshal(jdata, &res); err != nil {
+			return fmt.Errorf("unmarshal refresh session: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return RefreshSession{}, err
+	}
+	return res, nil
+}
+
+// SaveRefreshSession saves refresh session to database
+func (b *BoltStorage) SaveRefreshSession(session RefreshSession) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketRefreshSessions))
+		jdata, err := json.Marshal(session)
+		if err != nil {
+			return fmt.Errorf("marshal refresh session: %w", err)
+		}
+		if err := bucket.Put([]byte(session.ID), jdata); err != nil {
+			return fmt.Errorf("put refresh session: %w", err)
+		}
+		return nil
+	})
+}
+
+// RevokeToken adds token ID to denylist with its expiration time,
+// expired tokens are dropped from denylist meanwhile
+func (b *BoltStorage) RevokeToken(id string, expires time.Time) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(bucketRevokedTokens))
+		now := time.Now()
+		var expired [][]byte
+		if err := bucket.ForEach(func(k, v []byte) error {
+			var exp time.Time
+			if err := exp.UnmarshalText(v); err != nil || exp.Before(now) {
+				expired = append(expired, k)
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("iterate revoked tokens: %w", err)
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return fmt.Errorf("delete revoked token: %w", err)
+			}
+		}
+		data, err := expires.UTC().MarshalText()
+		if err != nil {
+			return fmt.Errorf("marshal expiration: %w", err)
+		}
+		if err := bucket.Put([]byte(id), data); err != nil {
+			return fmt.Errorf("put revoked token: %w", err)
+		}
+		return nil
+	})
+}
+
+// IsTokenRevoked returns true if token ID is in denylist and isn't expired yet
+func (b *BoltStorage) IsTokenRevoked(id string) (bool, error) {
+	var revoked bool
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket([]byte(bucketRevokedTokens)).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		var exp time.Time
+		if err := exp.UnmarshalText(data); err != nil {
+			return fmt.Errorf("unmarshal expiration: %w", err)
+		}
+		revoked = exp.After(time.Now())
+		return nil
+	})
+	return revoked, err
+}
+
+// GetPersonalToken returns personal access token by ID
+func (b *BoltStorage) GetPersonalToken(id string) (PersonalToken, error) {
+	var res PersonalToken
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		res, err = b.getPersonalToken(tx, id)
+		return err
+	})
+	if err != nil {
+		return PersonalToken{}, err
+	}
+	return res, nil
+}
+
+func (b *BoltStorage) getPersonalToken(tx *bolt.Tx, id string) (PersonalToken, error) {
+	var res PersonalToken
+	jdata := tx.Bucket([]byte(bucketPersonalTokens)).Get([]byte(id))
+	if len(jdata) == 0 {
+		return res, ErrNotFound
+	}
+	if err := json.Unmarshal(jdata, &res); err != nil {
+		return res, fmt.Errorf("unmarshal personal token: %w", err)
+	}
+	return res, nil
+}
+
+// GetPersonalTokens returns personal access tokens of the user ordered by creation time
+func (b *BoltStorage) GetPersonalTokens(user UserID) ([]PersonalToken, error) {
+	var res []PersonalToken
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(bucketPersonalTokens)).ForEach(func(k, v []byte) error {
+			var token PersonalToken
+			if err := json.Unmarshal(v, &token); err != nil {
+				return fmt.Errorf("unmarshal personal token: %w", err)
+			}
+			if token.User == user {
+				res = append(res, token)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sortPersonalTokens(res)
+	return res, nil
+}
+
+// SavePersonalToken saves personal access token to database
+func (b *BoltStorage) SavePersonalToken(token PersonalToken) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		jdata, err := json.Marshal(token)
+		if err != nil {
+			return fmt.Errorf("marshal personal token: %w", err)
+		}
+		if err := tx.Bucket([]byte(bucketPersonalTokens)).Put([]byte(token.ID), jdata); err != nil {
+			return fmt.Errorf("put personal token: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeletePersonalToken deletes personal access token of the user from database
+func (b *BoltStorage) DeletePersonalToken(user UserID, id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		token, err := b.getPersonalToken(tx, id)
+		if err != nil {
+			return err
+		}
+		if token.User != user {
+			return ErrNotFound
+		}
+		if err := tx.Bucket([]byte(bucketPersonalTokens)).Delete([]byte(id)); err != nil {
+			return fmt.Errorf("delete personal token: %w", err)
+		}
+		return nil
+	})
+}
+
 // NewBoltStorage creates BoltStorage instance and initialize buckets
 func NewBoltStorage(db *bolt.DB) (*BoltStorage, error) {
 	err := db.Update(func(tx *bolt.Tx) error {
-		for _, bucket := range []string{bucketUsers, bucketUsersDictionaries, bucketDictionary, bucketQuizzes} {
+		for _, bucket := range []string{
+			bucketUsers, bucketUsersDictionaries, bucketDictionary, bucketQuizzes, bucketUserQuizzes,
+			bucketGroups, bucketGroupQuizzes, bucketGroupScores,
+			bucketRefreshSessions, bucketRevokedTokens, bucketPersonalTokens, bucketWordRevisions,
+		} {
 			_, err := tx.CreateBucketIfNotExists([]byte(bucket))
 			if err != nil {
 				return err
@@ -2,6 +2,7 @@ package db
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -31,7 +32,7 @@ func getStorage(t *testing.T) (*BoltStorage, func()) {
 }
 
 func getItem() DictionaryItem {
-	meanings := []meaning{
+	meanings := []Meaning{
 		{
 			PartOfSpeech: "pof1",
 			Definition:   "def1",
@@ -159,6 +160,98 @@ func TestBoltSave(t *testing.T) {
 	})
 }
 
+func TestBoltPhrase(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	item := DictionaryItem{Word: "give up"}
+	require.NoError(t, storage.Save(item))
+	res, err := storage.Get(" Give  up")
+	assert.NoError(t, err)
+	assert.Equal(t, item, res)
+
+	userItem := UserDictionaryItem{User: UserID(1), Word: "give up", Created: time.Now().UTC().Truncate(time.Second)}
+	require.NoError(t, storage.SaveUserItem(userItem))
+	resUserItem, err := storage.GetUserItem(userItem.User, "give  up")
+	assert.NoError(t, err)
+	assert.Equal(t, userItem, resUserItem)
+	dict, err := storage.GetUserDictionary(userItem.User)
+	assert.NoError(t, err)
+	assert.Equal(t, map[UserDictionaryItem]DictionaryItem{userItem: item}, dict)
+}
+
+func TestBoltGetWords(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		storage, cleanup := getStorage(t)
+		defer cleanup()
+		require.NoError(t, storage.Save(DictionaryItem{Word: "test2"}))
+		require.NoError(t, storage.Save(DictionaryItem{Word: "test1"}))
+		words, err := storage.GetWords()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"test1", "test2"}, words)
+	})
+	t.Run("empty", func(t *testing.T) {
+		storage, cleanup := getStorage(t)
+		defer cleanup()
+		words, err := storage.GetWords()
+		assert.NoError(t, err)
+		assert.Empty(t, words)
+	})
+}
+
+func TestBoltGetWordsByPrefix(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	for _, w := range []string{"test", "cat", "tea", "team", "tf"} {
+		require.NoError(t, storage.Save(DictionaryItem{Word: w}))
+	}
+	words, err := storage.GetWordsByPrefix("te", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tea", "team"}, words)
+	words, err = storage.GetWordsByPrefix("te", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tea", "team", "test"}, words)
+	words, err = storage.GetWordsByPrefix("", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cat"}, words)
+	words, err = storage.GetWordsByPrefix("x", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, words)
+}
+
+func TestBoltWordRevisions(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	revisions, err := storage.GetWordRevisions("test")
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := make([]WordRevision, 0, 10)
+	for rev := 1; rev <= 10; rev++ {
+		expected = append(expected, WordRevision{
+			Word:    "test",
+			Rev:     rev,
+			Author:  UserID(1),
+			Created: created,
+			Diff:    []string{"+ translation (ru): тест"},
+			Item:    DictionaryItem{Word: "test", EditedBy: UserID(1)},
+		})
+	}
+	for _, rev := range expected {
+		n, err := storage.SaveWordRevision(WordRevision{
+			Word: rev.Word, Author: rev.Author, Created: rev.Created, Diff: rev.Diff, Item: rev.Item,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, rev.Rev, n)
+	}
+	n, err := storage.SaveWordRevision(WordRevision{Word: "other"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	revisions, err = storage.GetWordRevisions(" Test")
+	require.NoError(t, err)
+	assert.Equal(t, expected, revisions)
+}
+
 func TestBoltSaveUser(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		storage, cleanup := getStorage(t)
@@ -197,6 +290,22 @@ func TestBoltGetUser(t *testing.T) {
 	})
 }
 
+func TestBoltGetUsers(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	users, err := storage.GetUsers()
+	require.NoError(t, err)
+	assert.Empty(t, users)
+
+	lastSeen := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	expected := []User{{ID: UserID(2), Username: "second"}, {ID: UserID(10), Blocked: true, LastSeen: &lastSeen}}
+	require.NoError(t, storage.SaveUser(expected[1]))
+	require.NoError(t, storage.SaveUser(expected[0]))
+	users, err = storage.GetUsers()
+	require.NoError(t, err)
+	assert.Equal(t, expected, users)
+}
+
 func TestBoltGetUserItem(t *testing.T) {
 	t.Run("ok", func(t *testing.T) {
 		storage, cleanup := getStorage(t)
@@ -329,6 +438,20 @@ func TestBoltGetUserDictionary(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, map[UserDictionaryItem]DictionaryItem{userItem1: item1, userItem2: item2}, res)
 	})
+	t.Run("missing item", func(t *testing.T) {
+		storage, cleanup := getStorage(t)
+		defer cleanup()
+		userItem := UserDictionaryItem{Word: "qwerty", User: UserID(1), Custom: &UserItemCustom{Translations: []string{"йцукен"}}}
+		require.NoError(t, storage.SaveUserItem(userItem))
+
+		res, err := storage.GetUserDictionary(UserID(1))
+		require.NoError(t, err)
+		require.Len(t, res, 1)
+		for item, dictItem := range res {
+			assert.Equal(t, userItem.CustomTranslations(), item.CustomTranslations())
+			assert.Equal(t, DictionaryItem{Word: "qwerty"}, dictItem)
+		}
+	})
 	t.Run("empty", func(t *testing.T) {
 		storage, cleanup := getStorage(t)
 		defer cleanup()
@@ -339,6 +462,62 @@ func TestBoltGetUserDictionary(t *testing.T) {
 
 	})
 }
+func TestBoltGetUserQuizzes(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	now := time.Now().UTC()
+	for id, created := range map[string]time.Time{
+		"old": now.Add(-time.Hour), "new": now, "middle": now.Add(-time.Minute),
+	} {
+		require.NoError(t, storage.SaveQuiz(Quiz{ID: id, User: UserID(1), Created: created}))
+	}
+	require.NoError(t, storage.SaveQuiz(Quiz{ID: "other", User: UserID(2), Created: now}))
+
+	res, err := storage.GetUserQuizzes(UserID(1), 2)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "new", res[0].ID)
+	assert.Equal(t, "middle", res[1].ID)
+
+	// saving answered quiz doesn't duplicate it
+	quiz := res[0]
+	quiz.Result = &QuizResult{Choice: 1}
+	require.NoError(t, storage.SaveQuiz(quiz))
+	res, err = storage.GetUserQuizzes(UserID(1), 10)
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+	assert.Equal(t, &QuizResult{Choice: 1}, res[0].Result)
+	assert.Equal(t, "old", res[2].ID)
+
+	res, err = storage.GetUserQuizzes(UserID(3), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+func TestBoltIterateUserDictionary(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	item := DictionaryItem{Word: "test"}
+	require.NoError(t, storage.Save(item))
+	userItems := []UserDictionaryItem{{Word: "test", User: UserID(1)}, {Word: "missing", User: UserID(1)}}
+	for _, userItem := range userItems {
+		require.NoError(t, storage.SaveUserItem(userItem))
+	}
+	require.NoError(t, storage.SaveUserItem(UserDictionaryItem{Word: "test", User: UserID(2)}))
+
+	res := make(map[UserDictionaryItem]DictionaryItem)
+	err := storage.IterateUserDictionary(UserID(1), func(userItem UserDictionaryItem, item DictionaryItem) error {
+		res[userItem] = item
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[UserDictionaryItem]DictionaryItem{userItems[0]: item, userItems[1]: {Word: "missing"}}, res)
+
+	testErr := errors.New("test")
+	err = storage.IterateUserDictionary(UserID(1), func(UserDictionaryItem, DictionaryItem) error { return testErr })
+	assert.ErrorIs(t, err, testErr)
+}
+
 func TestBoltSaveQuiz(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		storage, cleanup := getStorage(t)
@@ -441,3 +620,145 @@ func TestBoltGetQuiz(t *testing.T) {
 		assert.Error(t, err)
 	})
 }
+
+func TestBoltGroup(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	_, err := storage.GetGroup(ChatID(-1))
+	assert.ErrorIs(t, err, ErrNotFound)
+	group := Group{ID: ChatID(-1), Title: "test", Words: []string{"cat", "give up"}}
+	require.NoError(t, storage.SaveGroup(group))
+	res, err := storage.GetGroup(ChatID(-1))
+	assert.NoError(t, err)
+	assert.Equal(t, group, res)
+}
+
+func TestBoltGroupQuiz(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	_, err := storage.GetGroupQuiz("test")
+	assert.ErrorIs(t, err, ErrNotFound)
+	winner := UserID(1)
+	quiz := GroupQuiz{
+		ID:      "test",
+		Chat:    ChatID(-1),
+		Word:    "test",
+		Choices: []QuizItem{{Word: "test", Text: "тест", Correct: true}},
+		Created: time.Now().UTC().Truncate(time.Second),
+		Answers: map[UserID]int{1: 0, 2: 0},
+		Winner:  &winner,
+	}
+	require.NoError(t, storage.SaveGroupQuiz(quiz))
+	res, err := storage.GetGroupQuiz("test")
+	assert.NoError(t, err)
+	assert.Equal(t, quiz, res)
+}
+
+func TestBoltGroupScores(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	res, err := storage.GetGroupScores(ChatID(-1), "2023-W19")
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+
+	scores := []GroupScore{
+		{Chat: ChatID(-1), User: UserID(1), Username: "first", Week: "2023-W19", Points: 2},
+		{Chat: ChatID(-1), User: UserID(2), Username: "second", Week: "2023-W19", Points: 1},
+	}
+	for _, s := range append(scores,
+		GroupScore{Chat: ChatID(-1), User: UserID(1), Week: "2023-W20", Points: 5},
+		GroupScore{Chat: ChatID(-2), User: UserID(1), Week: "2023-W19", Points: 5},
+	) {
+		require.NoError(t, storage.SaveGroupScore(s))
+	}
+	res, err = storage.GetGroupScores(ChatID(-1), "2023-W19")
+	assert.NoError(t, err)
+	assert.Equal(t, scores, res)
+}
+
+func TestBoltRefreshSession(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	_, err := storage.GetRefreshSession("test")
+	assert.ErrorIs(t, err, ErrNotFound)
+	now := time.Now().UTC().Truncate(time.Second)
+	session := RefreshSession{
+		ID:            "test",
+		User:          UserID(1),
+		TokenHash:     "hash",
+		AccessTokenID: "jti",
+		AccessExpires: now.Add(time.Hour),
+		Created:       now,
+		Expires:       now.Add(24 * time.Hour),
+	}
+	require.NoError(t, storage.SaveRefreshSession(session))
+	res, err := storage.GetRefreshSession("test")
+	assert.NoError(t, err)
+	assert.Equal(t, session, res)
+}
+
+func TestBoltRevokedTokens(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	revoked, err := storage.IsTokenRevoked("test")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, storage.RevokeToken("expired", time.Now().Add(-time.Minute)))
+	require.NoError(t, storage.RevokeToken("test", time.Now().Add(time.Hour)))
+	revoked, err = storage.IsTokenRevoked("test")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = storage.IsTokenRevoked("expired")
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+	err = storage.db.View(func(tx *bolt.Tx) error {
+		assert.Nil(t, tx.Bucket([]byte(bucketRevokedTokens)).Get([]byte("expired")))
+		return nil
+	})
+	assert.NoError(t, err)
+}
+
+func TestBoltPersonalTokens(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	_, err := storage.GetPersonalToken("test")
+	assert.ErrorIs(t, err, ErrNotFound)
+	now := time.Now().UTC().Truncate(time.Second)
+	tokens := []PersonalToken{
+		{ID: "b", User: UserID(1), Name: "first", Scope: ScopeRead, Hash: "hash", Created: now},
+		{ID: "a", User: UserID(1), Name: "second", Scope: ScopeWrite, Hash: "hash", Created: now.Add(time.Minute)},
+	}
+	for _, token := range append(tokens, PersonalToken{ID: "c", User: UserID(2), Created: now}) {
+		require.NoError(t, storage.SavePersonalToken(token))
+	}
+	res, err := storage.GetPersonalToken("b")
+	assert.NoError(t, err)
+	assert.Equal(t, tokens[0], res)
+	list, err := storage.GetPersonalTokens(UserID(1))
+	assert.NoError(t, err)
+	assert.Equal(t, tokens, list)
+
+	assert.ErrorIs(t, storage.DeletePersonalToken(UserID(2), "b"), ErrNotFound)
+	assert.ErrorIs(t, storage.DeletePersonalToken(UserID(1), "unknown"), ErrNotFound)
+	require.NoError(t, storage.DeletePersonalToken(UserID(1), "b"))
+	_, err = storage.GetPersonalToken("b")
+	assert.ErrorIs(t, err, ErrNotFound)
+	list, err = storage.GetPersonalTokens(UserID(1))
+	assert.NoError(t, err)
+	assert.Equal(t, tokens[1:], list)
+}
+
+func TestBoltDeleteUserItem(t *testing.T) {
+	storage, cleanup := getStorage(t)
+	defer cleanup()
+	assert.ErrorIs(t, storage.DeleteUserItem(UserID(1), "test"), ErrNotFound)
+	require.NoError(t, storage.SaveUserItem(UserDictionaryItem{User: UserID(1), Word: "test"}))
+	require.NoError(t, storage.SaveUserItem(UserDictionaryItem{User: UserID(1), Word: "other"}))
+	assert.NoError(t, storage.DeleteUserItem(UserID(1), " Test"))
+	_, err := storage.GetUserItem(UserID(1), "test")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = storage.GetUserItem(UserID(1), "other")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, storage.DeleteUserItem(UserID(1), "test"), ErrNotFound)
+}
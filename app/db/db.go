@@ -4,7 +4,11 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/rbhz/tg-dictionary/app/clients/dictionaryapi"
 	yandexdictionary "github.com/rbhz/tg-dictionary/app/clients/yandexdictionary"
@@ -18,35 +22,95 @@ type UserID int64
 // ErrNotFound is returned when object not found
 var ErrNotFound error = errors.New("not found")
 
+// NormalizeWord returns word or phrase in the form used as a storage key:
+// lower cased with surrounding spaces trimmed and inner whitespace collapsed
+func NormalizeWord(word string) string {
+	return strings.ToLower(strings.Join(strings.Fields(word), " "))
+}
+
 // GenerateID generates new uuid and encodes it to base64
 func GenerateID() string {
 	id := [16]byte(uuid.New())
 	return base64.RawURLEncoding.EncodeToString(id[:])
 }
 
+// MaxPhraseWords limits number of words in a looked up phrase
+const MaxPhraseWords = 5
+
 // Storage defines method provided by database interfaces
 type Storage interface {
 	// Get dictionary item by word
 	Get(string) (DictionaryItem, error)
 	// Save dictionary item to DB
 	Save(DictionaryItem) error
+	// GetWords returns all words saved in dictionary
+	GetWords() ([]string, error)
+	// GetWordsByPrefix returns up to limit words starting with prefix in lexicographical order,
+	// zero limit returns all of them
+	GetWordsByPrefix(prefix string, limit int) ([]string, error)
+	// GetWordRevisions returns edit history of dictionary item, the oldest revision first
+	GetWordRevisions(string) ([]WordRevision, error)
+	// SaveWordRevision appends revision to edit history of dictionary item,
+	// revision number is allocated by storage and returned
+	SaveWordRevision(WordRevision) (int, error)
 
 	// GetUser returns user by ID
 	GetUser(UserID) (User, error)
 	// SaveUser saves user to DB
 	SaveUser(User) error
+	// GetUsers returns all users sorted by ID
+	GetUsers() ([]User, error)
 
 	// GetUserDictionary returns item from user dictionary
 	GetUserItem(UserID, string) (UserDictionaryItem, error)
 	// SaveUserItem saves UserDictionaryItem
 	SaveUserItem(UserDictionaryItem) error
+	// DeleteUserItem removes word from user dictionary, ErrNotFound is returned for unknown words
+	DeleteUserItem(UserID, string) error
 	// GetUserDictionary returns map of user dictionary items
 	GetUserDictionary(UserID) (map[UserDictionaryItem]DictionaryItem, error)
+	// IterateUserDictionary calls function for every item of user dictionary in unspecified order
+	// without loading the whole dictionary, iteration stops on the first error returned by function.
+	// Function must not change storage
+	IterateUserDictionary(UserID, func(UserDictionaryItem, DictionaryItem) error) error
 
 	// SaveQuiz saves quiz to DB
 	SaveQuiz(Quiz) error
 	// GetQuiz returns quiz by ID
 	GetQuiz(string) (Quiz, error)
+	// GetUserQuizzes returns up to limit latest quizzes of the user, the newest first
+	GetUserQuizzes(UserID, int) ([]Quiz, error)
+
+	// GetGroup returns group chat by ID
+	GetGroup(ChatID) (Group, error)
+	// SaveGroup saves group chat
+	SaveGroup(Group) error
+	// SaveGroupQuiz saves group quiz
+	SaveGroupQuiz(GroupQuiz) error
+	// GetGroupQuiz returns group quiz by ID
+	GetGroupQuiz(string) (GroupQuiz, error)
+	// GetGroupScores returns scores of group members for the week
+	GetGroupScores(ChatID, string) ([]GroupScore, error)
+	// SaveGroupScore saves score of group member
+	SaveGroupScore(GroupScore) error
+
+	// GetRefreshSession returns API refresh session by ID
+	GetRefreshSession(string) (RefreshSession, error)
+	// SaveRefreshSession saves API refresh session, it may be dropped after expiration
+	SaveRefreshSession(RefreshSession) error
+	// RevokeToken adds API access token ID to denylist until the token expiration
+	RevokeToken(string, time.Time) error
+	// IsTokenRevoked returns true if API access token ID is in denylist
+	IsTokenRevoked(string) (bool, error)
+
+	// GetPersonalToken returns personal access token by ID
+	GetPersonalToken(string) (PersonalToken, error)
+	// GetPersonalTokens returns personal access tokens of the user
+	GetPersonalTokens(UserID) ([]PersonalToken, error)
+	// SavePersonalToken saves personal access token
+	SavePersonalToken(PersonalToken) error
+	// DeletePersonalToken deletes personal access token of the user, ErrNotFound is returned for unknown tokens
+	DeletePersonalToken(UserID, string) error
 }
 
 // User holds user data
@@ -56,11 +120,67 @@ type User struct {
 	Username string
 	Language string
 	Config   UserConfig
+	// Blocked users are ignored by the bot and can't log in to the API
+	Blocked bool `json:",omitempty"`
+	// LastSeen is a time of the latest user update handled by the bot
+	LastSeen *time.Time `json:",omitempty"`
 }
 
 // UserConfig holds user config params
 type UserConfig struct {
-	QuizType *string
+	// QuizType is a single quiz type picked before mixed quizzes were supported,
+	// it's used only if QuizTypes is empty
+	QuizType  *string
+	QuizTypes []string
+	// QuizTags restricts quizzes to words with any of the tags, all words are used if it's empty
+	QuizTags []string `json:",omitempty"`
+	// MasteryThreshold is a number of correct answers in a row making word mastered,
+	// DefaultMasteryThreshold is used if it's not set
+	MasteryThreshold int `json:",omitempty"`
+	Course           *CourseConfig
+}
+
+// mastery threshold limits
+const (
+	DefaultMasteryThreshold = 5
+	MinMasteryThreshold     = 2
+	MaxMasteryThreshold     = 20
+)
+
+// EffectiveMasteryThreshold returns mastery threshold picked by user or the default one
+func (c UserConfig) EffectiveMasteryThreshold() int {
+	if c.MasteryThreshold < MinMasteryThreshold || c.MasteryThreshold > MaxMasteryThreshold {
+		return DefaultMasteryThreshold
+	}
+	return c.MasteryThreshold
+}
+
+// CourseConfig holds user subscription to a built-in word list
+type CourseConfig struct {
+	Level       string
+	WordsPerDay int
+	// Offset is a position of the next word in the list
+	Offset     int
+	LastSeeded *time.Time
+}
+
+// IsDue returns true if new words should be added to user dictionary
+func (c CourseConfig) IsDue(now time.Time) bool {
+	if c.LastSeeded == nil {
+		return true
+	}
+	return c.LastSeeded.UTC().Truncate(24 * time.Hour).Before(now.UTC().Truncate(24 * time.Hour))
+}
+
+// EnabledQuizTypes returns quiz types enabled by user
+func (c UserConfig) EnabledQuizTypes() []string {
+	if len(c.QuizTypes) != 0 {
+		return c.QuizTypes
+	}
+	if c.QuizType != nil {
+		return []string{*c.QuizType}
+	}
+	return []string{QuizTypeDefault}
 }
 
 // DictionaryItem hold data for a single dictionary item
@@ -70,11 +190,27 @@ type DictionaryItem struct {
 		Text  string
 		Audio string
 	}
-	Meanings     []meaning
-	Translations []translation
+	Meanings     []Meaning
+	Translations []Translation
+	// EditedBy is an ID of the admin who made the latest change, it's zero for data fetched from providers
+	EditedBy UserID `json:",omitempty"`
+	// FetchedAt is a time of the latest lookup in dictionary providers, it's nil for items saved before it was kept
+	FetchedAt *time.Time `json:",omitempty"`
+	// Incomplete lists providers failed on the latest lookup, so their data is missing or outdated
+	Incomplete []string `json:",omitempty"`
 }
 
-type meaning struct {
+// NeedsRefresh returns true if item data should be fetched from providers again:
+// some providers failed or the data is older than maxAge. Items edited by admins are kept as is
+func (i DictionaryItem) NeedsRefresh(now time.Time, maxAge time.Duration) bool {
+	if i.EditedBy != 0 {
+		return false
+	}
+	return len(i.Incomplete) != 0 || i.FetchedAt == nil || now.Sub(*i.FetchedAt) > maxAge
+}
+
+// Meaning holds a single definition of dictionary item
+type Meaning struct {
 	PartOfSpeech string
 	Definition   string
 	Examples     []string
@@ -82,7 +218,8 @@ type meaning struct {
 	Antonyms     []string
 }
 
-type translation struct {
+// Translation holds a single translation of dictionary item
+type Translation struct {
 	Text         string
 	Audio        string
 	Language     string
@@ -117,7 +254,7 @@ func NewDictionaryItem(
 		}
 		for _, m := range ri.Meanings {
 			for _, d := range m.Definitions {
-				m := meaning{
+				m := Meaning{
 					PartOfSpeech: m.PartOfSpeech,
 					Definition:   d.Definition,
 					Antonyms:     d.Antonyms,
@@ -136,7 +273,7 @@ func NewDictionaryItem(
 	for lang, tranlationResponse := range translations {
 		for _, d := range tranlationResponse.Definitions {
 			for _, t := range d.Translations {
-				item.Translations = append(item.Translations, translation{
+				item.Translations = append(item.Translations, Translation{
 					Text:         t.Text,
 					Language:     lang,
 					PartOfSpeech: d.PartOfSpeech,
@@ -153,6 +290,294 @@ type UserDictionaryItem struct {
 	User     UserID
 	Created  time.Time
 	LastQuiz *time.Time
+	Stats    *UserItemStats  `json:",omitempty"`
+	Custom   *UserItemCustom `json:",omitempty"`
+}
+
+// CustomTranslationLanguage is a language of user specific translations
+const CustomTranslationLanguage = "ru"
+
+// UserItemCustom holds user specific data of the item: overrides of dictionary item data and tags
+type UserItemCustom struct {
+	Translations []string `json:",omitempty"`
+	Notes        string   `json:",omitempty"`
+	Examples     []string `json:",omitempty"`
+	Tags         []string `json:",omitempty"`
+}
+
+// IsEmpty returns true if there is no user specific data
+func (c UserItemCustom) IsEmpty() bool {
+	return len(c.Translations) == 0 && c.Notes == "" && len(c.Examples) == 0 && len(c.Tags) == 0
+}
+
+// maxTagLength limits length of a single tag
+const maxTagLength = 32
+
+// ErrInvalidTag is returned for tags which can't be normalized
+var ErrInvalidTag = errors.New("invalid tag")
+
+// NormalizeTag returns tag in the stored form: lower cased, without leading # and
+// with spaces replaced by underscores. Only letters, digits, _ and - are allowed
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(tag), "#")), "_"))
+	if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+		return "", ErrInvalidTag
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return "", ErrInvalidTag
+		}
+	}
+	return tag, nil
+}
+
+// CustomData returns copy of user specific data of the item
+func (i UserDictionaryItem) CustomData() UserItemCustom {
+	if i.Custom == nil {
+		return UserItemCustom{}
+	}
+	return *i.Custom
+}
+
+// SetCustom sets user specific data of the item, empty data is removed
+func (i *UserDictionaryItem) SetCustom(custom UserItemCustom) {
+	if custom.IsEmpty() {
+		i.Custom = nil
+		return
+	}
+	i.Custom = &custom
+}
+
+// Personalize returns copy of dictionary item with user specific data applied:
+// custom translations replace translations to their language
+// and custom examples are added to the first meaning if there is one.
+// Word of the user item is used for words missing in the shared dictionary
+func (i UserDictionaryItem) Personalize(item DictionaryItem) DictionaryItem {
+	if item.Word == "" {
+		item.Word = i.Word
+	}
+	if i.Custom == nil {
+		return item
+	}
+	if len(i.Custom.Translations) != 0 {
+		translations := make([]Translation, 0, len(item.Translations)+len(i.Custom.Translations))
+		for _, t := range i.Custom.Translations {
+			translations = append(translations, Translation{Text: t, Language: CustomTranslationLanguage})
+		}
+		for _, t := range item.Translations {
+			if t.Language != CustomTranslationLanguage {
+				translations = append(translations, t)
+			}
+		}
+		item.Translations = translations
+	}
+	if len(i.Custom.Examples) != 0 && len(item.Meanings) != 0 {
+		meanings := make([]Meaning, len(item.Meanings))
+		copy(meanings, item.Meanings)
+		meanings[0].Examples = append(append([]string{}, i.Custom.Examples...), meanings[0].Examples...)
+		item.Meanings = meanings
+	}
+	return item
+}
+
+// CustomTranslations returns user specific translations of the item
+func (i UserDictionaryItem) CustomTranslations() []string {
+	if i.Custom == nil {
+		return nil
+	}
+	return i.Custom.Translations
+}
+
+// Tags returns user defined tags of the item
+func (i UserDictionaryItem) Tags() []string {
+	if i.Custom == nil {
+		return nil
+	}
+	return i.Custom.Tags
+}
+
+// HasAnyTag returns true if the item has at least one of the tags
+func (i UserDictionaryItem) HasAnyTag(tags []string) bool {
+	for _, t := range i.Tags() {
+		for _, tag := range tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetTags replaces item tags, tags are expected to be normalized.
+// Duplicates are removed and tags are kept sorted
+func (i *UserDictionaryItem) SetTags(tags []string) {
+	unique := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, t := range tags {
+		if _, ok := seen[t]; !ok {
+			seen[t] = struct{}{}
+			unique = append(unique, t)
+		}
+	}
+	sort.Strings(unique)
+	if len(unique) == 0 {
+		unique = nil
+	}
+	custom := i.CustomData()
+	custom.Tags = unique
+	i.SetCustom(custom)
+}
+
+// AddTags adds tags to the item
+func (i *UserDictionaryItem) AddTags(tags ...string) {
+	i.SetTags(append(append([]string{}, i.Tags()...), tags...))
+}
+
+// RemoveTags removes tags from the item
+func (i *UserDictionaryItem) RemoveTags(tags ...string) {
+	kept := make([]string, 0, len(i.Tags()))
+	for _, t := range i.Tags() {
+		var removed bool
+		for _, tag := range tags {
+			if t == tag {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			kept = append(kept, t)
+		}
+	}
+	i.SetTags(kept)
+}
+
+// AddUserItem adds word to user dictionary if it's not there yet,
+// returns user item and true if the word was added
+func AddUserItem(s Storage, user UserID, word string) (UserDictionaryItem, bool, error) {
+	userItem, err := s.GetUserItem(user, word)
+	if err == nil {
+		return userItem, false, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return userItem, false, fmt.Errorf("get user item: %w", err)
+	}
+	userItem = UserDictionaryItem{
+		User:    user,
+		Word:    word,
+		Created: time.Now().UTC(),
+	}
+	if err := s.SaveUserItem(userItem); err != nil {
+		return userItem, false, fmt.Errorf("save user item: %w", err)
+	}
+	return userItem, true, nil
+}
+
+// TagCounts returns number of items by tag in user dictionary
+func TagCounts(dictionary map[UserDictionaryItem]DictionaryItem) map[string]int {
+	counts := make(map[string]int)
+	for userItem := range dictionary {
+		for _, t := range userItem.Tags() {
+			counts[t]++
+		}
+	}
+	return counts
+}
+
+// FilterByTags returns part of user dictionary with items having any of the tags,
+// dictionary is returned as is if there are no tags
+func FilterByTags(
+	dictionary map[UserDictionaryItem]DictionaryItem, tags []string,
+) map[UserDictionaryItem]DictionaryItem {
+	if len(tags) == 0 {
+		return dictionary
+	}
+	result := make(map[UserDictionaryItem]DictionaryItem)
+	for userItem, item := range dictionary {
+		if userItem.HasAnyTag(tags) {
+			result[userItem] = item
+		}
+	}
+	return result
+}
+
+// QuizStats holds answers statistics for a single quiz type
+type QuizStats struct {
+	Total   int
+	Correct int
+}
+
+// Accuracy returns smoothed share of correct answers,
+// types without answers are considered to be 50% accurate
+func (s QuizStats) Accuracy() float64 {
+	return float64(s.Correct+1) / float64(s.Total+2)
+}
+
+// UserItemStats holds quiz statistics for a user dictionary item
+type UserItemStats struct {
+	Quizzes map[string]QuizStats
+	// Streak is a number of consecutive correct answers across all quiz types
+	Streak int `json:",omitempty"`
+}
+
+// Streak returns number of consecutive correct answers for the item
+func (i UserDictionaryItem) Streak() int {
+	if i.Stats == nil {
+		return 0
+	}
+	return i.Stats.Streak
+}
+
+// MasteryLevel returns item mastery level from 0 to threshold
+func (i UserDictionaryItem) MasteryLevel(threshold int) int {
+	if streak := i.Streak(); streak < threshold {
+		return streak
+	}
+	return threshold
+}
+
+// IsMastered returns true if item was answered correctly threshold times in a row
+func (i UserDictionaryItem) IsMastered(threshold int) bool {
+	return i.Streak() >= threshold
+}
+
+// TypeStats returns statistics for given quiz type
+func (i UserDictionaryItem) TypeStats(qType string) QuizStats {
+	if i.Stats == nil {
+		return QuizStats{}
+	}
+	return i.Stats.Quizzes[qType]
+}
+
+// TotalStats returns statistics summed over all quiz types
+func (i UserDictionaryItem) TotalStats() QuizStats {
+	var total QuizStats
+	if i.Stats == nil {
+		return total
+	}
+	for _, s := range i.Stats.Quizzes {
+		total.Total += s.Total
+		total.Correct += s.Correct
+	}
+	return total
+}
+
+// addQuizResult updates item statistics with a quiz result
+func (i *UserDictionaryItem) addQuizResult(qType string, correct bool) {
+	stats := UserItemStats{Quizzes: make(map[string]QuizStats), Streak: i.Streak()}
+	if i.Stats != nil {
+		for t, s := range i.Stats.Quizzes {
+			stats.Quizzes[t] = s
+		}
+	}
+	s := stats.Quizzes[qType]
+	s.Total++
+	stats.Streak = 0
+	if correct {
+		s.Correct++
+		stats.Streak = i.Streak() + 1
+	}
+	stats.Quizzes[qType] = s
+	i.Stats = &stats
 }
 
 // QuizResult holds data for a quiz result
@@ -177,6 +602,9 @@ const (
 	QuizTypeDefault = QuizTypeTranslations
 )
 
+// QuizTypes lists all supported quiz types
+var QuizTypes = []string{QuizTypeTranslations, QuizTypeReverseTranslations, QuizTypeMeanings}
+
 // Quiz holds data for a single quiz`
 type Quiz struct {
 	ID          string
@@ -190,26 +618,35 @@ type Quiz struct {
 	Result      *QuizResult
 }
 
-// SetResult sets checks if choice is correct and saves result
+// ErrInvalidChoice is returned for answers which are not quiz choices
+var ErrInvalidChoice = errors.New("invalid choice")
+
+// SetResult sets checks if choice is correct and saves result,
+// ErrAlreadyAnswered is returned if result is already set.
+// Word stats aren't updated if the word is deleted from user dictionary since the quiz was created
 func (q *Quiz) SetResult(choice int, s Storage) error {
 	if choice < 0 || choice >= len(q.Choices) {
-		return errors.New("invalid choice")
+		return ErrInvalidChoice
 	}
 	if q.Result != nil {
-		return errors.New("result already set")
+		return ErrAlreadyAnswered
 	}
 
 	q.Result = &QuizResult{
 		Choice:  choice,
 		Correct: q.Choices[choice].Correct,
 	}
-	if q.Result.Correct {
-		item, err := s.GetUserItem(q.User, q.Word)
-		if err != nil {
-			return err
+	item, err := s.GetUserItem(q.User, q.Word)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return fmt.Errorf("get user item: %w", err)
+	default:
+		item.addQuizResult(q.Type, q.Result.Correct)
+		if q.Result.Correct {
+			now := time.Now().UTC()
+			item.LastQuiz = &now
 		}
-		now := time.Now().UTC()
-		item.LastQuiz = &now
 		if err := s.SaveUserItem(item); err != nil {
 			return fmt.Errorf("save user item: %w", err)
 		}
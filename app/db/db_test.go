@@ -1,6 +1,7 @@
 package db
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -91,7 +92,7 @@ func TestNewDictionaryItem(t *testing.T) {
 	getExpected := func() DictionaryItem {
 		expected := DictionaryItem{
 			Word: "test",
-			Meanings: []meaning{
+			Meanings: []Meaning{
 				{
 					PartOfSpeech: "pos1",
 					Definition:   "def11",
@@ -114,7 +115,7 @@ func TestNewDictionaryItem(t *testing.T) {
 					Antonyms:     []string{},
 				},
 			},
-			Translations: []translation{
+			Translations: []Translation{
 				{Text: "тест", Language: "ru", PartOfSpeech: "pos1"},
 			},
 		}
@@ -152,6 +153,26 @@ func TestNewDictionaryItem(t *testing.T) {
 	})
 }
 
+func TestDictionaryItemNeedsRefresh(t *testing.T) {
+	now := time.Date(2023, 1, 10, 0, 0, 0, 0, time.UTC)
+	fresh, old := now.Add(-time.Hour), now.Add(-48*time.Hour)
+	cases := map[string]struct {
+		item     DictionaryItem
+		expected bool
+	}{
+		"fresh":         {DictionaryItem{FetchedAt: &fresh}, false},
+		"old":           {DictionaryItem{FetchedAt: &old}, true},
+		"never fetched": {DictionaryItem{}, true},
+		"incomplete":    {DictionaryItem{FetchedAt: &fresh, Incomplete: []string{"yandex"}}, true},
+		"edited":        {DictionaryItem{FetchedAt: &old, EditedBy: 1}, false},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.item.NeedsRefresh(now, 24*time.Hour))
+		})
+	}
+}
+
 func TestNewQuiz(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		expected := Quiz{
@@ -230,6 +251,11 @@ func TestQuizSetResult(t *testing.T) {
 		dbQuiz, err := storage.GetQuiz(quiz.ID)
 		require.NoError(t, err)
 		assert.Equal(t, quiz, dbQuiz)
+
+		item, err := storage.GetUserItem(quiz.User, quiz.Word)
+		require.NoError(t, err)
+		assert.NotNil(t, item.LastQuiz)
+		assert.Equal(t, QuizStats{Total: 1, Correct: 1}, item.TypeStats(quiz.Type))
 	})
 	t.Run("success wrong", func(t *testing.T) {
 		storage := NewInMemoryStorage()
@@ -248,6 +274,48 @@ func TestQuizSetResult(t *testing.T) {
 		dbQuiz, err := storage.GetQuiz(quiz.ID)
 		require.NoError(t, err)
 		assert.Equal(t, quiz, dbQuiz)
+
+		item, err := storage.GetUserItem(quiz.User, quiz.Word)
+		require.NoError(t, err)
+		assert.Nil(t, item.LastQuiz)
+		assert.Equal(t, QuizStats{Total: 1, Correct: 0}, item.TypeStats(quiz.Type))
+	})
+	t.Run("stats accumulated", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		require.NoError(t, storage.SaveUserItem(UserDictionaryItem{
+			Word:    "test",
+			User:    UserID(1),
+			Created: time.Now().UTC()}))
+		for _, choice := range []int{2, 1, 2} {
+			quiz := getQuiz()
+			quiz.Type = QuizTypeMeanings
+			require.NoError(t, quiz.SetResult(choice, storage))
+		}
+		quiz := getQuiz()
+		quiz.Type = QuizTypeTranslations
+		require.NoError(t, quiz.SetResult(0, storage))
+
+		item, err := storage.GetUserItem(UserID(1), "test")
+		require.NoError(t, err)
+		assert.Equal(t, QuizStats{Total: 3, Correct: 2}, item.TypeStats(QuizTypeMeanings))
+		assert.Equal(t, QuizStats{Total: 1, Correct: 0}, item.TypeStats(QuizTypeTranslations))
+		assert.Equal(t, QuizStats{}, item.TypeStats(QuizTypeReverseTranslations))
+		assert.Equal(t, 0, item.Streak())
+	})
+	t.Run("streak across types", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		require.NoError(t, storage.SaveUserItem(UserDictionaryItem{
+			Word:    "test",
+			User:    UserID(1),
+			Created: time.Now().UTC()}))
+		for _, qType := range []string{QuizTypeMeanings, QuizTypeTranslations, QuizTypeMeanings} {
+			quiz := getQuiz()
+			quiz.Type = qType
+			require.NoError(t, quiz.SetResult(2, storage))
+		}
+		item, err := storage.GetUserItem(UserID(1), "test")
+		require.NoError(t, err)
+		assert.Equal(t, 3, item.Streak())
 	})
 	t.Run("invalid choice", func(t *testing.T) {
 		for _, choice := range []int{-1, 3} {
@@ -258,7 +326,7 @@ func TestQuizSetResult(t *testing.T) {
 				Word:    quiz.Word,
 				User:    quiz.User,
 				Created: time.Now().UTC()}))
-			assert.Error(t, quiz.SetResult(choice, storage))
+			assert.ErrorIs(t, quiz.SetResult(choice, storage), ErrInvalidChoice)
 		}
 	})
 	t.Run("already set", func(t *testing.T) {
@@ -270,6 +338,211 @@ func TestQuizSetResult(t *testing.T) {
 			User:    quiz.User,
 			Created: time.Now().UTC()}))
 		assert.NoError(t, quiz.SetResult(2, storage))
-		assert.Error(t, quiz.SetResult(2, storage))
+		assert.ErrorIs(t, quiz.SetResult(2, storage), ErrAlreadyAnswered)
+	})
+	t.Run("deleted word", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		quiz := getQuiz()
+		require.NoError(t, storage.SaveQuiz(quiz))
+		require.NoError(t, quiz.SetResult(1, storage))
+		saved, err := storage.GetQuiz(quiz.ID)
+		require.NoError(t, err)
+		assert.Equal(t, quiz.Result, saved.Result)
+		_, err = storage.GetUserItem(quiz.User, quiz.Word)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestQuizStatsAccuracy(t *testing.T) {
+	assert.Equal(t, 0.5, QuizStats{}.Accuracy())
+	assert.Equal(t, 0.75, QuizStats{Total: 2, Correct: 2}.Accuracy())
+	assert.Equal(t, 0.25, QuizStats{Total: 2, Correct: 0}.Accuracy())
+}
+
+func TestUserDictionaryItemTotalStats(t *testing.T) {
+	assert.Equal(t, QuizStats{}, UserDictionaryItem{}.TotalStats())
+	item := UserDictionaryItem{Stats: &UserItemStats{Quizzes: map[string]QuizStats{
+		QuizTypeTranslations: {Total: 3, Correct: 2},
+		QuizTypeMeanings:     {Total: 2, Correct: 0},
+	}}}
+	assert.Equal(t, QuizStats{Total: 5, Correct: 2}, item.TotalStats())
+}
+
+func TestUserDictionaryItemCustomTranslations(t *testing.T) {
+	assert.Nil(t, UserDictionaryItem{}.CustomTranslations())
+	item := UserDictionaryItem{Custom: &UserItemCustom{Translations: []string{"тест"}}}
+	assert.Equal(t, []string{"тест"}, item.CustomTranslations())
+}
+
+func TestUserDictionaryItemCustomData(t *testing.T) {
+	item := UserDictionaryItem{}
+	assert.Equal(t, UserItemCustom{}, item.CustomData())
+	item.SetCustom(UserItemCustom{Notes: "note"})
+	assert.Equal(t, &UserItemCustom{Notes: "note"}, item.Custom)
+	custom := item.CustomData()
+	custom.Notes = "changed"
+	assert.Equal(t, "note", item.Custom.Notes)
+	item.SetCustom(UserItemCustom{})
+	assert.Nil(t, item.Custom)
+}
+
+func TestUserDictionaryItemPersonalize(t *testing.T) {
+	item := DictionaryItem{
+		Word:     "test",
+		Meanings: []Meaning{{Definition: "def", Examples: []string{"ex"}}, {Definition: "def2"}},
+		Translations: []Translation{
+			{Text: "тест", Language: "ru"},
+			{Text: "test", Language: "de"},
+		},
+	}
+	t.Run("no custom data", func(t *testing.T) {
+		assert.Equal(t, item, UserDictionaryItem{}.Personalize(item))
+	})
+	t.Run("custom data", func(t *testing.T) {
+		userItem := UserDictionaryItem{Custom: &UserItemCustom{
+			Translations: []string{"проверка"},
+			Examples:     []string{"my ex"},
+		}}
+		res := userItem.Personalize(item)
+		assert.Equal(t, []Translation{{Text: "проверка", Language: "ru"}, {Text: "test", Language: "de"}}, res.Translations)
+		assert.Equal(t, []string{"my ex", "ex"}, res.Meanings[0].Examples)
+		assert.Equal(t, "def2", res.Meanings[1].Definition)
+		// original item is not changed
+		assert.Equal(t, []string{"ex"}, item.Meanings[0].Examples)
+		assert.Equal(t, "тест", item.Translations[0].Text)
+	})
+	t.Run("examples without meanings", func(t *testing.T) {
+		userItem := UserDictionaryItem{Custom: &UserItemCustom{Examples: []string{"my ex"}}}
+		assert.Empty(t, userItem.Personalize(DictionaryItem{Word: "test"}).Meanings)
+	})
+	t.Run("missing item", func(t *testing.T) {
+		userItem := UserDictionaryItem{Word: "qwerty", Custom: &UserItemCustom{Translations: []string{"йцукен"}}}
+		res := userItem.Personalize(DictionaryItem{})
+		assert.Equal(t, "qwerty", res.Word)
+		assert.Equal(t, []Translation{{Text: "йцукен", Language: CustomTranslationLanguage}}, res.Translations)
+	})
+}
+
+func TestUserConfigEnabledQuizTypes(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		assert.Equal(t, []string{QuizTypeDefault}, UserConfig{}.EnabledQuizTypes())
+	})
+	t.Run("legacy single type", func(t *testing.T) {
+		config := UserConfig{QuizType: ptrStr(QuizTypeMeanings)}
+		assert.Equal(t, []string{QuizTypeMeanings}, config.EnabledQuizTypes())
+	})
+	t.Run("multiple types", func(t *testing.T) {
+		config := UserConfig{
+			QuizType:  ptrStr(QuizTypeMeanings),
+			QuizTypes: []string{QuizTypeTranslations, QuizTypeReverseTranslations},
+		}
+		assert.Equal(t, []string{QuizTypeTranslations, QuizTypeReverseTranslations}, config.EnabledQuizTypes())
+	})
+}
+
+func TestCourseConfigIsDue(t *testing.T) {
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	ptrTime := func(t time.Time) *time.Time { return &t }
+	t.Run("never seeded", func(t *testing.T) {
+		assert.True(t, CourseConfig{}.IsDue(now))
+	})
+	t.Run("seeded today", func(t *testing.T) {
+		assert.False(t, CourseConfig{LastSeeded: ptrTime(now.Add(-11 * time.Hour))}.IsDue(now))
 	})
+	t.Run("seeded yesterday", func(t *testing.T) {
+		assert.True(t, CourseConfig{LastSeeded: ptrTime(now.Add(-13 * time.Hour))}.IsDue(now))
+	})
+}
+
+func TestNormalizeWord(t *testing.T) {
+	for word, expected := range map[string]string{
+		"test": "test", " Give  Up ": "give up", "look\tforward  to": "look forward to", "": "",
+	} {
+		assert.Equal(t, expected, NormalizeWord(word), word)
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	for tag, expected := range map[string]string{
+		"work": "work", "#Work": "work", " travel  plans ": "travel_plans", "b2-level": "b2-level", "слова": "слова",
+	} {
+		res, err := NormalizeTag(tag)
+		assert.NoError(t, err, tag)
+		assert.Equal(t, expected, res, tag)
+	}
+	for _, tag := range []string{"", "#", "a|b", "work!", strings.Repeat("a", maxTagLength+1)} {
+		_, err := NormalizeTag(tag)
+		assert.ErrorIs(t, err, ErrInvalidTag, tag)
+	}
+}
+
+func TestUserDictionaryItemTags(t *testing.T) {
+	item := UserDictionaryItem{Word: "test"}
+	assert.Nil(t, item.Tags())
+	assert.False(t, item.HasAnyTag([]string{"work"}))
+
+	item.AddTags("work", "travel", "work")
+	assert.Equal(t, []string{"travel", "work"}, item.Tags())
+	assert.True(t, item.HasAnyTag([]string{"home", "work"}))
+	assert.False(t, item.HasAnyTag([]string{"home"}))
+
+	item.RemoveTags("travel", "home")
+	assert.Equal(t, []string{"work"}, item.Tags())
+	item.RemoveTags("work")
+	assert.Nil(t, item.Tags())
+	assert.Nil(t, item.Custom)
+
+	item.SetCustom(UserItemCustom{Notes: "note"})
+	item.SetTags([]string{"work"})
+	assert.Equal(t, &UserItemCustom{Notes: "note", Tags: []string{"work"}}, item.Custom)
+}
+
+func TestFilterByTags(t *testing.T) {
+	dict := map[UserDictionaryItem]DictionaryItem{
+		{Word: "a", Custom: &UserItemCustom{Tags: []string{"work"}}}:         {Word: "a"},
+		{Word: "b", Custom: &UserItemCustom{Tags: []string{"home", "work"}}}: {Word: "b"},
+		{Word: "c"}: {Word: "c"},
+	}
+	assert.Equal(t, dict, FilterByTags(dict, nil))
+	assert.Len(t, FilterByTags(dict, []string{"work"}), 2)
+	assert.Len(t, FilterByTags(dict, []string{"home"}), 1)
+	assert.Empty(t, FilterByTags(dict, []string{"travel"}))
+	assert.Equal(t, map[string]int{"work": 2, "home": 1}, TagCounts(dict))
+}
+
+func TestUserDictionaryItemMastery(t *testing.T) {
+	item := UserDictionaryItem{}
+	assert.Equal(t, 0, item.MasteryLevel(5))
+	assert.False(t, item.IsMastered(5))
+	item.Stats = &UserItemStats{Streak: 3}
+	assert.Equal(t, 3, item.MasteryLevel(5))
+	assert.False(t, item.IsMastered(5))
+	assert.Equal(t, 2, item.MasteryLevel(2))
+	assert.True(t, item.IsMastered(2))
+	assert.True(t, item.IsMastered(3))
+}
+
+func TestUserConfigEffectiveMasteryThreshold(t *testing.T) {
+	for threshold, expected := range map[int]int{
+		0: DefaultMasteryThreshold, 1: DefaultMasteryThreshold, 3: 3, 20: 20, 21: DefaultMasteryThreshold,
+	} {
+		assert.Equal(t, expected, UserConfig{MasteryThreshold: threshold}.EffectiveMasteryThreshold(), threshold)
+	}
+}
+
+func TestAddUserItem(t *testing.T) {
+	storage := NewInMemoryStorage()
+	item, added, err := AddUserItem(storage, UserID(1), "test")
+	require.NoError(t, err)
+	assert.True(t, added)
+	assert.Equal(t, "test", item.Word)
+	assert.Equal(t, UserID(1), item.User)
+	assert.False(t, item.Created.IsZero())
+
+	item.SetTags([]string{"home"})
+	require.NoError(t, storage.SaveUserItem(item))
+	existing, added, err := AddUserItem(storage, UserID(1), "test")
+	require.NoError(t, err)
+	assert.False(t, added)
+	assert.Equal(t, item, existing)
 }
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ChatID is a type for telegram group chats ID
+type ChatID int64
+
+// GroupMaxWords limits number of words in group word list
+const GroupMaxWords = 500
+
+// group quiz errors
+var (
+	ErrAlreadyAnswered = errors.New("already answered")
+	ErrQuizClosed      = errors.New("quiz is closed")
+)
+
+// Group holds data of a group chat
+type Group struct {
+	ID    ChatID
+	Title string
+	// Words is a shared word list used for group quizzes
+	Words []string
+}
+
+// HasWord returns true if word is in group word list
+func (g Group) HasWord(word string) bool {
+	word = NormalizeWord(word)
+	for _, w := range g.Words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
+
+// AddWord adds word to group word list,
+// false is returned if it's already there or the list is full
+func (g *Group) AddWord(word string) bool {
+	word = NormalizeWord(word)
+	if word == "" || g.HasWord(word) || len(g.Words) >= GroupMaxWords {
+		return false
+	}
+	g.Words = append(g.Words, word)
+	return true
+}
+
+// RemoveWord removes word from group word list, false is returned if it's not there
+func (g *Group) RemoveWord(word string) bool {
+	word = NormalizeWord(word)
+	for idx, w := range g.Words {
+		if w == word {
+			g.Words = append(g.Words[:idx:idx], g.Words[idx+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// GroupQuiz holds data for a quiz posted to a group chat, every member can answer it once
+// and the first one answered correctly wins
+type GroupQuiz struct {
+	ID          string
+	Chat        ChatID
+	Word        string
+	DisplayWord string
+	Language    string
+	Type        string
+	Choices     []QuizItem
+	Created     time.Time
+	// Answers holds choices of users answered the quiz
+	Answers map[UserID]int `json:",omitempty"`
+	Winner  *UserID        `json:",omitempty"`
+}
+
+// IsClosed returns true if someone answered the quiz correctly
+func (q GroupQuiz) IsClosed() bool {
+	return q.Winner != nil
+}
+
+// SetAnswer saves user choice, a point is added to the user weekly score if the user is the first
+// one answered correctly
+func (q *GroupQuiz) SetAnswer(user UserID, username string, choice int, s Storage) (correct bool, err error) {
+	if choice < 0 || choice >= len(q.Choices) {
+		return false, ErrInvalidChoice
+	}
+	if q.IsClosed() {
+		return false, ErrQuizClosed
+	}
+	if _, ok := q.Answers[user]; ok {
+		return false, ErrAlreadyAnswered
+	}
+	answers := make(map[UserID]int, len(q.Answers)+1)
+	for u, c := range q.Answers {
+		answers[u] = c
+	}
+	answers[user] = choice
+	q.Answers = answers
+	correct = q.Choices[choice].Correct
+	if correct {
+		q.Winner = &user
+		if err := AddGroupPoints(s, q.Chat, user, username, 1, time.Now()); err != nil {
+			return correct, fmt.Errorf("add points: %w", err)
+		}
+	}
+	if err := s.SaveGroupQuiz(*q); err != nil {
+		return correct, fmt.Errorf("save group quiz: %w", err)
+	}
+	return correct, nil
+}
+
+// NewGroupQuiz creates new group quiz
+func NewGroupQuiz(chat ChatID, word string, displayWord string, lang string, items []QuizItem, qType string) GroupQuiz {
+	return GroupQuiz{
+		ID:          GenerateID(),
+		Chat:        chat,
+		Type:        qType,
+		Word:        word,
+		DisplayWord: displayWord,
+		Language:    lang,
+		Choices:     items,
+		Created:     time.Now().UTC(),
+	}
+}
+
+// GroupScore holds points of a group member for a week
+type GroupScore struct {
+	Chat     ChatID
+	User     UserID
+	Username string
+	// Week is an ISO week in 2006-W01 format
+	Week   string
+	Points int
+}
+
+// Week returns ISO week of the time in format used by GroupScore
+func Week(t time.Time) string {
+	year, week := t.UTC().ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// AddGroupPoints adds points to user score for the week of now
+func AddGroupPoints(s Storage, chat ChatID, user UserID, username string, points int, now time.Time) error {
+	week := Week(now)
+	scores, err := s.GetGroupScores(chat, week)
+	if err != nil {
+		return fmt.Errorf("get scores: %w", err)
+	}
+	score := GroupScore{Chat: chat, User: user, Week: week}
+	for _, sc := range scores {
+		if sc.User == user {
+			score = sc
+			break
+		}
+	}
+	score.Points += points
+	if username != "" {
+		score.Username = username
+	}
+	if err := s.SaveGroupScore(score); err != nil {
+		return fmt.Errorf("save score: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupWords(t *testing.T) {
+	group := Group{ID: 1}
+	assert.True(t, group.AddWord(" Give  Up"))
+	assert.True(t, group.AddWord("cat"))
+	assert.False(t, group.AddWord("give up"))
+	assert.False(t, group.AddWord(" "))
+	assert.Equal(t, []string{"give up", "cat"}, group.Words)
+	assert.True(t, group.HasWord("CAT"))
+
+	assert.True(t, group.RemoveWord("give up"))
+	assert.False(t, group.RemoveWord("dog"))
+	assert.Equal(t, []string{"cat"}, group.Words)
+
+	t.Run("limit", func(t *testing.T) {
+		group := Group{ID: 1}
+		for i := 0; i < GroupMaxWords; i++ {
+			require.True(t, group.AddWord(fmt.Sprintf("word%d", i)))
+		}
+		assert.False(t, group.AddWord("extra"))
+	})
+}
+
+func TestGroupQuizSetAnswer(t *testing.T) {
+	getQuiz := func() GroupQuiz {
+		return NewGroupQuiz(ChatID(-1), "test", "test", "ru", []QuizItem{
+			{Word: "t1", Text: "t1"},
+			{Word: "test", Text: "тест", Correct: true},
+		}, QuizTypeTranslations)
+	}
+	t.Run("first correct wins", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		quiz := getQuiz()
+
+		correct, err := quiz.SetAnswer(UserID(1), "first", 0, storage)
+		require.NoError(t, err)
+		assert.False(t, correct)
+		assert.False(t, quiz.IsClosed())
+
+		_, err = quiz.SetAnswer(UserID(1), "first", 1, storage)
+		assert.ErrorIs(t, err, ErrAlreadyAnswered)
+
+		correct, err = quiz.SetAnswer(UserID(2), "second", 1, storage)
+		require.NoError(t, err)
+		assert.True(t, correct)
+		assert.True(t, quiz.IsClosed())
+		assert.Equal(t, UserID(2), *quiz.Winner)
+		assert.Equal(t, map[UserID]int{1: 0, 2: 1}, quiz.Answers)
+
+		_, err = quiz.SetAnswer(UserID(3), "third", 1, storage)
+		assert.ErrorIs(t, err, ErrQuizClosed)
+
+		dbQuiz, err := storage.GetGroupQuiz(quiz.ID)
+		require.NoError(t, err)
+		assert.Equal(t, quiz, dbQuiz)
+
+		scores, err := storage.GetGroupScores(ChatID(-1), Week(time.Now()))
+		require.NoError(t, err)
+		assert.Equal(t, []GroupScore{
+			{Chat: ChatID(-1), User: UserID(2), Username: "second", Week: Week(time.Now()), Points: 1},
+		}, scores)
+	})
+	t.Run("invalid choice", func(t *testing.T) {
+		quiz := getQuiz()
+		for _, choice := range []int{-1, 2} {
+			_, err := quiz.SetAnswer(UserID(1), "", choice, NewInMemoryStorage())
+			assert.Error(t, err)
+		}
+	})
+}
+
+func TestWeek(t *testing.T) {
+	assert.Equal(t, "2023-W19", Week(time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "2020-W53", Week(time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestAddGroupPoints(t *testing.T) {
+	storage := NewInMemoryStorage()
+	now := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, AddGroupPoints(storage, ChatID(-1), UserID(1), "user", 1, now))
+	require.NoError(t, AddGroupPoints(storage, ChatID(-1), UserID(1), "", 2, now))
+	require.NoError(t, AddGroupPoints(storage, ChatID(-1), UserID(1), "user", 1, now.AddDate(0, 0, 7)))
+
+	scores, err := storage.GetGroupScores(ChatID(-1), "2023-W19")
+	require.NoError(t, err)
+	assert.Equal(t, []GroupScore{{Chat: ChatID(-1), User: UserID(1), Username: "user", Week: "2023-W19", Points: 3}}, scores)
+	scores, err = storage.GetGroupScores(ChatID(-1), "2023-W20")
+	require.NoError(t, err)
+	assert.Len(t, scores, 1)
+	scores, err = storage.GetGroupScores(ChatID(-2), "2023-W19")
+	require.NoError(t, err)
+	assert.Empty(t, scores)
+}
@@ -1,6 +1,11 @@
 package db
 
-import "sync"
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
 
 // InMemoryStorage is a storage implementation using in-memory maps
 type InMemoryStorage struct {
@@ -8,6 +13,13 @@ type InMemoryStorage struct {
 	users             map[UserID]User
 	quizzes           map[string]Quiz
 	usersDictionaries map[UserID]map[string]UserDictionaryItem
+	groups            map[ChatID]Group
+	groupQuizzes      map[string]GroupQuiz
+	groupScores       map[ChatID]map[string]map[UserID]GroupScore
+	refreshSessions   map[string]RefreshSession
+	revokedTokens     map[string]time.Time
+	personalTokens    map[string]PersonalToken
+	wordRevisions     map[string][]WordRevision
 	mx                sync.RWMutex
 }
 
@@ -15,7 +27,7 @@ type InMemoryStorage struct {
 func (d *InMemoryStorage) Get(word string) (DictionaryItem, error) {
 	d.mx.RLock()
 	defer d.mx.RUnlock()
-	item, ok := d.dictionary[word]
+	item, ok := d.dictionary[NormalizeWord(word)]
 	if !ok {
 		return DictionaryItem{}, ErrNotFound
 	}
@@ -26,10 +38,58 @@ func (d *InMemoryStorage) Get(word string) (DictionaryItem, error) {
 func (d *InMemoryStorage) Save(item DictionaryItem) error {
 	d.mx.Lock()
 	defer d.mx.Unlock()
-	d.dictionary[item.Word] = item
+	d.dictionary[NormalizeWord(item.Word)] = item
 	return nil
 }
 
+// GetWords returns all dictionary words
+func (d *InMemoryStorage) GetWords() ([]string, error) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	words := make([]string, 0, len(d.dictionary))
+	for word := range d.dictionary {
+		words = append(words, word)
+	}
+	return words, nil
+}
+
+// GetWordsByPrefix returns up to limit words starting with prefix in lexicographical order
+func (d *InMemoryStorage) GetWordsByPrefix(prefix string, limit int) ([]string, error) {
+	d.mx.RLock()
+	words := make([]string, 0)
+	for word := range d.dictionary {
+		if strings.HasPrefix(word, prefix) {
+			words = append(words, word)
+		}
+	}
+	d.mx.RUnlock()
+	sort.Strings(words)
+	if limit > 0 && len(words) > limit {
+		words = words[:limit]
+	}
+	return words, nil
+}
+
+// GetWordRevisions returns edit history of dictionary item, the oldest revision first
+func (d *InMemoryStorage) GetWordRevisions(word string) ([]WordRevision, error) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	revisions := d.wordRevisions[NormalizeWord(word)]
+	res := make([]WordRevision, len(revisions))
+	copy(res, revisions)
+	return res, nil
+}
+
+// SaveWordRevision appends revision to edit history of dictionary item, returns number of the revision
+func (d *InMemoryStorage) SaveWordRevision(rev WordRevision) (int, error) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	word := NormalizeWord(rev.Word)
+	rev.Rev = len(d.wordRevisions[word]) + 1
+	d.wordRevisions[word] = append(d.wordRevisions[word], rev)
+	return rev.Rev, nil
+}
+
 // GetUser returns user by ID
 func (d *InMemoryStorage) GetUser(id UserID) (User, error) {
 	d.mx.RLock()
@@ -49,11 +109,23 @@ func (d *InMemoryStorage) SaveUser(item User) error {
 	return nil
 }
 
+// GetUsers returns all users sorted by ID
+func (d *InMemoryStorage) GetUsers() ([]User, error) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	res := make([]User, 0, len(d.users))
+	for _, user := range d.users {
+		res = append(res, user)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res, nil
+}
+
 // GetUserItem returns item from user dictionary
 func (d *InMemoryStorage) GetUserItem(user UserID, word string) (UserDictionaryItem, error) {
 	d.mx.RLock()
 	defer d.mx.RUnlock()
-	item, ok := d.usersDictionaries[user][word]
+	item, ok := d.usersDictionaries[user][NormalizeWord(word)]
 	if !ok {
 		return UserDictionaryItem{}, ErrNotFound
 	}
@@ -69,7 +141,38 @@ func (d *InMemoryStorage) SaveUserItem(item UserDictionaryItem) error {
 		userDict = make(map[string]UserDictionaryItem)
 		d.usersDictionaries[item.User] = userDict
 	}
-	userDict[item.Word] = item
+	userDict[NormalizeWord(item.Word)] = item
+	return nil
+}
+
+// IterateUserDictionary calls function for every item of user dictionary,
+// items are copied first so function is called without lock
+func (d *InMemoryStorage) IterateUserDictionary(user UserID, fn func(UserDictionaryItem, DictionaryItem) error) error {
+	d.mx.RLock()
+	userItems := make([]UserDictionaryItem, 0, len(d.usersDictionaries[user]))
+	items := make([]DictionaryItem, 0, len(d.usersDictionaries[user]))
+	for _, item := range d.usersDictionaries[user] {
+		userItems = append(userItems, item)
+		items = append(items, d.userDictionaryItem(item))
+	}
+	d.mx.RUnlock()
+	for i := range userItems {
+		if err := fn(userItems[i], items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteUserItem removes item from user dictionary
+func (d *InMemoryStorage) DeleteUserItem(user UserID, word string) error {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	word = NormalizeWord(word)
+	if _, ok := d.usersDictionaries[user][word]; !ok {
+		return ErrNotFound
+	}
+	delete(d.usersDictionaries[user], word)
 	return nil
 }
 
@@ -79,11 +182,20 @@ func (d *InMemoryStorage) GetUserDictionary(user UserID) (map[UserDictionaryItem
 	d.mx.RLock()
 	defer d.mx.RUnlock()
 	for _, item := range d.usersDictionaries[user] {
-		result[item] = d.dictionary[item.Word]
+		result[item] = d.userDictionaryItem(item)
 	}
 	return result, nil
 }
 
+// userDictionaryItem returns shared dictionary item of the user item,
+// item without data is returned if it's missing in shared dictionary
+func (d *InMemoryStorage) userDictionaryItem(item UserDictionaryItem) DictionaryItem {
+	if dictItem, ok := d.dictionary[NormalizeWord(item.Word)]; ok {
+		return dictItem
+	}
+	return DictionaryItem{Word: item.Word}
+}
+
 // SaveQuiz saves quiz
 func (d *InMemoryStorage) SaveQuiz(q Quiz) error {
 	d.mx.Lock()
@@ -92,6 +204,23 @@ func (d *InMemoryStorage) SaveQuiz(q Quiz) error {
 	return nil
 }
 
+// GetUserQuizzes returns latest quizzes of the user
+func (d *InMemoryStorage) GetUserQuizzes(user UserID, limit int) ([]Quiz, error) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	res := make([]Quiz, 0)
+	for _, q := range d.quizzes {
+		if q.User == user {
+			res = append(res, q)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Created.After(res[j].Created) })
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
 // GetQuiz returns quiz by ID
 func (d *InMemoryStorage) GetQuiz(id string) (Quiz, error) {
 	d.mx.RLock()
@@ -103,6 +232,153 @@ func (d *InMemoryStorage) GetQuiz(id string) (Quiz, error) {
 	return q, nil
 }
 
+// GetGroup returns group by ID
+func (d *InMemoryStorage) GetGroup(id ChatID) (Group, error) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	g, ok := d.groups[id]
+	if !ok {
+		return Group{}, ErrNotFound
+	}
+	return g, nil
+}
+
+// SaveGroup saves group
+func (d *InMemoryStorage) SaveGroup(g Group) error {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	d.groups[g.ID] = g
+	return nil
+}
+
+// SaveGroupQuiz saves group quiz
+func (d *InMemoryStorage) SaveGroupQuiz(q GroupQuiz) error {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	d.groupQuizzes[q.ID] = q
+	return nil
+}
+
+// GetGroupQuiz returns group quiz by ID
+func (d *InMemoryStorage) GetGroupQuiz(id string) (GroupQuiz, error) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	q, ok := d.groupQuizzes[id]
+	if !ok {
+		return GroupQuiz{}, ErrNotFound
+	}
+	return q, nil
+}
+
+// GetGroupScores returns scores of group members for the week
+func (d *InMemoryStorage) GetGroupScores(chat ChatID, week string) ([]GroupScore, error) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	scores := make([]GroupScore, 0, len(d.groupScores[chat][week]))
+	for _, s := range d.groupScores[chat][week] {
+		scores = append(scores, s)
+	}
+	return scores, nil
+}
+
+// SaveGroupScore saves score of group member
+func (d *InMemoryStorage) SaveGroupScore(s GroupScore) error {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	weeks, ok := d.groupScores[s.Chat]
+	if !ok {
+		weeks = make(map[string]map[UserID]GroupScore)
+		d.groupScores[s.Chat] = weeks
+	}
+	users, ok := weeks[s.Week]
+	if !ok {
+		users = make(map[UserID]GroupScore)
+		weeks[s.Week] = users
+	}
+	users[s.User] = s
+	return nil
+}
+
+// GetRefreshSession returns refresh session by ID
+func (d *InMemoryStorage) GetRefreshSession(id string) (RefreshSession, error) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	s, ok := d.refreshSessions[id]
+	if !ok {
+		return RefreshSession{}, ErrNotFound
+	}
+	return s, nil
+}
+
+// SaveRefreshSession saves refresh session
+func (d *InMemoryStorage) SaveRefreshSession(s RefreshSession) error {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	d.refreshSessions[s.ID] = s
+	return nil
+}
+
+// RevokeToken adds token ID to denylist until its expiration
+func (d *InMemoryStorage) RevokeToken(id string, expires time.Time) error {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	d.revokedTokens[id] = expires
+	return nil
+}
+
+// IsTokenRevoked returns true if token ID is in denylist and isn't expired yet
+func (d *InMemoryStorage) IsTokenRevoked(id string) (bool, error) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	expires, ok := d.revokedTokens[id]
+	return ok && expires.After(time.Now()), nil
+}
+
+// GetPersonalToken returns personal access token by ID
+func (d *InMemoryStorage) GetPersonalToken(id string) (PersonalToken, error) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	t, ok := d.personalTokens[id]
+	if !ok {
+		return PersonalToken{}, ErrNotFound
+	}
+	return t, nil
+}
+
+// GetPersonalTokens returns personal access tokens of the user ordered by creation time
+func (d *InMemoryStorage) GetPersonalTokens(user UserID) ([]PersonalToken, error) {
+	d.mx.RLock()
+	defer d.mx.RUnlock()
+	var tokens []PersonalToken
+	for _, t := range d.personalTokens {
+		if t.User == user {
+			tokens = append(tokens, t)
+		}
+	}
+	sortPersonalTokens(tokens)
+	return tokens, nil
+}
+
+// SavePersonalToken saves personal access token
+func (d *InMemoryStorage) SavePersonalToken(t PersonalToken) error {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	d.personalTokens[t.ID] = t
+	return nil
+}
+
+// DeletePersonalToken deletes personal access token of the user
+func (d *InMemoryStorage) DeletePersonalToken(user UserID, id string) error {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	t, ok := d.personalTokens[id]
+	if !ok || t.User != user {
+		return ErrNotFound
+	}
+	delete(d.personalTokens, id)
+	return nil
+}
+
 // NewInMemoryStorage creates new empty in-memory storage
 func NewInMemoryStorage() *InMemoryStorage {
 	return &InMemoryStorage{
@@ -110,5 +386,12 @@ func NewInMemoryStorage() *InMemoryStorage {
 		dictionary:        make(map[string]DictionaryItem),
 		quizzes:           make(map[string]Quiz),
 		usersDictionaries: make(map[UserID]map[string]UserDictionaryItem),
+		groups:            make(map[ChatID]Group),
+		groupQuizzes:      make(map[string]GroupQuiz),
+		groupScores:       make(map[ChatID]map[string]map[UserID]GroupScore),
+		refreshSessions:   make(map[string]RefreshSession),
+		revokedTokens:     make(map[string]time.Time),
+		personalTokens:    make(map[string]PersonalToken),
+		wordRevisions:     make(map[string][]WordRevision),
 	}
 }
@@ -1,6 +1,7 @@
 package db
 
 import (
+	"errors"
 	"testing"
 	"time"
 
@@ -36,6 +37,65 @@ func TestInMemorySave(t *testing.T) {
 	})
 }
 
+func TestInMemoryPhrase(t *testing.T) {
+	storage := NewInMemoryStorage()
+	item := DictionaryItem{Word: "give up"}
+	require.NoError(t, storage.Save(item))
+	res, err := storage.Get(" Give  up")
+	assert.NoError(t, err)
+	assert.Equal(t, item, res)
+
+	userItem := UserDictionaryItem{User: UserID(1), Word: "give up", Created: time.Now().UTC()}
+	require.NoError(t, storage.SaveUserItem(userItem))
+	resUserItem, err := storage.GetUserItem(userItem.User, "give  up")
+	assert.NoError(t, err)
+	assert.Equal(t, userItem, resUserItem)
+	dict, err := storage.GetUserDictionary(userItem.User)
+	assert.NoError(t, err)
+	assert.Equal(t, map[UserDictionaryItem]DictionaryItem{userItem: item}, dict)
+}
+
+func TestInMemoryGetWords(t *testing.T) {
+	storage := NewInMemoryStorage()
+	require.NoError(t, storage.Save(DictionaryItem{Word: "test1"}))
+	require.NoError(t, storage.Save(DictionaryItem{Word: "test2"}))
+	words, err := storage.GetWords()
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test1", "test2"}, words)
+}
+
+func TestInMemoryGetWordsByPrefix(t *testing.T) {
+	storage := NewInMemoryStorage()
+	for _, w := range []string{"test", "cat", "tea", "team"} {
+		require.NoError(t, storage.Save(DictionaryItem{Word: w}))
+	}
+	words, err := storage.GetWordsByPrefix("te", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tea", "team"}, words)
+	words, err = storage.GetWordsByPrefix("te", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tea", "team", "test"}, words)
+	words, err = storage.GetWordsByPrefix("x", 0)
+	assert.NoError(t, err)
+	assert.Empty(t, words)
+}
+
+func TestInMemoryWordRevisions(t *testing.T) {
+	storage := NewInMemoryStorage()
+	expected := []WordRevision{{Word: "test", Rev: 1}, {Word: "test", Rev: 2, Author: UserID(1)}}
+	for _, rev := range expected {
+		n, err := storage.SaveWordRevision(WordRevision{Word: rev.Word, Author: rev.Author})
+		require.NoError(t, err)
+		assert.Equal(t, rev.Rev, n)
+	}
+	n, err := storage.SaveWordRevision(WordRevision{Word: "other"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	revisions, err := storage.GetWordRevisions("Test")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, revisions)
+}
+
 func TestInMemoryGetUser(t *testing.T) {
 	t.Run("existing", func(t *testing.T) {
 		storage := NewInMemoryStorage()
@@ -64,6 +124,16 @@ func TestInMemorySaveUser(t *testing.T) {
 	})
 }
 
+func TestInMemoryGetUsers(t *testing.T) {
+	storage := NewInMemoryStorage()
+	expected := []User{{ID: UserID(1), Username: "first"}, {ID: UserID(2), IsAdmin: true}}
+	require.NoError(t, storage.SaveUser(expected[1]))
+	require.NoError(t, storage.SaveUser(expected[0]))
+	users, err := storage.GetUsers()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, users)
+}
+
 func TestInMemorySaveUserItem(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		storage := NewInMemoryStorage()
@@ -123,6 +193,15 @@ func TestInMemoryGetUserDictionary(t *testing.T) {
 		assert.Equal(t, map[UserDictionaryItem]DictionaryItem{userItem1: item1, userItem2: item2}, res)
 
 	})
+	t.Run("missing item", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		userItem := UserDictionaryItem{Word: "qwerty", User: UserID(1)}
+		require.NoError(t, storage.SaveUserItem(userItem))
+
+		res, err := storage.GetUserDictionary(UserID(1))
+		assert.NoError(t, err)
+		assert.Equal(t, map[UserDictionaryItem]DictionaryItem{userItem: {Word: "qwerty"}}, res)
+	})
 	t.Run("empty", func(t *testing.T) {
 		storage := NewInMemoryStorage()
 		res, err := storage.GetUserDictionary(UserID(1))
@@ -131,6 +210,60 @@ func TestInMemoryGetUserDictionary(t *testing.T) {
 	})
 }
 
+func TestInMemoryGetUserQuizzes(t *testing.T) {
+	storage := NewInMemoryStorage()
+	now := time.Now().UTC()
+	for id, created := range map[string]time.Time{
+		"old": now.Add(-time.Hour), "new": now, "middle": now.Add(-time.Minute),
+	} {
+		require.NoError(t, storage.SaveQuiz(Quiz{ID: id, User: UserID(1), Created: created}))
+	}
+	require.NoError(t, storage.SaveQuiz(Quiz{ID: "other", User: UserID(2), Created: now}))
+
+	res, err := storage.GetUserQuizzes(UserID(1), 2)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	assert.Equal(t, "new", res[0].ID)
+	assert.Equal(t, "middle", res[1].ID)
+
+	// saving answered quiz doesn't duplicate it
+	quiz := res[0]
+	quiz.Result = &QuizResult{Choice: 1}
+	require.NoError(t, storage.SaveQuiz(quiz))
+	res, err = storage.GetUserQuizzes(UserID(1), 10)
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+	assert.Equal(t, &QuizResult{Choice: 1}, res[0].Result)
+	assert.Equal(t, "old", res[2].ID)
+
+	res, err = storage.GetUserQuizzes(UserID(3), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+func TestInMemoryIterateUserDictionary(t *testing.T) {
+	storage := NewInMemoryStorage()
+	item := DictionaryItem{Word: "test"}
+	require.NoError(t, storage.Save(item))
+	userItems := []UserDictionaryItem{{Word: "test", User: UserID(1)}, {Word: "missing", User: UserID(1)}}
+	for _, userItem := range userItems {
+		require.NoError(t, storage.SaveUserItem(userItem))
+	}
+	require.NoError(t, storage.SaveUserItem(UserDictionaryItem{Word: "test", User: UserID(2)}))
+
+	res := make(map[UserDictionaryItem]DictionaryItem)
+	err := storage.IterateUserDictionary(UserID(1), func(userItem UserDictionaryItem, item DictionaryItem) error {
+		res[userItem] = item
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, map[UserDictionaryItem]DictionaryItem{userItems[0]: item, userItems[1]: {Word: "missing"}}, res)
+
+	testErr := errors.New("test")
+	err = storage.IterateUserDictionary(UserID(1), func(UserDictionaryItem, DictionaryItem) error { return testErr })
+	assert.ErrorIs(t, err, testErr)
+}
+
 func TestInMemoryGetQuiz(t *testing.T) {
 	t.Run("existing", func(t *testing.T) {
 		storage := NewInMemoryStorage()
@@ -157,3 +290,93 @@ func TestInMemorySaveQuoz(t *testing.T) {
 		assert.Equal(t, quiz, res)
 	})
 }
+
+func TestInMemoryGroup(t *testing.T) {
+	storage := NewInMemoryStorage()
+	_, err := storage.GetGroup(ChatID(-1))
+	assert.ErrorIs(t, err, ErrNotFound)
+	group := Group{ID: ChatID(-1), Title: "test", Words: []string{"cat"}}
+	require.NoError(t, storage.SaveGroup(group))
+	res, err := storage.GetGroup(ChatID(-1))
+	assert.NoError(t, err)
+	assert.Equal(t, group, res)
+}
+
+func TestInMemoryGroupQuiz(t *testing.T) {
+	storage := NewInMemoryStorage()
+	_, err := storage.GetGroupQuiz("test")
+	assert.ErrorIs(t, err, ErrNotFound)
+	quiz := GroupQuiz{ID: "test", Chat: ChatID(-1), Answers: map[UserID]int{1: 2}}
+	require.NoError(t, storage.SaveGroupQuiz(quiz))
+	res, err := storage.GetGroupQuiz("test")
+	assert.NoError(t, err)
+	assert.Equal(t, quiz, res)
+}
+
+func TestInMemoryGroupScores(t *testing.T) {
+	storage := NewInMemoryStorage()
+	score := GroupScore{Chat: ChatID(-1), User: UserID(1), Week: "2023-W19", Points: 2}
+	require.NoError(t, storage.SaveGroupScore(score))
+	require.NoError(t, storage.SaveGroupScore(GroupScore{Chat: ChatID(-1), User: UserID(1), Week: "2023-W20"}))
+	res, err := storage.GetGroupScores(ChatID(-1), "2023-W19")
+	assert.NoError(t, err)
+	assert.Equal(t, []GroupScore{score}, res)
+	res, err = storage.GetGroupScores(ChatID(-2), "2023-W19")
+	assert.NoError(t, err)
+	assert.Empty(t, res)
+}
+
+func TestInMemoryRefreshSession(t *testing.T) {
+	storage := NewInMemoryStorage()
+	_, err := storage.GetRefreshSession("test")
+	assert.ErrorIs(t, err, ErrNotFound)
+	session := RefreshSession{ID: "test", User: UserID(1), TokenHash: "hash"}
+	require.NoError(t, storage.SaveRefreshSession(session))
+	res, err := storage.GetRefreshSession("test")
+	assert.NoError(t, err)
+	assert.Equal(t, session, res)
+}
+
+func TestInMemoryRevokedTokens(t *testing.T) {
+	storage := NewInMemoryStorage()
+	require.NoError(t, storage.RevokeToken("test", time.Now().Add(time.Hour)))
+	require.NoError(t, storage.RevokeToken("expired", time.Now().Add(-time.Minute)))
+	for id, expected := range map[string]bool{"test": true, "expired": false, "unknown": false} {
+		revoked, err := storage.IsTokenRevoked(id)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, revoked, id)
+	}
+}
+
+func TestInMemoryPersonalTokens(t *testing.T) {
+	storage := NewInMemoryStorage()
+	_, err := storage.GetPersonalToken("test")
+	assert.ErrorIs(t, err, ErrNotFound)
+	now := time.Now()
+	tokens := []PersonalToken{
+		{ID: "b", User: UserID(1), Name: "first", Created: now},
+		{ID: "a", User: UserID(1), Name: "second", Created: now.Add(time.Minute)},
+	}
+	for _, token := range append(tokens, PersonalToken{ID: "c", User: UserID(2)}) {
+		require.NoError(t, storage.SavePersonalToken(token))
+	}
+	res, err := storage.GetPersonalToken("a")
+	assert.NoError(t, err)
+	assert.Equal(t, tokens[1], res)
+	list, err := storage.GetPersonalTokens(UserID(1))
+	assert.NoError(t, err)
+	assert.Equal(t, tokens, list)
+	assert.ErrorIs(t, storage.DeletePersonalToken(UserID(2), "a"), ErrNotFound)
+	require.NoError(t, storage.DeletePersonalToken(UserID(1), "a"))
+	_, err = storage.GetPersonalToken("a")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestInMemoryDeleteUserItem(t *testing.T) {
+	storage := NewInMemoryStorage()
+	assert.ErrorIs(t, storage.DeleteUserItem(UserID(1), "test"), ErrNotFound)
+	require.NoError(t, storage.SaveUserItem(UserDictionaryItem{User: UserID(1), Word: "test"}))
+	assert.NoError(t, storage.DeleteUserItem(UserID(1), "Test"))
+	_, err := storage.GetUserItem(UserID(1), "test")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
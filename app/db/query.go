@@ -0,0 +1,248 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DictionarySort is a field user dictionary is sorted by
+type DictionarySort string
+
+// user dictionary sort fields
+const (
+	SortByWord     DictionarySort = "word"
+	SortByCreated  DictionarySort = "created"
+	SortByLastQuiz DictionarySort = "last_quiz"
+)
+
+// dictionary query limits
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// dictionary query errors
+var (
+	ErrInvalidSort   = errors.New("invalid sort")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// ParseDictionarySort returns sort field by its name, empty name means sorting by word
+func ParseDictionarySort(name string) (DictionarySort, error) {
+	switch s := DictionarySort(name); s {
+	case "":
+		return SortByWord, nil
+	case SortByWord, SortByCreated, SortByLastQuiz:
+		return s, nil
+	}
+	return "", ErrInvalidSort
+}
+
+// DictionaryQuery defines filtering, sorting and pagination of user dictionary, zero values disable filters
+type DictionaryQuery struct {
+	// Tags filters items having any of the tags
+	Tags []string
+	// PartOfSpeech filters items having a meaning with the part of speech
+	PartOfSpeech string
+	// Language filters items having a translation to the language, user translations count too
+	Language string
+	// CreatedFrom filters items added at this time or later
+	CreatedFrom time.Time
+	// CreatedTo filters items added before this time
+	CreatedTo time.Time
+	// Prefix filters words starting with it
+	Prefix string
+	Sort   DictionarySort
+	Desc   bool
+	// Cursor is a position after which page starts, it's returned with the previous page
+	Cursor string
+	Limit  int
+}
+
+// UserDictionaryEntry is a user dictionary item with its dictionary data
+type UserDictionaryEntry struct {
+	UserItem UserDictionaryItem
+	Item     DictionaryItem
+}
+
+// DictionaryPage is a page of user dictionary query result
+type DictionaryPage struct {
+	Entries []UserDictionaryEntry
+	// Total is a number of items matching query filters
+	Total int
+	// NextCursor is empty for the last page
+	NextCursor string
+}
+
+// match returns true if entry passes query filters
+func (q DictionaryQuery) match(e UserDictionaryEntry) bool {
+	if len(q.Tags) != 0 && !e.UserItem.HasAnyTag(q.Tags) {
+		return false
+	}
+	if q.Prefix != "" && !strings.HasPrefix(NormalizeWord(e.UserItem.Word), NormalizeWord(q.Prefix)) {
+		return false
+	}
+	if !q.CreatedFrom.IsZero() && e.UserItem.Created.Before(q.CreatedFrom) {
+		return false
+	}
+	if !q.CreatedTo.IsZero() && !e.UserItem.Created.Before(q.CreatedTo) {
+		return false
+	}
+	if q.PartOfSpeech != "" && !hasPartOfSpeech(e.Item, q.PartOfSpeech) {
+		return false
+	}
+	if q.Language != "" && !hasLanguage(e, q.Language) {
+		return false
+	}
+	return true
+}
+
+func hasPartOfSpeech(item DictionaryItem, pos string) bool {
+	for _, m := range item.Meanings {
+		if strings.EqualFold(m.PartOfSpeech, pos) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasLanguage(e UserDictionaryEntry, lang string) bool {
+	if strings.EqualFold(lang, CustomTranslationLanguage) && len(e.UserItem.CustomTranslations()) != 0 {
+		return true
+	}
+	for _, t := range e.Item.Translations {
+		if strings.EqualFold(t.Language, lang) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortKey is a position of an entry in sorted dictionary, words break ties of time fields
+type sortKey struct {
+	Sort DictionarySort
+	Desc bool
+	Time int64 `json:",omitempty"`
+	Word string
+}
+
+func (q DictionaryQuery) key(item UserDictionaryItem) sortKey {
+	key := sortKey{Sort: q.Sort, Desc: q.Desc, Word: NormalizeWord(item.Word)}
+	switch q.Sort {
+	case SortByCreated:
+		key.Time = unixNano(item.Created)
+	case SortByLastQuiz:
+		key.Time = math.MinInt64
+		if item.LastQuiz != nil {
+			key.Time = unixNano(*item.LastQuiz)
+		}
+	}
+	return key
+}
+
+// unixNano returns time as a number keeping zero time before any other
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return math.MinInt64
+	}
+	return t.UnixNano()
+}
+
+// less returns true if key a goes before key b in query order
+func (q DictionaryQuery) less(a, b sortKey) bool {
+	res := a.Word < b.Word
+	if a.Time != b.Time {
+		res = a.Time < b.Time
+	}
+	if q.Desc {
+		if a.Time == b.Time && a.Word == b.Word {
+			return false
+		}
+		return !res
+	}
+	return res
+}
+
+func encodeCursor(key sortKey) string {
+	jdata, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(jdata)
+}
+
+// decodeCursor returns position of the cursor, cursor of other sort order is invalid
+func (q DictionaryQuery) decodeCursor() (sortKey, error) {
+	var key sortKey
+	jdata, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return key, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(jdata, &key); err != nil || key.Sort != q.Sort || key.Desc != q.Desc {
+		return key, ErrInvalidCursor
+	}
+	return key, nil
+}
+
+// QueryUserDictionary returns page of user dictionary items matching the query.
+// Dictionary is iterated in storage, only a page of items is kept in memory
+func QueryUserDictionary(s Storage, user UserID, q DictionaryQuery) (DictionaryPage, error) {
+	var err error
+	if q.Sort, err = ParseDictionarySort(string(q.Sort)); err != nil {
+		return DictionaryPage{}, err
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageLimit
+	}
+	if q.Limit > MaxPageLimit {
+		q.Limit = MaxPageLimit
+	}
+	var after *sortKey
+	if q.Cursor != "" {
+		key, err := q.decodeCursor()
+		if err != nil {
+			return DictionaryPage{}, err
+		}
+		after = &key
+	}
+
+	var page DictionaryPage
+	// one more entry is kept to know if there is a next page
+	keep := q.Limit + 1
+	entries := make([]UserDictionaryEntry, 0, 2*keep)
+	sortEntries := func() {
+		sort.Slice(entries, func(i, j int) bool {
+			return q.less(q.key(entries[i].UserItem), q.key(entries[j].UserItem))
+		})
+		if len(entries) > keep {
+			entries = entries[:keep]
+		}
+	}
+	err = s.IterateUserDictionary(user, func(userItem UserDictionaryItem, item DictionaryItem) error {
+		e := UserDictionaryEntry{UserItem: userItem, Item: item}
+		if !q.match(e) {
+			return nil
+		}
+		page.Total++
+		if after != nil && !q.less(*after, q.key(userItem)) {
+			return nil
+		}
+		entries = append(entries, e)
+		if len(entries) >= 2*keep {
+			sortEntries()
+		}
+		return nil
+	})
+	if err != nil {
+		return DictionaryPage{}, err
+	}
+	sortEntries()
+	if len(entries) > q.Limit {
+		entries = entries[:q.Limit]
+		page.NextCursor = encodeCursor(q.key(entries[len(entries)-1].UserItem))
+	}
+	page.Entries = entries
+	return page, nil
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pageWords(page DictionaryPage) []string {
+	words := make([]string, 0, len(page.Entries))
+	for _, e := range page.Entries {
+		words = append(words, e.UserItem.Word)
+	}
+	return words
+}
+
+func TestQueryUserDictionary(t *testing.T) {
+	storage := NewInMemoryStorage()
+	base := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastQuiz := base.Add(time.Hour)
+	for i, word := range []string{"cat", "dog", "desk", "apple", "car"} {
+		userItem := UserDictionaryItem{Word: word, User: UserID(1), Created: base.Add(time.Duration(i) * 24 * time.Hour)}
+		if word == "desk" {
+			userItem.LastQuiz = &lastQuiz
+			userItem.Custom = &UserItemCustom{Tags: []string{"work"}, Translations: []string{"стол"}}
+		}
+		require.NoError(t, storage.SaveUserItem(userItem))
+	}
+	require.NoError(t, storage.SaveUserItem(UserDictionaryItem{Word: "cow", User: UserID(2)}))
+	require.NoError(t, storage.Save(DictionaryItem{
+		Word:         "cat",
+		Meanings:     []Meaning{{PartOfSpeech: "noun"}},
+		Translations: []Translation{{Text: "chat", Language: "fr"}},
+	}))
+	require.NoError(t, storage.Save(DictionaryItem{Word: "dog", Meanings: []Meaning{{PartOfSpeech: "verb"}}}))
+
+	t.Run("sort by word", func(t *testing.T) {
+		page, err := QueryUserDictionary(storage, UserID(1), DictionaryQuery{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"apple", "car", "cat", "desk", "dog"}, pageWords(page))
+		assert.Equal(t, 5, page.Total)
+		assert.Empty(t, page.NextCursor)
+		assert.Equal(t, "noun", page.Entries[2].Item.Meanings[0].PartOfSpeech)
+	})
+	t.Run("sort by created desc", func(t *testing.T) {
+		page, err := QueryUserDictionary(storage, UserID(1), DictionaryQuery{Sort: SortByCreated, Desc: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"car", "apple", "desk", "dog", "cat"}, pageWords(page))
+	})
+	t.Run("sort by last quiz", func(t *testing.T) {
+		page, err := QueryUserDictionary(storage, UserID(1), DictionaryQuery{Sort: SortByLastQuiz, Desc: true})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"desk", "dog", "cat", "car", "apple"}, pageWords(page))
+	})
+	t.Run("filters", func(t *testing.T) {
+		cases := []struct {
+			name     string
+			query    DictionaryQuery
+			expected []string
+		}{
+			{"tags", DictionaryQuery{Tags: []string{"work"}}, []string{"desk"}},
+			{"part of speech", DictionaryQuery{PartOfSpeech: "Noun"}, []string{"cat"}},
+			{"language", DictionaryQuery{Language: "fr"}, []string{"cat"}},
+			{"custom language", DictionaryQuery{Language: CustomTranslationLanguage}, []string{"desk"}},
+			{"prefix", DictionaryQuery{Prefix: " CA"}, []string{"car", "cat"}},
+			{
+				"created",
+				DictionaryQuery{CreatedFrom: base.Add(24 * time.Hour), CreatedTo: base.Add(3 * 24 * time.Hour)},
+				[]string{"desk", "dog"},
+			},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				page, err := QueryUserDictionary(storage, UserID(1), c.query)
+				require.NoError(t, err)
+				assert.Equal(t, c.expected, pageWords(page))
+				assert.Equal(t, len(c.expected), page.Total)
+			})
+		}
+	})
+	t.Run("pagination", func(t *testing.T) {
+		for _, q := range []DictionaryQuery{
+			{Limit: 2},
+			{Limit: 1, Sort: SortByCreated, Desc: true},
+			{Limit: 3, Sort: SortByLastQuiz},
+		} {
+			all, err := QueryUserDictionary(storage, UserID(1), DictionaryQuery{Sort: q.Sort, Desc: q.Desc})
+			require.NoError(t, err)
+			var words []string
+			for {
+				page, err := QueryUserDictionary(storage, UserID(1), q)
+				require.NoError(t, err)
+				assert.Equal(t, 5, page.Total)
+				assert.LessOrEqual(t, len(page.Entries), q.Limit)
+				words = append(words, pageWords(page)...)
+				if page.NextCursor == "" {
+					break
+				}
+				q.Cursor = page.NextCursor
+			}
+			assert.Equal(t, pageWords(all), words)
+		}
+	})
+	t.Run("invalid cursor", func(t *testing.T) {
+		page, err := QueryUserDictionary(storage, UserID(1), DictionaryQuery{Limit: 1})
+		require.NoError(t, err)
+		_, err = QueryUserDictionary(storage, UserID(1), DictionaryQuery{Limit: 1, Sort: SortByCreated, Cursor: page.NextCursor})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+		_, err = QueryUserDictionary(storage, UserID(1), DictionaryQuery{Cursor: "invalid!"})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+	t.Run("invalid sort", func(t *testing.T) {
+		_, err := QueryUserDictionary(storage, UserID(1), DictionaryQuery{Sort: "invalid"})
+		assert.ErrorIs(t, err, ErrInvalidSort)
+	})
+}
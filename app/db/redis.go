@@ -6,7 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -16,8 +19,26 @@ const (
 	prefixUser     = "user:"
 	prefixUserItem = "user_item:"
 	prefixQuiz     = "quiz:"
+	// user quizzes are indexed by a sorted set scored by creation time
+	prefixUserQuizzes = "user_quizzes:"
+	prefixGroup       = "group:"
+	prefixGroupQuiz   = "group_quiz:"
+	prefixGroupScore  = "group_score:"
+	prefixSession     = "refresh_session:"
+	prefixRevoked     = "revoked_token:"
+	// personal tokens are kept in a hash per user with index of token owners
+	prefixPersonalTokens = "personal_tokens:"
+	prefixPersonalToken  = "personal_token:"
+	// word revisions are kept in a list per word, the oldest revision first
+	prefixWordRevisions = "word_revisions:"
+
+	// keyWords is a sorted set of all dictionary words used for prefix search
+	keyWords = "words"
 )
 
+// redisScanCount is a batch size hint for SCAN commands
+const redisScanCount = 100
+
 // RedisStorage is a storage implementation using redis
 type RedisStorage struct {
 	db *redis.Client
@@ -25,7 +46,7 @@ type RedisStorage struct {
 
 // Get word from redis
 func (s *RedisStorage) Get(word string) (DictionaryItem, error) {
-	data, err := s.db.Get(context.Background(), prefixWord+word).Result()
+	data, err := s.db.Get(context.Background(), prefixWord+NormalizeWord(word)).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return DictionaryItem{}, ErrNotFound
@@ -42,18 +63,109 @@ func (s *RedisStorage) Get(word string) (DictionaryItem, error) {
 
 // Save word to redis
 func (s *RedisStorage) Save(item DictionaryItem) error {
-	key := prefixWord + item.Word
+	key := prefixWord + NormalizeWord(item.Word)
 	jdata, jerr := json.Marshal(item)
 	if jerr != nil {
 		return fmt.Errorf("marshal word: %w", jerr)
 	}
-	_, err := s.db.Set(context.Background(), key, string(jdata), 0).Result()
+	_, err := s.db.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Set(context.Background(), key, string(jdata), 0)
+		pipe.ZAdd(context.Background(), keyWords, &redis.Z{Member: NormalizeWord(item.Word)})
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("saving word: %w", err)
 	}
 	return nil
 }
 
+// GetWords returns all words from redis
+func (s *RedisStorage) GetWords() ([]string, error) {
+	var words []string
+	var cursor uint64
+	for {
+		keys, next, err := s.db.Scan(context.Background(), cursor, prefixWord+"*", redisScanCount).Result()
+		if err != nil {
+			return nil, fmt.Errorf("scan words: %w", err)
+		}
+		for _, key := range keys {
+			words = append(words, strings.TrimPrefix(key, prefixWord))
+		}
+		if next == 0 {
+			return words, nil
+		}
+		cursor = next
+	}
+}
+
+// GetWordsByPrefix returns up to limit words starting with prefix in lexicographical order
+func (s *RedisStorage) GetWordsByPrefix(prefix string, limit int) ([]string, error) {
+	// words are UTF-8 strings, so they never have 0xff byte
+	by := &redis.ZRangeBy{Min: "[" + prefix, Max: "(" + prefix + "\xff", Count: int64(limit)}
+	if prefix == "" {
+		by.Min, by.Max = "-", "+"
+	}
+	words, err := s.db.ZRangeByLex(context.Background(), keyWords, by).Result()
+	if err != nil {
+		return nil, fmt.Errorf("fetching words: %w", err)
+	}
+	return words, nil
+}
+
+// indexWords adds all saved words to the words index, it's needed for words saved before the index was kept
+func (s *RedisStorage) indexWords() error {
+	exists, err := s.db.Exists(context.Background(), keyWords).Result()
+	if err != nil || exists != 0 {
+		return err
+	}
+	words, err := s.GetWords()
+	if err != nil {
+		return err
+	}
+	members := make([]*redis.Z, 0, len(words))
+	for _, w := range words {
+		members = append(members, &redis.Z{Member: w})
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	return s.db.ZAdd(context.Background(), keyWords, members...).Err()
+}
+
+// GetWordRevisions returns edit history of dictionary item from redis, the oldest revision first,
+// revisions are numbered by their position in the list
+func (s *RedisStorage) GetWordRevisions(word string) ([]WordRevision, error) {
+	values, err := s.db.LRange(context.Background(), prefixWordRevisions+NormalizeWord(word), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("fetching revisions: %w", err)
+	}
+	res := make([]WordRevision, 0, len(values))
+	for i, data := range values {
+		var rev WordRevision
+		if jerr := json.Unmarshal([]byte(data), &rev); jerr != nil {
+			return nil, fmt.Errorf("unmarshal revision: %w", jerr)
+		}
+		rev.Rev = i + 1
+		res = append(res, rev)
+	}
+	return res, nil
+}
+
+// SaveWordRevision appends revision to edit history of dictionary item in redis,
+// number of the revision is the list length after the push
+func (s *RedisStorage) SaveWordRevision(rev WordRevision) (int, error) {
+	rev.Rev = 0
+	jdata, jerr := json.Marshal(rev)
+	if jerr != nil {
+		return 0, fmt.Errorf("marshal revision: %w", jerr)
+	}
+	length, err := s.db.RPush(context.Background(), prefixWordRevisions+NormalizeWord(rev.Word), string(jdata)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("saving revision: %w", err)
+	}
+	return int(length), nil
+}
+
 // GetUser from redis
 func (s *RedisStorage) GetUser(id UserID) (User, error) {
 	data, err := s.db.Get(context.Background(), prefixUser+strconv.FormatInt(int64(id), 10)).Result()
@@ -86,10 +198,45 @@ func (s *RedisStorage) SaveUser(user User) error {
 	return nil
 }
 
+// GetUsers returns all users sorted by ID
+func (s *RedisStorage) GetUsers() ([]User, error) {
+	var users []User
+	var cursor uint64
+	for {
+		keys, next, err := s.db.Scan(context.Background(), cursor, prefixUser+"*", redisScanCount).Result()
+		if err != nil {
+			return nil, fmt.Errorf("scan users: %w", err)
+		}
+		if len(keys) != 0 {
+			values, err := s.db.MGet(context.Background(), keys...).Result()
+			if err != nil {
+				return nil, fmt.Errorf("fetching users: %w", err)
+			}
+			for _, value := range values {
+				data, ok := value.(string)
+				if !ok {
+					continue
+				}
+				var user User
+				if jerr := json.Unmarshal([]byte(data), &user); jerr != nil {
+					return nil, fmt.Errorf("unmarshal user: %w", jerr)
+				}
+				users = append(users, user)
+			}
+		}
+		if next == 0 {
+			break
+		}
+		cursor = next
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
 // GetUserItem returns user item from redis
 func (s *RedisStorage) GetUserItem(user UserID, word string) (UserDictionaryItem, error) {
 	key := prefixUserItem + strconv.FormatInt(int64(user), 10)
-	data, err := s.db.HGet(context.Background(), key, word).Result()
+	data, err := s.db.HGet(context.Background(), key, NormalizeWord(word)).Result()
 	if err != nil {
 		if errors.Is(err, redis.Nil) {
 			return UserDictionaryItem{}, ErrNotFound
@@ -111,13 +258,70 @@ func (s *RedisStorage) SaveUserItem(item UserDictionaryItem) error {
 	if jerr != nil {
 		return fmt.Errorf("marshal user item: %w", jerr)
 	}
-	_, err := s.db.HSet(context.Background(), key, item.Word, string(jdata)).Result()
+	_, err := s.db.HSet(context.Background(), key, NormalizeWord(item.Word), string(jdata)).Result()
 	if err != nil {
 		return fmt.Errorf("saving user item: %w", err)
 	}
 	return nil
 }
 
+// IterateUserDictionary scans user items hash in batches, dictionary items are fetched for every batch
+func (s *RedisStorage) IterateUserDictionary(user UserID, fn func(UserDictionaryItem, DictionaryItem) error) error {
+	key := prefixUserItem + strconv.FormatInt(int64(user), 10)
+	var cursor uint64
+	for {
+		fields, next, err := s.db.HScan(context.Background(), key, cursor, "", redisScanCount).Result()
+		if err != nil {
+			return fmt.Errorf("scanning user items: %w", err)
+		}
+		// fields holds field and value pairs
+		userItems := make([]UserDictionaryItem, 0, len(fields)/2)
+		words := make([]string, 0, len(fields)/2)
+		for i := 1; i < len(fields); i += 2 {
+			var item UserDictionaryItem
+			if jerr := json.NewDecoder(bytes.NewBufferString(fields[i])).Decode(&item); jerr != nil {
+				return fmt.Errorf("unmarshal user item: %w", jerr)
+			}
+			userItems = append(userItems, item)
+			words = append(words, prefixWord+NormalizeWord(item.Word))
+		}
+		if len(words) != 0 {
+			wordsData, err := s.db.MGet(context.Background(), words...).Result()
+			if err != nil {
+				return fmt.Errorf("fetching words: %w", err)
+			}
+			for i, item := range userItems {
+				word := DictionaryItem{Word: item.Word}
+				if jdata, ok := wordsData[i].(string); ok {
+					if jerr := json.NewDecoder(bytes.NewBufferString(jdata)).Decode(&word); jerr != nil {
+						return fmt.Errorf("unmarshal word: %w", jerr)
+					}
+				}
+				if err := fn(item, word); err != nil {
+					return err
+				}
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// DeleteUserItem from redis
+func (s *RedisStorage) DeleteUserItem(user UserID, word string) error {
+	key := prefixUserItem + strconv.FormatInt(int64(user), 10)
+	deleted, err := s.db.HDel(context.Background(), key, NormalizeWord(word)).Result()
+	if err != nil {
+		return fmt.Errorf("deleting user item: %w", err)
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // GetUserDictionary from redis
 func (s *RedisStorage) GetUserDictionary(user UserID) (map[UserDictionaryItem]DictionaryItem, error) {
 	key := prefixUserItem + strconv.FormatInt(int64(user), 10)
@@ -129,6 +333,7 @@ func (s *RedisStorage) GetUserDictionary(user UserID) (map[UserDictionaryItem]Di
 		return nil, fmt.Errorf("fetching user items: %w", err)
 	}
 	dict := make(map[UserDictionaryItem]DictionaryItem, len(userItems))
+	items := make([]UserDictionaryItem, 0, len(userItems))
 	words := make([]string, 0, len(userItems))
 	for word, jdata := range userItems {
 		buf := bytes.NewBufferString(jdata)
@@ -136,27 +341,25 @@ func (s *RedisStorage) GetUserDictionary(user UserID) (map[UserDictionaryItem]Di
 		if jerr := json.NewDecoder(buf).Decode(&item); jerr != nil {
 			return nil, fmt.Errorf("unmarshal user item: %w", jerr)
 		}
-		dict[item] = DictionaryItem{Word: word}
+		items = append(items, item)
 		words = append(words, prefixWord+word)
 	}
-	wordsData := s.db.MGet(context.Background(), words...)
-	if err := wordsData.Err(); err != nil {
+	if len(words) == 0 {
+		return dict, nil
+	}
+	wordsData, err := s.db.MGet(context.Background(), words...).Result()
+	if err != nil {
 		return nil, fmt.Errorf("fetching words: %w", err)
 	}
-	wordsJsons := make(map[string]DictionaryItem, len(wordsData.Val()))
-	for _, wd := range wordsData.Val() {
-		jdata, ok := wd.(string)
-		if !ok {
-			return nil, fmt.Errorf("unexpected word data: %w", err)
-		}
-		word := DictionaryItem{}
-		if jerr := json.NewDecoder(bytes.NewBufferString(jdata)).Decode(&word); jerr != nil {
-			return nil, fmt.Errorf("unmarshal word: %w", jerr)
+	for i, item := range items {
+		// item might be missing in shared dictionary, e.g. imported unknown word with user translations
+		word := DictionaryItem{Word: item.Word}
+		if jdata, ok := wordsData[i].(string); ok {
+			if jerr := json.NewDecoder(bytes.NewBufferString(jdata)).Decode(&word); jerr != nil {
+				return nil, fmt.Errorf("unmarshal word: %w", jerr)
+			}
 		}
-		wordsJsons[word.Word] = word
-	}
-	for item := range dict {
-		dict[item] = wordsJsons[item.Word]
+		dict[item] = word
 	}
 	return dict, nil
 }
@@ -185,13 +388,280 @@ func (s *RedisStorage) SaveQuiz(q Quiz) error {
 	if jerr != nil {
 		return fmt.Errorf("marshal quiz: %w", jerr)
 	}
-	set := s.db.Set(context.Background(), key, string(jdata), 0)
-	if err := set.Err(); err != nil {
+	_, err := s.db.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Set(context.Background(), key, string(jdata), 0)
+		pipe.ZAdd(context.Background(), prefixUserQuizzes+strconv.FormatInt(int64(q.User), 10), &redis.Z{
+			Score:  float64(q.Created.UnixMilli()),
+			Member: q.ID,
+		})
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("saving quiz: %w", err)
 	}
 	return nil
 }
 
+// GetUserQuizzes from redis
+func (s *RedisStorage) GetUserQuizzes(user UserID, limit int) ([]Quiz, error) {
+	res := make([]Quiz, 0)
+	if limit <= 0 {
+		return res, nil
+	}
+	key := prefixUserQuizzes + strconv.FormatInt(int64(user), 10)
+	ids, err := s.db.ZRevRange(context.Background(), key, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("fetching user quizzes: %w", err)
+	}
+	if len(ids) == 0 {
+		return res, nil
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, prefixQuiz+id)
+	}
+	data, err := s.db.MGet(context.Background(), keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("fetching quizzes: %w", err)
+	}
+	for _, d := range data {
+		jdata, ok := d.(string)
+		if !ok {
+			continue
+		}
+		var quiz Quiz
+		if jerr := json.NewDecoder(bytes.NewBufferString(jdata)).Decode(&quiz); jerr != nil {
+			return nil, fmt.Errorf("unmarshal quiz: %w", jerr)
+		}
+		res = append(res, quiz)
+	}
+	return res, nil
+}
+
+// GetGroup from redis
+func (s *RedisStorage) GetGroup(id ChatID) (Group, error) {
+	data, err := s.db.Get(context.Background(), prefixGroup+strconv.FormatInt(int64(id), 10)).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return Group{}, ErrNotFound
+		}
+		return Group{}, fmt.Errorf("fetching group: %w", err)
+	}
+	var group Group
+	if jerr := json.NewDecoder(bytes.NewBufferString(data)).Decode(&group); jerr != nil {
+		return group, fmt.Errorf("unmarshal group: %w", jerr)
+	}
+	return group, nil
+}
+
+// SaveGroup to redis
+func (s *RedisStorage) SaveGroup(group Group) error {
+	key := prefixGroup + strconv.FormatInt(int64(group.ID), 10)
+	jdata, jerr := json.Marshal(group)
+	if jerr != nil {
+		return fmt.Errorf("marshal group: %w", jerr)
+	}
+	if err := s.db.Set(context.Background(), key, string(jdata), 0).Err(); err != nil {
+		return fmt.Errorf("saving group: %w", err)
+	}
+	return nil
+}
+
+// GetGroupQuiz from redis
+func (s *RedisStorage) GetGroupQuiz(id string) (GroupQuiz, error) {
+	data, err := s.db.Get(context.Background(), prefixGroupQuiz+id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return GroupQuiz{}, ErrNotFound
+		}
+		return GroupQuiz{}, fmt.Errorf("fetching group quiz: %w", err)
+	}
+	var quiz GroupQuiz
+	if jerr := json.NewDecoder(bytes.NewBufferString(data)).Decode(&quiz); jerr != nil {
+		return quiz, fmt.Errorf("unmarshal group quiz: %w", jerr)
+	}
+	return quiz, nil
+}
+
+// SaveGroupQuiz to redis
+func (s *RedisStorage) SaveGroupQuiz(q GroupQuiz) error {
+	jdata, jerr := json.Marshal(q)
+	if jerr != nil {
+		return fmt.Errorf("marshal group quiz: %w", jerr)
+	}
+	if err := s.db.Set(context.Background(), prefixGroupQuiz+q.ID, string(jdata), 0).Err(); err != nil {
+		return fmt.Errorf("saving group quiz: %w", err)
+	}
+	return nil
+}
+
+// groupScoresKey returns key of hash with group scores for the week
+func groupScoresKey(chat ChatID, week string) string {
+	return prefixGroupScore + strconv.FormatInt(int64(chat), 10) + ":" + week
+}
+
+// GetGroupScores from redis
+func (s *RedisStorage) GetGroupScores(chat ChatID, week string) ([]GroupScore, error) {
+	data, err := s.db.HGetAll(context.Background(), groupScoresKey(chat, week)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("fetching group scores: %w", err)
+	}
+	scores := make([]GroupScore, 0, len(data))
+	for _, jdata := range data {
+		var score GroupScore
+		if jerr := json.NewDecoder(bytes.NewBufferString(jdata)).Decode(&score); jerr != nil {
+			return nil, fmt.Errorf("unmarshal group score: %w", jerr)
+		}
+		scores = append(scores, score)
+	}
+	return scores, nil
+}
+
+// SaveGroupScore to redis
+func (s *RedisStorage) SaveGroupScore(score GroupScore) error {
+	jdata, jerr := json.Marshal(score)
+	if jerr != nil {
+		return fmt.Errorf("marshal group score: %w", jerr)
+	}
+	key := groupScoresKey(score.Chat, score.Week)
+	field := strconv.FormatInt(int64(score.User), 10)
+	if err := s.db.HSet(context.Background(), key, field, string(jdata)).Err(); err != nil {
+		return fmt.Errorf("saving group score: %w", err)
+	}
+	return nil
+}
+
+// setUntil sets key value which expires at the given time
+func (s *RedisStorage) setUntil(key string, value string, expires time.Time) error {
+	_, err := s.db.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.Set(context.Background(), key, value, 0)
+		pipe.ExpireAt(context.Background(), key, expires)
+		return nil
+	})
+	return err
+}
+
+// GetRefreshSession from redis
+func (s *RedisStorage) GetRefreshSession(id string) (RefreshSession, error) {
+	data, err := s.db.Get(context.Background(), prefixSession+id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return RefreshSession{}, ErrNotFound
+		}
+		return RefreshSession{}, fmt.Errorf("fetching refresh session: %w", err)
+	}
+	var session RefreshSession
+	if jerr := json.NewDecoder(bytes.NewBufferString(data)).Decode(&session); jerr != nil {
+		return session, fmt.Errorf("unmarshal refresh session: %w", jerr)
+	}
+	return session, nil
+}
+
+// SaveRefreshSession to redis, the key expires together with the session
+func (s *RedisStorage) SaveRefreshSession(session RefreshSession) error {
+	jdata, jerr := json.Marshal(session)
+	if jerr != nil {
+		return fmt.Errorf("marshal refresh session: %w", jerr)
+	}
+	if err := s.setUntil(prefixSession+session.ID, string(jdata), session.Expires); err != nil {
+		return fmt.Errorf("saving refresh session: %w", err)
+	}
+	return nil
+}
+
+// RevokeToken adds token ID to denylist until its expiration
+func (s *RedisStorage) RevokeToken(id string, expires time.Time) error {
+	if err := s.setUntil(prefixRevoked+id, "1", expires); err != nil {
+		return fmt.Errorf("saving revoked token: %w", err)
+	}
+	return nil
+}
+
+// IsTokenRevoked returns true if token ID is in denylist
+func (s *RedisStorage) IsTokenRevoked(id string) (bool, error) {
+	n, err := s.db.Exists(context.Background(), prefixRevoked+id).Result()
+	if err != nil {
+		return false, fmt.Errorf("fetching revoked token: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetPersonalToken from redis, owner of the token is looked up first
+func (s *RedisStorage) GetPersonalToken(id string) (PersonalToken, error) {
+	user, err := s.db.Get(context.Background(), prefixPersonalToken+id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return PersonalToken{}, ErrNotFound
+		}
+		return PersonalToken{}, fmt.Errorf("fetching personal token owner: %w", err)
+	}
+	data, err := s.db.HGet(context.Background(), prefixPersonalTokens+user, id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return PersonalToken{}, ErrNotFound
+		}
+		return PersonalToken{}, fmt.Errorf("fetching personal token: %w", err)
+	}
+	var token PersonalToken
+	if jerr := json.NewDecoder(bytes.NewBufferString(data)).Decode(&token); jerr != nil {
+		return token, fmt.Errorf("unmarshal personal token: %w", jerr)
+	}
+	return token, nil
+}
+
+// GetPersonalTokens from redis ordered by creation time
+func (s *RedisStorage) GetPersonalTokens(user UserID) ([]PersonalToken, error) {
+	key := prefixPersonalTokens + strconv.FormatInt(int64(user), 10)
+	data, err := s.db.HGetAll(context.Background(), key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("fetching personal tokens: %w", err)
+	}
+	tokens := make([]PersonalToken, 0, len(data))
+	for _, jdata := range data {
+		var token PersonalToken
+		if jerr := json.NewDecoder(bytes.NewBufferString(jdata)).Decode(&token); jerr != nil {
+			return nil, fmt.Errorf("unmarshal personal token: %w", jerr)
+		}
+		tokens = append(tokens, token)
+	}
+	sortPersonalTokens(tokens)
+	return tokens, nil
+}
+
+// SavePersonalToken to redis
+func (s *RedisStorage) SavePersonalToken(token PersonalToken) error {
+	jdata, jerr := json.Marshal(token)
+	if jerr != nil {
+		return fmt.Errorf("marshal personal token: %w", jerr)
+	}
+	user := strconv.FormatInt(int64(token.User), 10)
+	_, err := s.db.TxPipelined(context.Background(), func(pipe redis.Pipeliner) error {
+		pipe.HSet(context.Background(), prefixPersonalTokens+user, token.ID, string(jdata))
+		pipe.Set(context.Background(), prefixPersonalToken+token.ID, user, 0)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("saving personal token: %w", err)
+	}
+	return nil
+}
+
+// DeletePersonalToken from redis
+func (s *RedisStorage) DeletePersonalToken(user UserID, id string) error {
+	key := prefixPersonalTokens + strconv.FormatInt(int64(user), 10)
+	deleted, err := s.db.HDel(context.Background(), key, id).Result()
+	if err != nil {
+		return fmt.Errorf("deleting personal token: %w", err)
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	if err := s.db.Del(context.Background(), prefixPersonalToken+id).Err(); err != nil {
+		return fmt.Errorf("deleting personal token owner: %w", err)
+	}
+	return nil
+}
+
 // NewRedisStorage creates RedisStorage with given url
 func NewRedisStorage(url string) (*RedisStorage, error) {
 	opt, err := redis.ParseURL(url)
@@ -202,5 +672,9 @@ func NewRedisStorage(url string) (*RedisStorage, error) {
 	if _, err := rdb.Ping(context.Background()).Result(); err != nil {
 		return nil, fmt.Errorf("ping redis: %w", err)
 	}
-	return &RedisStorage{db: rdb}, nil
+	storage := &RedisStorage{db: rdb}
+	if err := storage.indexWords(); err != nil {
+		return nil, fmt.Errorf("index words: %w", err)
+	}
+	return storage, nil
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-redis/redis/v8"
 	"github.com/go-redis/redismock/v8"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -44,22 +45,181 @@ func TestRedisSet(t *testing.T) {
 		db, mock := redismock.NewClientMock()
 		storage := RedisStorage{db: db}
 		expected := `{"Word":"test","Phonetics":{"Text":"","Audio":""},"Meanings":null,"Translations":null}`
+		mock.ExpectTxPipeline()
 		mock.ExpectSet("word:test", expected, 0).SetVal("OK")
+		mock.ExpectZAdd("words", &redis.Z{Member: "test"}).SetVal(1)
+		mock.ExpectTxPipelineExec()
 
 		err := storage.Save(DictionaryItem{Word: "test"})
 		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("error", func(t *testing.T) {
 		db, mock := redismock.NewClientMock()
 		storage := RedisStorage{db: db}
 		expected := `{"Word":"test","Phonetics":{"Text":"","Audio":""},"Meanings":null,"Translations":null}`
+		mock.ExpectTxPipeline()
 		mock.ExpectSet("word:test", expected, 0).SetErr(errors.New("FAIL"))
+		mock.ExpectZAdd("words", &redis.Z{Member: "test"}).SetVal(1)
+		mock.ExpectTxPipelineExec()
 
 		err := storage.Save(DictionaryItem{Word: "test"})
 		assert.Error(t, err)
 	})
 }
 
+func TestRedisPhrase(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	storage := RedisStorage{db: db}
+	mock.ExpectGet("word:give up").SetVal("{\"word\":\"give up\"}")
+	word, err := storage.Get(" Give  up")
+	assert.NoError(t, err)
+	assert.Equal(t, DictionaryItem{Word: "give up"}, word)
+
+	item := UserDictionaryItem{Word: "Give Up", User: UserID(1), Created: time.Now().UTC()}
+	expected, err := json.Marshal(item)
+	require.NoError(t, err)
+	mock.ExpectHSet("user_item:1", "give up", string(expected)).SetVal(1)
+	assert.NoError(t, storage.SaveUserItem(item))
+	mock.ExpectHGet("user_item:1", "give up").RedisNil()
+	_, err = storage.GetUserItem(item.User, "give  up")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedisGetWords(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectScan(0, "word:*", redisScanCount).SetVal([]string{"word:test1"}, 5)
+		mock.ExpectScan(5, "word:*", redisScanCount).SetVal([]string{"word:test2", "word:give up"}, 0)
+
+		words, err := storage.GetWords()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"test1", "test2", "give up"}, words)
+	})
+	t.Run("error", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectScan(0, "word:*", redisScanCount).SetErr(errors.New("FAIL"))
+
+		_, err := storage.GetWords()
+		assert.Error(t, err)
+	})
+}
+
+func TestRedisGetWordsByPrefix(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectZRangeByLex("words", &redis.ZRangeBy{Min: "[te", Max: "(te\xff", Count: 2}).
+			SetVal([]string{"tea", "test"})
+
+		words, err := storage.GetWordsByPrefix("te", 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"tea", "test"}, words)
+	})
+	t.Run("all", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectZRangeByLex("words", &redis.ZRangeBy{Min: "-", Max: "+"}).SetVal([]string{"cat"})
+
+		words, err := storage.GetWordsByPrefix("", 0)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"cat"}, words)
+	})
+	t.Run("error", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectZRangeByLex("words", &redis.ZRangeBy{Min: "[te", Max: "(te\xff"}).SetErr(errors.New("FAIL"))
+
+		_, err := storage.GetWordsByPrefix("te", 0)
+		assert.Error(t, err)
+	})
+}
+
+func TestRedisIndexWords(t *testing.T) {
+	t.Run("missing index", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectExists("words").SetVal(0)
+		mock.ExpectScan(0, "word:*", redisScanCount).SetVal([]string{"word:cat", "word:dog"}, 0)
+		mock.ExpectZAdd("words", &redis.Z{Member: "cat"}, &redis.Z{Member: "dog"}).SetVal(2)
+
+		assert.NoError(t, storage.indexWords())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("existing index", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectExists("words").SetVal(1)
+
+		assert.NoError(t, storage.indexWords())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRedisGetWordRevisions(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		expected := []WordRevision{{Word: "test", Rev: 1}, {Word: "test", Rev: 2, Author: UserID(1)}}
+		values := make([]string, 0, len(expected))
+		for _, rev := range expected {
+			// numbers aren't stored, they are positions in the list
+			rev.Rev = 0
+			jdata, err := json.Marshal(rev)
+			require.NoError(t, err)
+			values = append(values, string(jdata))
+		}
+		mock.ExpectLRange("word_revisions:test", 0, -1).SetVal(values)
+
+		revisions, err := storage.GetWordRevisions("Test")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, revisions)
+	})
+	t.Run("error", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectLRange("word_revisions:test", 0, -1).SetErr(errors.New("FAIL"))
+
+		_, err := storage.GetWordRevisions("test")
+		assert.Error(t, err)
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectLRange("word_revisions:test", 0, -1).SetVal([]string{"NOT_JSON"})
+
+		_, err := storage.GetWordRevisions("test")
+		assert.Error(t, err)
+	})
+}
+
+func TestRedisSaveWordRevision(t *testing.T) {
+	rev := WordRevision{Word: "test", Diff: []string{"+ translation (ru): тест"}}
+	jdata, err := json.Marshal(rev)
+	require.NoError(t, err)
+	t.Run("success", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectRPush("word_revisions:test", string(jdata)).SetVal(3)
+
+		n, err := storage.SaveWordRevision(rev)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("error", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectRPush("word_revisions:test", string(jdata)).SetErr(errors.New("FAIL"))
+
+		_, err := storage.SaveWordRevision(rev)
+		assert.Error(t, err)
+	})
+}
+
 func TestGetUser(t *testing.T) {
 	t.Run("existing", func(t *testing.T) {
 		db, mock := redismock.NewClientMock()
@@ -114,6 +274,38 @@ func TestRedisSaveUser(t *testing.T) {
 	})
 }
 
+func TestRedisGetUsers(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectScan(0, "user:*", redisScanCount).SetVal([]string{"user:2"}, 5)
+		mock.ExpectMGet("user:2").SetVal([]interface{}{`{"ID":2,"Username":"second"}`})
+		mock.ExpectScan(5, "user:*", redisScanCount).SetVal([]string{"user:1", "user:3"}, 0)
+		mock.ExpectMGet("user:1", "user:3").SetVal([]interface{}{`{"ID":1,"Blocked":true}`, nil})
+
+		users, err := storage.GetUsers()
+		assert.NoError(t, err)
+		assert.Equal(t, []User{{ID: 1, Blocked: true}, {ID: 2, Username: "second"}}, users)
+	})
+	t.Run("error", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectScan(0, "user:*", redisScanCount).SetErr(errors.New("FAIL"))
+
+		_, err := storage.GetUsers()
+		assert.Error(t, err)
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectScan(0, "user:*", redisScanCount).SetVal([]string{"user:1"}, 0)
+		mock.ExpectMGet("user:1").SetVal([]interface{}{"NOT_JSON"})
+
+		_, err := storage.GetUsers()
+		assert.Error(t, err)
+	})
+}
+
 func TestRedisSaveUserItem(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		db, mock := redismock.NewClientMock()
@@ -202,6 +394,30 @@ func TestRedisGetUserDictionary(t *testing.T) {
 		assert.Equal(t, map[UserDictionaryItem]DictionaryItem{userItem1: item1, userItem2: item2}, res)
 
 	})
+	t.Run("missing item", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		userItem := UserDictionaryItem{Word: "qwerty", User: UserID(1)}
+		userItemJSON, err := json.Marshal(userItem)
+		require.NoError(t, err)
+		mock.ExpectHGetAll("user_item:1").SetVal(map[string]string{"qwerty": string(userItemJSON)})
+		mock.ExpectMGet("word:qwerty").SetVal([]interface{}{nil})
+
+		res, err := storage.GetUserDictionary(UserID(1))
+		assert.NoError(t, err)
+		assert.Equal(t, map[UserDictionaryItem]DictionaryItem{userItem: {Word: "qwerty"}}, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("no items", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectHGetAll("user_item:1").SetVal(map[string]string{})
+
+		items, err := storage.GetUserDictionary(UserID(1))
+		assert.NoError(t, err)
+		assert.Empty(t, items)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 	t.Run("empty", func(t *testing.T) {
 		db, mock := redismock.NewClientMock()
 		storage := RedisStorage{db: db}
@@ -213,6 +429,48 @@ func TestRedisGetUserDictionary(t *testing.T) {
 	})
 }
 
+func TestRedisIterateUserDictionary(t *testing.T) {
+	t.Run("existing", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		item := DictionaryItem{Word: "test"}
+		itemJSON, err := json.Marshal(item)
+		require.NoError(t, err)
+		userItem1 := UserDictionaryItem{Word: "test", User: UserID(1)}
+		userItem1JSON, err := json.Marshal(userItem1)
+		require.NoError(t, err)
+		userItem2 := UserDictionaryItem{Word: "missing", User: UserID(1)}
+		userItem2JSON, err := json.Marshal(userItem2)
+		require.NoError(t, err)
+
+		mock.ExpectHScan("user_item:1", 0, "", redisScanCount).
+			SetVal([]string{userItem1.Word, string(userItem1JSON)}, 5)
+		mock.ExpectMGet("word:test").SetVal([]interface{}{string(itemJSON)})
+		mock.ExpectHScan("user_item:1", 5, "", redisScanCount).
+			SetVal([]string{userItem2.Word, string(userItem2JSON)}, 0)
+		mock.ExpectMGet("word:missing").SetVal([]interface{}{nil})
+
+		res := make(map[UserDictionaryItem]DictionaryItem)
+		err = storage.IterateUserDictionary(UserID(1), func(userItem UserDictionaryItem, item DictionaryItem) error {
+			res[userItem] = item
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[UserDictionaryItem]DictionaryItem{userItem1: item, userItem2: {Word: "missing"}}, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("empty", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectHScan("user_item:1", 0, "", redisScanCount).SetVal([]string{}, 0)
+
+		err := storage.IterateUserDictionary(UserID(1), func(UserDictionaryItem, DictionaryItem) error {
+			return errors.New("unexpected item")
+		})
+		assert.NoError(t, err)
+	})
+}
+
 func TestGetQuiz(t *testing.T) {
 	t.Run("existing", func(t *testing.T) {
 		db, mock := redismock.NewClientMock()
@@ -252,10 +510,14 @@ func TestRedisSaveQuiz(t *testing.T) {
 		quiz := Quiz{ID: "test", User: UserID(1), Language: "en"}
 		expected, err := json.Marshal(quiz)
 		require.NoError(t, err)
+		mock.ExpectTxPipeline()
 		mock.ExpectSet("quiz:test", string(expected), 0).SetVal("OK")
+		mock.ExpectZAdd("user_quizzes:1", &redis.Z{Score: float64(quiz.Created.UnixMilli()), Member: "test"}).SetVal(1)
+		mock.ExpectTxPipelineExec()
 
 		err = storage.SaveQuiz(quiz)
 		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 	t.Run("error", func(t *testing.T) {
 		db, mock := redismock.NewClientMock()
@@ -263,9 +525,293 @@ func TestRedisSaveQuiz(t *testing.T) {
 		quiz := Quiz{ID: "test", User: UserID(1), Language: "en"}
 		expected, err := json.Marshal(quiz)
 		require.NoError(t, err)
+		mock.ExpectTxPipeline()
 		mock.ExpectSet("quiz:test", string(expected), 0).SetErr(errors.New("FAIL"))
+		mock.ExpectZAdd("user_quizzes:1", &redis.Z{Score: float64(quiz.Created.UnixMilli()), Member: "test"}).SetVal(1)
+		mock.ExpectTxPipelineExec()
 
 		err = storage.SaveQuiz(quiz)
 		assert.Error(t, err)
 	})
 }
+
+func TestRedisGetUserQuizzes(t *testing.T) {
+	t.Run("existing", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		quiz := Quiz{ID: "test", User: UserID(1), Language: "en"}
+		jdata, err := json.Marshal(quiz)
+		require.NoError(t, err)
+		mock.ExpectZRevRange("user_quizzes:1", 0, 1).SetVal([]string{"test", "expired"})
+		mock.ExpectMGet("quiz:test", "quiz:expired").SetVal([]interface{}{string(jdata), nil})
+
+		res, err := storage.GetUserQuizzes(UserID(1), 2)
+		assert.NoError(t, err)
+		assert.Equal(t, []Quiz{quiz}, res)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("empty", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectZRevRange("user_quizzes:1", 0, 9).SetVal([]string{})
+
+		res, err := storage.GetUserQuizzes(UserID(1), 10)
+		assert.NoError(t, err)
+		assert.Empty(t, res)
+	})
+	t.Run("error", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectZRevRange("user_quizzes:1", 0, 9).SetErr(errors.New("FAIL"))
+
+		_, err := storage.GetUserQuizzes(UserID(1), 10)
+		assert.Error(t, err)
+	})
+}
+
+func TestRedisGroup(t *testing.T) {
+	group := Group{ID: ChatID(-1), Title: "test", Words: []string{"cat"}}
+	jdata, err := json.Marshal(group)
+	require.NoError(t, err)
+	t.Run("get", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectGet("group:-1").SetVal(string(jdata))
+		res, err := storage.GetGroup(ChatID(-1))
+		assert.NoError(t, err)
+		assert.Equal(t, group, res)
+	})
+	t.Run("not found", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectGet("group:-1").RedisNil()
+		_, err := storage.GetGroup(ChatID(-1))
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+	t.Run("save", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectSet("group:-1", string(jdata), 0).SetVal("OK")
+		assert.NoError(t, storage.SaveGroup(group))
+	})
+	t.Run("save error", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectSet("group:-1", string(jdata), 0).SetErr(errors.New("FAIL"))
+		assert.Error(t, storage.SaveGroup(group))
+	})
+}
+
+func TestRedisGroupQuiz(t *testing.T) {
+	quiz := GroupQuiz{ID: "test", Chat: ChatID(-1), Answers: map[UserID]int{1: 2}}
+	jdata, err := json.Marshal(quiz)
+	require.NoError(t, err)
+	t.Run("get", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectGet("group_quiz:test").SetVal(string(jdata))
+		res, err := storage.GetGroupQuiz("test")
+		assert.NoError(t, err)
+		assert.Equal(t, quiz, res)
+	})
+	t.Run("not found", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectGet("group_quiz:test").RedisNil()
+		_, err := storage.GetGroupQuiz("test")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+	t.Run("invalid JSON", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectGet("group_quiz:test").SetVal("INVALID_JSON")
+		_, err := storage.GetGroupQuiz("test")
+		assert.Error(t, err)
+	})
+	t.Run("save", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectSet("group_quiz:test", string(jdata), 0).SetVal("OK")
+		assert.NoError(t, storage.SaveGroupQuiz(quiz))
+	})
+}
+
+func TestRedisGroupScores(t *testing.T) {
+	score := GroupScore{Chat: ChatID(-1), User: UserID(1), Username: "test", Week: "2023-W19", Points: 2}
+	jdata, err := json.Marshal(score)
+	require.NoError(t, err)
+	t.Run("get", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectHGetAll("group_score:-1:2023-W19").SetVal(map[string]string{"1": string(jdata)})
+		res, err := storage.GetGroupScores(ChatID(-1), "2023-W19")
+		assert.NoError(t, err)
+		assert.Equal(t, []GroupScore{score}, res)
+	})
+	t.Run("get error", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectHGetAll("group_score:-1:2023-W19").SetErr(errors.New("FAIL"))
+		_, err := storage.GetGroupScores(ChatID(-1), "2023-W19")
+		assert.Error(t, err)
+	})
+	t.Run("save", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectHSet("group_score:-1:2023-W19", "1", string(jdata)).SetVal(1)
+		assert.NoError(t, storage.SaveGroupScore(score))
+	})
+}
+
+func TestRedisRefreshSession(t *testing.T) {
+	expires := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	session := RefreshSession{ID: "test", User: UserID(1), TokenHash: "hash", Expires: expires}
+	jdata, err := json.Marshal(session)
+	require.NoError(t, err)
+	t.Run("get", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectGet("refresh_session:test").SetVal(string(jdata))
+		res, err := storage.GetRefreshSession("test")
+		assert.NoError(t, err)
+		assert.Equal(t, session, res)
+	})
+	t.Run("not found", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectGet("refresh_session:test").RedisNil()
+		_, err := storage.GetRefreshSession("test")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+	t.Run("save", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectTxPipeline()
+		mock.ExpectSet("refresh_session:test", string(jdata), 0).SetVal("OK")
+		mock.ExpectExpireAt("refresh_session:test", expires).SetVal(true)
+		mock.ExpectTxPipelineExec()
+		assert.NoError(t, storage.SaveRefreshSession(session))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("save error", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectTxPipeline()
+		mock.ExpectSet("refresh_session:test", string(jdata), 0).SetErr(errors.New("FAIL"))
+		mock.ExpectExpireAt("refresh_session:test", expires).SetVal(true)
+		mock.ExpectTxPipelineExec()
+		assert.Error(t, storage.SaveRefreshSession(session))
+	})
+}
+
+func TestRedisRevokedTokens(t *testing.T) {
+	expires := time.Now().Add(time.Hour)
+	t.Run("revoke", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectTxPipeline()
+		mock.ExpectSet("revoked_token:test", "1", 0).SetVal("OK")
+		mock.ExpectExpireAt("revoked_token:test", expires).SetVal(true)
+		mock.ExpectTxPipelineExec()
+		assert.NoError(t, storage.RevokeToken("test", expires))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("revoked", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectExists("revoked_token:test").SetVal(1)
+		revoked, err := storage.IsTokenRevoked("test")
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+	t.Run("not revoked", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectExists("revoked_token:test").SetVal(0)
+		revoked, err := storage.IsTokenRevoked("test")
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+	})
+	t.Run("error", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectExists("revoked_token:test").SetErr(errors.New("FAIL"))
+		_, err := storage.IsTokenRevoked("test")
+		assert.Error(t, err)
+	})
+}
+
+func TestRedisPersonalTokens(t *testing.T) {
+	token := PersonalToken{ID: "abc", User: UserID(1), Name: "test", Scope: ScopeRead, Hash: "hash"}
+	jdata, err := json.Marshal(token)
+	require.NoError(t, err)
+	t.Run("get", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectGet("personal_token:abc").SetVal("1")
+		mock.ExpectHGet("personal_tokens:1", "abc").SetVal(string(jdata))
+		res, err := storage.GetPersonalToken("abc")
+		assert.NoError(t, err)
+		assert.Equal(t, token, res)
+	})
+	t.Run("not found", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectGet("personal_token:abc").RedisNil()
+		_, err := storage.GetPersonalToken("abc")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+	t.Run("list", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectHGetAll("personal_tokens:1").SetVal(map[string]string{"abc": string(jdata)})
+		res, err := storage.GetPersonalTokens(UserID(1))
+		assert.NoError(t, err)
+		assert.Equal(t, []PersonalToken{token}, res)
+	})
+	t.Run("save", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectTxPipeline()
+		mock.ExpectHSet("personal_tokens:1", "abc", string(jdata)).SetVal(1)
+		mock.ExpectSet("personal_token:abc", "1", 0).SetVal("OK")
+		mock.ExpectTxPipelineExec()
+		assert.NoError(t, storage.SavePersonalToken(token))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("delete", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectHDel("personal_tokens:1", "abc").SetVal(1)
+		mock.ExpectDel("personal_token:abc").SetVal(1)
+		assert.NoError(t, storage.DeletePersonalToken(UserID(1), "abc"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("delete not found", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectHDel("personal_tokens:2", "abc").SetVal(0)
+		assert.ErrorIs(t, storage.DeletePersonalToken(UserID(2), "abc"), ErrNotFound)
+	})
+}
+
+func TestRedisDeleteUserItem(t *testing.T) {
+	t.Run("delete", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectHDel("user_item:1", "test").SetVal(1)
+		assert.NoError(t, storage.DeleteUserItem(UserID(1), "Test"))
+	})
+	t.Run("not found", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectHDel("user_item:1", "test").SetVal(0)
+		assert.ErrorIs(t, storage.DeleteUserItem(UserID(1), "test"), ErrNotFound)
+	})
+	t.Run("error", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+		storage := RedisStorage{db: db}
+		mock.ExpectHDel("user_item:1", "test").SetErr(errors.New("FAIL"))
+		assert.Error(t, storage.DeleteUserItem(UserID(1), "test"))
+	})
+}
@@ -0,0 +1,161 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// WordRevision is a version of dictionary item kept in its edit history
+type WordRevision struct {
+	// Word is a normalized word of the item
+	Word string
+	// Rev is a number of the revision starting from 1
+	Rev int
+	// Author is an ID of the admin who made the revision, it's zero for data fetched from providers
+	Author  UserID
+	Created time.Time
+	// RevertedFrom is a number of the revision restored by this one
+	RevertedFrom int `json:",omitempty"`
+	// Diff lists changes made to the previous revision
+	Diff []string
+	Item DictionaryItem
+}
+
+// SaveWord saves dictionary item and appends it to the item edit history, author is zero for automatic updates.
+// Item saved before the history was kept becomes the first revision, so it can be restored too.
+// If the item isn't changed, only newer fetch details are saved keeping the editor and the latest revision is returned
+func SaveWord(s Storage, item DictionaryItem, author UserID, now time.Time) (WordRevision, error) {
+	return saveWord(s, item, author, now, 0)
+}
+
+// RevertWord restores dictionary item from the revision as a new revision by author,
+// ErrNotFound is returned for unknown revisions
+func RevertWord(s Storage, word string, rev int, author UserID, now time.Time) (WordRevision, error) {
+	revisions, err := s.GetWordRevisions(word)
+	if err != nil {
+		return WordRevision{}, fmt.Errorf("get revisions: %w", err)
+	}
+	for _, r := range revisions {
+		if r.Rev == rev {
+			return saveWord(s, r.Item, author, now, rev)
+		}
+	}
+	return WordRevision{}, ErrNotFound
+}
+
+func saveWord(s Storage, item DictionaryItem, author UserID, now time.Time, revertedFrom int) (WordRevision, error) {
+	word := NormalizeWord(item.Word)
+	revisions, err := s.GetWordRevisions(word)
+	if err != nil {
+		return WordRevision{}, fmt.Errorf("get revisions: %w", err)
+	}
+	prev, err := s.Get(word)
+	switch {
+	case errors.Is(err, ErrNotFound):
+	case err != nil:
+		return WordRevision{}, fmt.Errorf("get item: %w", err)
+	case len(revisions) == 0:
+		base := WordRevision{Word: word, Author: prev.EditedBy, Created: now, Item: prev}
+		if base.Rev, err = s.SaveWordRevision(base); err != nil {
+			return WordRevision{}, fmt.Errorf("save revision: %w", err)
+		}
+		revisions = append(revisions, base)
+	}
+	diff := DiffItems(prev, item)
+	if len(diff) == 0 && len(revisions) != 0 {
+		if item.FetchedAt != nil && (prev.FetchedAt == nil || item.FetchedAt.After(*prev.FetchedAt)) {
+			prev.FetchedAt, prev.Incomplete = item.FetchedAt, item.Incomplete
+			if err := s.Save(prev); err != nil {
+				return WordRevision{}, fmt.Errorf("save item: %w", err)
+			}
+		}
+		return revisions[len(revisions)-1], nil
+	}
+	item.EditedBy = author
+	// revision number is allocated by storage, so concurrent edits don't get the same one
+	rev := WordRevision{
+		Word:         word,
+		Author:       author,
+		Created:      now,
+		RevertedFrom: revertedFrom,
+		Diff:         diff,
+		Item:         item,
+	}
+	if rev.Rev, err = s.SaveWordRevision(rev); err != nil {
+		return WordRevision{}, fmt.Errorf("save revision: %w", err)
+	}
+	if err := s.Save(item); err != nil {
+		return WordRevision{}, fmt.Errorf("save item: %w", err)
+	}
+	return rev, nil
+}
+
+// DiffItems returns human readable list of changes between two versions of dictionary item:
+// added and removed translations and meanings prefixed with + and -, changed meanings prefixed with ~
+func DiffItems(prev, next DictionaryItem) []string {
+	var diff []string
+	if prev.Phonetics.Text != next.Phonetics.Text {
+		diff = append(diff, fmt.Sprintf("~ phonetics: %q → %q", prev.Phonetics.Text, next.Phonetics.Text))
+	}
+	if prev.Phonetics.Audio != next.Phonetics.Audio {
+		diff = append(diff, fmt.Sprintf("~ audio: %q → %q", prev.Phonetics.Audio, next.Phonetics.Audio))
+	}
+
+	translationKey := func(t Translation) string {
+		return strings.TrimSpace(fmt.Sprintf("translation (%s): %s %s", t.Language, t.Text, t.PartOfSpeech))
+	}
+	prevTranslations := make(map[string]bool, len(prev.Translations))
+	for _, t := range prev.Translations {
+		prevTranslations[translationKey(t)] = true
+	}
+	nextTranslations := make(map[string]bool, len(next.Translations))
+	for _, t := range next.Translations {
+		nextTranslations[translationKey(t)] = true
+		if !prevTranslations[translationKey(t)] {
+			diff = append(diff, "+ "+translationKey(t))
+		}
+	}
+	for _, t := range prev.Translations {
+		if !nextTranslations[translationKey(t)] {
+			diff = append(diff, "- "+translationKey(t))
+		}
+	}
+
+	meaningKey := func(m Meaning) string {
+		return fmt.Sprintf("meaning (%s): %s", m.PartOfSpeech, m.Definition)
+	}
+	prevMeanings := make(map[string]Meaning, len(prev.Meanings))
+	for _, m := range prev.Meanings {
+		prevMeanings[meaningKey(m)] = m
+	}
+	nextMeanings := make(map[string]bool, len(next.Meanings))
+	for _, m := range next.Meanings {
+		nextMeanings[meaningKey(m)] = true
+		old, ok := prevMeanings[meaningKey(m)]
+		switch {
+		case !ok:
+			diff = append(diff, "+ "+meaningKey(m))
+		case !reflect.DeepEqual(normalizeLists(old), normalizeLists(m)):
+			diff = append(diff, "~ "+meaningKey(m))
+		}
+	}
+	for _, m := range prev.Meanings {
+		if !nextMeanings[meaningKey(m)] {
+			diff = append(diff, "- "+meaningKey(m))
+		}
+	}
+	return diff
+}
+
+// normalizeLists returns meaning with nil instead of empty lists, so both are compared as equal
+func normalizeLists(m Meaning) Meaning {
+	for _, list := range []*[]string{&m.Examples, &m.Synonyms, &m.Antonyms} {
+		if len(*list) == 0 {
+			*list = nil
+		}
+	}
+	return m
+}
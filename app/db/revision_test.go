@@ -0,0 +1,157 @@
+package db
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveWord(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	original := DictionaryItem{Word: "test", Translations: []Translation{{Text: "тест", Language: "ru"}}}
+	edited := DictionaryItem{
+		Word:         "test",
+		Translations: []Translation{{Text: "проверка", Language: "ru"}},
+		Meanings:     []Meaning{{PartOfSpeech: "noun", Definition: "a procedure"}},
+	}
+
+	t.Run("existing item", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		require.NoError(t, storage.Save(original))
+
+		rev, err := SaveWord(storage, edited, UserID(1), now)
+		require.NoError(t, err)
+		assert.Equal(t, 2, rev.Rev)
+		assert.Equal(t, UserID(1), rev.Author)
+		assert.Equal(t, []string{
+			"+ translation (ru): проверка",
+			"- translation (ru): тест",
+			"+ meaning (noun): a procedure",
+		}, rev.Diff)
+
+		saved, err := storage.Get("test")
+		require.NoError(t, err)
+		assert.Equal(t, UserID(1), saved.EditedBy)
+		revisions, err := storage.GetWordRevisions("test")
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, WordRevision{Word: "test", Rev: 1, Created: now, Item: original}, revisions[0])
+		assert.Equal(t, rev, revisions[1])
+	})
+	t.Run("new item", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		rev, err := SaveWord(storage, original, 0, now)
+		require.NoError(t, err)
+		assert.Equal(t, 1, rev.Rev)
+		assert.Equal(t, []string{"+ translation (ru): тест"}, rev.Diff)
+	})
+	t.Run("newer fetch details", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		edited := original
+		edited.EditedBy = UserID(1)
+		require.NoError(t, storage.Save(edited))
+		fetched := now.Add(time.Hour)
+		refreshed := original
+		refreshed.FetchedAt = &fetched
+		refreshed.Incomplete = []string{"yandex"}
+
+		_, err := SaveWord(storage, refreshed, 0, fetched)
+		require.NoError(t, err)
+		saved, err := storage.Get("test")
+		require.NoError(t, err)
+		assert.Equal(t, UserID(1), saved.EditedBy)
+		assert.Equal(t, fetched, *saved.FetchedAt)
+		assert.Equal(t, []string{"yandex"}, saved.Incomplete)
+
+		// admin edits without changes don't reset fetch details
+		_, err = SaveWord(storage, original, UserID(2), now)
+		require.NoError(t, err)
+		saved, err = storage.Get("test")
+		require.NoError(t, err)
+		assert.Equal(t, UserID(1), saved.EditedBy)
+		assert.Equal(t, fetched, *saved.FetchedAt)
+		revisions, err := storage.GetWordRevisions("test")
+		require.NoError(t, err)
+		assert.Len(t, revisions, 1)
+	})
+	t.Run("not changed", func(t *testing.T) {
+		storage := NewInMemoryStorage()
+		first, err := SaveWord(storage, original, UserID(1), now)
+		require.NoError(t, err)
+		rev, err := SaveWord(storage, original, UserID(2), now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, first, rev)
+		revisions, err := storage.GetWordRevisions("test")
+		require.NoError(t, err)
+		assert.Len(t, revisions, 1)
+	})
+}
+
+func TestSaveWordConcurrent(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	storage := NewInMemoryStorage()
+	_, err := SaveWord(storage, DictionaryItem{Word: "test"}, UserID(1), now)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			item := DictionaryItem{Word: "test", Translations: []Translation{{Text: fmt.Sprintf("тест %d", i), Language: "ru"}}}
+			_, err := SaveWord(storage, item, UserID(1), now)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	revisions, err := storage.GetWordRevisions("test")
+	require.NoError(t, err)
+	require.Len(t, revisions, 11)
+	for i, rev := range revisions {
+		assert.Equal(t, i+1, rev.Rev)
+	}
+}
+
+func TestRevertWord(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	storage := NewInMemoryStorage()
+	original := DictionaryItem{Word: "test", Translations: []Translation{{Text: "тест", Language: "ru"}}}
+	require.NoError(t, storage.Save(original))
+	_, err := SaveWord(storage, DictionaryItem{Word: "test"}, UserID(1), now)
+	require.NoError(t, err)
+
+	rev, err := RevertWord(storage, "test", 1, UserID(2), now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 3, rev.Rev)
+	assert.Equal(t, 1, rev.RevertedFrom)
+	assert.Equal(t, UserID(2), rev.Author)
+	assert.Equal(t, []string{"+ translation (ru): тест"}, rev.Diff)
+	saved, err := storage.Get("test")
+	require.NoError(t, err)
+	assert.Equal(t, original.Translations, saved.Translations)
+	assert.Equal(t, UserID(2), saved.EditedBy)
+
+	_, err = RevertWord(storage, "test", 10, UserID(2), now)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = RevertWord(storage, "unknown", 1, UserID(2), now)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDiffItems(t *testing.T) {
+	prev := DictionaryItem{Word: "test", Meanings: []Meaning{
+		{PartOfSpeech: "noun", Definition: "a procedure", Examples: []string{}},
+		{PartOfSpeech: "verb", Definition: "to check", Examples: []string{"test it"}},
+	}}
+	next := DictionaryItem{Word: "test", Meanings: []Meaning{
+		{PartOfSpeech: "noun", Definition: "a procedure"},
+		{PartOfSpeech: "verb", Definition: "to check", Examples: []string{"test it again"}},
+	}}
+	next.Phonetics.Text = "/test/"
+	assert.Equal(t, []string{`~ phonetics: "" → "/test/"`, "~ meaning (verb): to check"}, DiffItems(prev, next))
+	assert.Empty(t, DiffItems(prev, prev))
+}
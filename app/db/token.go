@@ -0,0 +1,176 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// RefreshSession is a chain of API refresh tokens issued by rotation starting from a single login.
+// Only hash of the latest token is kept, so reuse of an already rotated token can be detected
+type RefreshSession struct {
+	ID        string
+	User      UserID
+	TokenHash string
+	// AccessTokenID is an ID of the latest access token issued for the session,
+	// it is revoked together with the session
+	AccessTokenID string
+	AccessExpires time.Time
+	Created       time.Time
+	Expires       time.Time
+	Revoked       bool
+}
+
+// IsActive returns true if session is neither revoked nor expired
+func (s RefreshSession) IsActive(now time.Time) bool {
+	return !s.Revoked && now.Before(s.Expires)
+}
+
+// PersonalTokenPrefix starts every personal access token
+const PersonalTokenPrefix = "tgd_"
+
+// PersonalTokensMax limits number of personal access tokens per user
+const PersonalTokensMax = 10
+
+// personalTokenNameMaxLen limits length of personal access token name
+const personalTokenNameMaxLen = 64
+
+// TokenScope defines what personal access token is allowed to do
+type TokenScope string
+
+// personal access token scopes
+const (
+	// ScopeRead allows only reading of user data
+	ScopeRead TokenScope = "read"
+	// ScopeWrite allows reading and changing of user data
+	ScopeWrite TokenScope = "write"
+)
+
+// personal access token errors
+var (
+	ErrInvalidScope     = errors.New("invalid scope")
+	ErrInvalidTokenName = errors.New("invalid token name")
+	ErrTooManyTokens    = errors.New("too many tokens")
+)
+
+// ParseTokenScope returns scope by its name
+func ParseTokenScope(scope string) (TokenScope, error) {
+	switch s := TokenScope(strings.ToLower(strings.TrimSpace(scope))); s {
+	case ScopeRead, ScopeWrite:
+		return s, nil
+	}
+	return "", ErrInvalidScope
+}
+
+// PersonalToken is a long living API token created by user for scripting,
+// only hash of the token is stored so it's shown to user only once
+type PersonalToken struct {
+	ID      string
+	User    UserID
+	Name    string
+	Scope   TokenScope
+	Hash    string
+	Created time.Time
+}
+
+// NewPersonalToken creates personal access token, returns it together with
+// the token string in tgd_<id>_<secret> form
+func NewPersonalToken(user UserID, name string, scope TokenScope) (PersonalToken, string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" || utf8.RuneCountInString(name) > personalTokenNameMaxLen {
+		return PersonalToken{}, "", ErrInvalidTokenName
+	}
+	if _, err := ParseTokenScope(string(scope)); err != nil {
+		return PersonalToken{}, "", err
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return PersonalToken{}, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return PersonalToken{}, "", err
+	}
+	raw := PersonalTokenPrefix + id + "_" + secret
+	token := PersonalToken{
+		ID:      id,
+		User:    user,
+		Name:    name,
+		Scope:   scope,
+		Hash:    hashToken(raw),
+		Created: time.Now().UTC(),
+	}
+	return token, raw, nil
+}
+
+// CreatePersonalToken creates and saves personal access token of the user,
+// returns it together with the token string which can't be restored later
+func CreatePersonalToken(s Storage, user UserID, name string, scope TokenScope) (PersonalToken, string, error) {
+	tokens, err := s.GetPersonalTokens(user)
+	if err != nil {
+		return PersonalToken{}, "", fmt.Errorf("get tokens: %w", err)
+	}
+	if len(tokens) >= PersonalTokensMax {
+		return PersonalToken{}, "", ErrTooManyTokens
+	}
+	token, raw, err := NewPersonalToken(user, name, scope)
+	if err != nil {
+		return PersonalToken{}, "", err
+	}
+	if err := s.SavePersonalToken(token); err != nil {
+		return PersonalToken{}, "", fmt.Errorf("save token: %w", err)
+	}
+	return token, raw, nil
+}
+
+// ParsePersonalToken returns ID of personal access token string, false is returned for other strings
+func ParsePersonalToken(raw string) (string, bool) {
+	rest, ok := strings.CutPrefix(raw, PersonalTokenPrefix)
+	if !ok {
+		return "", false
+	}
+	id, secret, ok := strings.Cut(rest, "_")
+	return id, ok && id != "" && secret != ""
+}
+
+// Check returns true if the token string matches token hash
+func (t PersonalToken) Check(raw string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashToken(raw)), []byte(t.Hash)) == 1
+}
+
+// IsWritable returns true if token allows to change user data
+func (t PersonalToken) IsWritable() bool {
+	return t.Scope == ScopeWrite
+}
+
+// sortPersonalTokens sorts tokens by creation time
+func sortPersonalTokens(tokens []PersonalToken) {
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].Created.Equal(tokens[j].Created) {
+			return tokens[i].Created.Before(tokens[j].Created)
+		}
+		return tokens[i].ID < tokens[j].ID
+	})
+}
+
+// hashToken returns hex encoded SHA-256 hash of the token
+func hashToken(raw string) string {
+	hash := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(hash[:])
+}
+
+// randomHex returns n random bytes encoded to hex
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
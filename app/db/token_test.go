@@ -0,0 +1,96 @@
+package db
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshSessionIsActive(t *testing.T) {
+	now := time.Now()
+	assert.True(t, RefreshSession{Expires: now.Add(time.Hour)}.IsActive(now))
+	assert.False(t, RefreshSession{Expires: now.Add(time.Hour), Revoked: true}.IsActive(now))
+	assert.False(t, RefreshSession{Expires: now.Add(-time.Hour)}.IsActive(now))
+}
+
+func TestNewPersonalToken(t *testing.T) {
+	token, raw, err := NewPersonalToken(UserID(1), "  my   script ", ScopeRead)
+	require.NoError(t, err)
+	assert.Equal(t, UserID(1), token.User)
+	assert.Equal(t, "my script", token.Name)
+	assert.Equal(t, ScopeRead, token.Scope)
+	assert.False(t, token.IsWritable())
+	assert.True(t, strings.HasPrefix(raw, "tgd_"+token.ID+"_"))
+	assert.NotContains(t, token.Hash, raw)
+	assert.True(t, token.Check(raw))
+	assert.False(t, token.Check(raw+"1"))
+
+	id, ok := ParsePersonalToken(raw)
+	assert.True(t, ok)
+	assert.Equal(t, token.ID, id)
+
+	other, otherRaw, err := NewPersonalToken(UserID(1), "other", ScopeWrite)
+	require.NoError(t, err)
+	assert.NotEqual(t, token.ID, other.ID)
+	assert.NotEqual(t, raw, otherRaw)
+	assert.True(t, other.IsWritable())
+
+	_, _, err = NewPersonalToken(UserID(1), " ", ScopeRead)
+	assert.ErrorIs(t, err, ErrInvalidTokenName)
+	_, _, err = NewPersonalToken(UserID(1), strings.Repeat("a", 65), ScopeRead)
+	assert.ErrorIs(t, err, ErrInvalidTokenName)
+	_, _, err = NewPersonalToken(UserID(1), "test", TokenScope("admin"))
+	assert.ErrorIs(t, err, ErrInvalidScope)
+}
+
+func TestParsePersonalToken(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"tgd_abc_secret": "abc",
+		"tgd_abc_":       "",
+		"tgd__secret":    "",
+		"tgd_abc":        "",
+		"abc_secret":     "",
+		"eyJhbGciOi":     "",
+	} {
+		id, ok := ParsePersonalToken(raw)
+		assert.Equal(t, expected != "", ok, raw)
+		if ok {
+			assert.Equal(t, expected, id, raw)
+		}
+	}
+}
+
+func TestParseTokenScope(t *testing.T) {
+	scope, err := ParseTokenScope(" Read")
+	assert.NoError(t, err)
+	assert.Equal(t, ScopeRead, scope)
+	scope, err = ParseTokenScope("write")
+	assert.NoError(t, err)
+	assert.Equal(t, ScopeWrite, scope)
+	_, err = ParseTokenScope("admin")
+	assert.ErrorIs(t, err, ErrInvalidScope)
+}
+
+func TestCreatePersonalToken(t *testing.T) {
+	storage := NewInMemoryStorage()
+	token, raw, err := CreatePersonalToken(storage, UserID(1), "test", ScopeWrite)
+	require.NoError(t, err)
+	saved, err := storage.GetPersonalToken(token.ID)
+	require.NoError(t, err)
+	assert.Equal(t, token, saved)
+	assert.True(t, saved.Check(raw))
+
+	_, _, err = CreatePersonalToken(storage, UserID(1), "", ScopeWrite)
+	assert.ErrorIs(t, err, ErrInvalidTokenName)
+	for i := 1; i < PersonalTokensMax; i++ {
+		_, _, err = CreatePersonalToken(storage, UserID(1), "test", ScopeRead)
+		require.NoError(t, err)
+	}
+	_, _, err = CreatePersonalToken(storage, UserID(1), "test", ScopeRead)
+	assert.ErrorIs(t, err, ErrTooManyTokens)
+	_, _, err = CreatePersonalToken(storage, UserID(2), "test", ScopeRead)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// user management errors
+var (
+	ErrSelfUpdate   = errors.New("admin can't change own role or block themselves")
+	ErrAdminBlocked = errors.New("admin can't be blocked")
+)
+
+// lastSeenPrecision limits how often user last seen time is saved
+const lastSeenPrecision = time.Hour
+
+// UserActivity holds statistics of user dictionary and quizzes
+type UserActivity struct {
+	Words    int
+	Mastered int
+	// Answers is a number of answered quizzes over all words of the dictionary
+	Answers  int
+	LastQuiz *time.Time
+}
+
+// Touch updates user last seen time, it returns false if the saved time is recent enough
+// and the user doesn't have to be saved
+func (u *User) Touch(now time.Time) bool {
+	if u.LastSeen != nil && now.Sub(*u.LastSeen) < lastSeenPrecision {
+		return false
+	}
+	now = now.UTC()
+	u.LastSeen = &now
+	return true
+}
+
+// updateUser changes user by admin, admins can't change themselves so they don't lose access by accident
+func updateUser(s Storage, admin, id UserID, update func(*User) error) (User, error) {
+	if admin == id {
+		return User{}, ErrSelfUpdate
+	}
+	user, err := s.GetUser(id)
+	if err != nil {
+		return User{}, err
+	}
+	if err := update(&user); err != nil {
+		return User{}, err
+	}
+	if err := s.SaveUser(user); err != nil {
+		return User{}, fmt.Errorf("save user: %w", err)
+	}
+	return user, nil
+}
+
+// UserAccess is a change of user role and access, nil fields are kept as is
+type UserAccess struct {
+	IsAdmin *bool
+	Blocked *bool
+}
+
+// UpdateUserAccess applies role and access changes by the admin at once,
+// the whole change is rejected if the user would end up as a blocked admin
+func UpdateUserAccess(s Storage, admin, id UserID, access UserAccess) (User, error) {
+	return updateUser(s, admin, id, func(u *User) error {
+		isAdmin := u.IsAdmin
+		if access.IsAdmin != nil {
+			isAdmin = *access.IsAdmin
+		}
+		if access.Blocked != nil && *access.Blocked && isAdmin {
+			return ErrAdminBlocked
+		}
+		u.IsAdmin = isAdmin
+		if access.Blocked != nil {
+			u.Blocked = *access.Blocked
+		}
+		return nil
+	})
+}
+
+// SetUserAdmin grants or revokes admin role of the user by the admin
+func SetUserAdmin(s Storage, admin, id UserID, isAdmin bool) (User, error) {
+	return UpdateUserAccess(s, admin, id, UserAccess{IsAdmin: &isAdmin})
+}
+
+// SetUserBlocked blocks or unblocks the user by the admin, admin role should be revoked before blocking
+func SetUserBlocked(s Storage, admin, id UserID, blocked bool) (User, error) {
+	return UpdateUserAccess(s, admin, id, UserAccess{Blocked: &blocked})
+}
+
+// GetUserActivity returns dictionary size and quiz activity of the user,
+// words are counted as mastered with threshold picked by the user
+func GetUserActivity(s Storage, user User) (UserActivity, error) {
+	var res UserActivity
+	threshold := user.Config.EffectiveMasteryThreshold()
+	err := s.IterateUserDictionary(user.ID, func(item UserDictionaryItem, _ DictionaryItem) error {
+		res.Words++
+		if item.IsMastered(threshold) {
+			res.Mastered++
+		}
+		res.Answers += item.TotalStats().Total
+		if item.LastQuiz != nil && (res.LastQuiz == nil || item.LastQuiz.After(*res.LastQuiz)) {
+			lastQuiz := *item.LastQuiz
+			res.LastQuiz = &lastQuiz
+		}
+		return nil
+	})
+	if err != nil {
+		return UserActivity{}, fmt.Errorf("iterate user dictionary: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserTouch(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	var user User
+	assert.True(t, user.Touch(now))
+	assert.Equal(t, now, *user.LastSeen)
+	assert.False(t, user.Touch(now.Add(time.Minute)))
+	assert.Equal(t, now, *user.LastSeen)
+	assert.True(t, user.Touch(now.Add(lastSeenPrecision)))
+	assert.Equal(t, now.Add(lastSeenPrecision), *user.LastSeen)
+}
+
+func TestSetUserAdmin(t *testing.T) {
+	storage := NewInMemoryStorage()
+	require.NoError(t, storage.SaveUser(User{ID: UserID(1), IsAdmin: true}))
+	require.NoError(t, storage.SaveUser(User{ID: UserID(2), Username: "test"}))
+
+	user, err := SetUserAdmin(storage, UserID(1), UserID(2), true)
+	require.NoError(t, err)
+	assert.Equal(t, User{ID: UserID(2), Username: "test", IsAdmin: true}, user)
+	saved, err := storage.GetUser(UserID(2))
+	require.NoError(t, err)
+	assert.True(t, saved.IsAdmin)
+
+	_, err = SetUserAdmin(storage, UserID(1), UserID(2), false)
+	require.NoError(t, err)
+	saved, err = storage.GetUser(UserID(2))
+	require.NoError(t, err)
+	assert.False(t, saved.IsAdmin)
+
+	_, err = SetUserAdmin(storage, UserID(1), UserID(1), false)
+	assert.ErrorIs(t, err, ErrSelfUpdate)
+	_, err = SetUserAdmin(storage, UserID(1), UserID(3), true)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestSetUserBlocked(t *testing.T) {
+	storage := NewInMemoryStorage()
+	require.NoError(t, storage.SaveUser(User{ID: UserID(1), IsAdmin: true}))
+	require.NoError(t, storage.SaveUser(User{ID: UserID(2)}))
+	require.NoError(t, storage.SaveUser(User{ID: UserID(3), IsAdmin: true}))
+
+	user, err := SetUserBlocked(storage, UserID(1), UserID(2), true)
+	require.NoError(t, err)
+	assert.True(t, user.Blocked)
+	user, err = SetUserBlocked(storage, UserID(1), UserID(2), false)
+	require.NoError(t, err)
+	assert.False(t, user.Blocked)
+
+	_, err = SetUserBlocked(storage, UserID(1), UserID(3), true)
+	assert.ErrorIs(t, err, ErrAdminBlocked)
+	_, err = SetUserBlocked(storage, UserID(1), UserID(1), true)
+	assert.ErrorIs(t, err, ErrSelfUpdate)
+	saved, err := storage.GetUser(UserID(3))
+	require.NoError(t, err)
+	assert.False(t, saved.Blocked)
+}
+
+func TestUpdateUserAccess(t *testing.T) {
+	storage := NewInMemoryStorage()
+	require.NoError(t, storage.SaveUser(User{ID: UserID(1), IsAdmin: true}))
+	require.NoError(t, storage.SaveUser(User{ID: UserID(2)}))
+	yes, no := true, false
+
+	_, err := UpdateUserAccess(storage, UserID(1), UserID(2), UserAccess{IsAdmin: &yes, Blocked: &yes})
+	assert.ErrorIs(t, err, ErrAdminBlocked)
+	saved, err := storage.GetUser(UserID(2))
+	require.NoError(t, err)
+	assert.Equal(t, User{ID: UserID(2)}, saved)
+
+	_, err = UpdateUserAccess(storage, UserID(1), UserID(2), UserAccess{IsAdmin: &yes})
+	require.NoError(t, err)
+	user, err := UpdateUserAccess(storage, UserID(1), UserID(2), UserAccess{IsAdmin: &no, Blocked: &yes})
+	require.NoError(t, err)
+	assert.Equal(t, User{ID: UserID(2), Blocked: true}, user)
+	saved, err = storage.GetUser(UserID(2))
+	require.NoError(t, err)
+	assert.Equal(t, user, saved)
+
+	user, err = UpdateUserAccess(storage, UserID(1), UserID(2), UserAccess{})
+	require.NoError(t, err)
+	assert.Equal(t, saved, user)
+}
+
+func TestGetUserActivity(t *testing.T) {
+	storage := NewInMemoryStorage()
+	user := User{ID: UserID(1), Config: UserConfig{MasteryThreshold: 2}}
+	lastQuiz := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	mastered := UserDictionaryItem{User: user.ID, Word: "cat", LastQuiz: &lastQuiz}
+	mastered.addQuizResult(QuizTypeTranslations, true)
+	mastered.addQuizResult(QuizTypeTranslations, true)
+	earlier := lastQuiz.Add(-time.Hour)
+	learning := UserDictionaryItem{User: user.ID, Word: "dog", LastQuiz: &earlier}
+	learning.addQuizResult(QuizTypeTranslations, false)
+	require.NoError(t, storage.SaveUserItem(mastered))
+	require.NoError(t, storage.SaveUserItem(learning))
+	require.NoError(t, storage.SaveUserItem(UserDictionaryItem{User: user.ID, Word: "cow"}))
+	require.NoError(t, storage.SaveUserItem(UserDictionaryItem{User: UserID(2), Word: "car"}))
+
+	activity, err := GetUserActivity(storage, user)
+	require.NoError(t, err)
+	assert.Equal(t, UserActivity{Words: 3, Mastered: 1, Answers: 3, LastQuiz: &lastQuiz}, activity)
+
+	activity, err = GetUserActivity(storage, User{ID: UserID(3)})
+	require.NoError(t, err)
+	assert.Equal(t, UserActivity{}, activity)
+}
@@ -0,0 +1,210 @@
+// Package export writes user dictionary in formats suitable for other tools
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+)
+
+// Format is an export file format
+type Format string
+
+// supported formats
+const (
+	FormatCSV  Format = "csv"
+	FormatTSV  Format = "tsv"
+	FormatJSON Format = "json"
+)
+
+// Formats lists all supported formats
+var Formats = []Format{FormatCSV, FormatTSV, FormatJSON}
+
+// ErrUnknownFormat is returned for unsupported formats
+var ErrUnknownFormat = errors.New("unknown format")
+
+// AnkiTag is added to all notes exported to Anki, so they are recognized on import
+const AnkiTag = "tg-dictionary"
+
+// ParseFormat returns format by its name, CSV is used by default
+func ParseFormat(name string) (Format, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return FormatCSV, nil
+	}
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	return "", ErrUnknownFormat
+}
+
+// ContentType returns MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatTSV:
+		return "text/tab-separated-values; charset=utf-8"
+	case FormatJSON:
+		return "application/json"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// FileName returns name of exported file
+func (f Format) FileName() string {
+	return "dictionary." + string(f)
+}
+
+// Meaning is an exported word meaning
+type Meaning struct {
+	PartOfSpeech string   `json:"part_of_speech"`
+	Definition   string   `json:"definition"`
+	Examples     []string `json:"examples,omitempty"`
+}
+
+// Entry is an exported user dictionary item
+type Entry struct {
+	Word         string     `json:"word"`
+	Phonetics    string     `json:"phonetics,omitempty"`
+	Translations []string   `json:"translations"`
+	Meanings     []Meaning  `json:"meanings"`
+	Added        time.Time  `json:"added"`
+	LastQuiz     *time.Time `json:"last_quiz,omitempty"`
+	Quizzes      int        `json:"quizzes"`
+	Correct      int        `json:"correct"`
+	Tags         []string   `json:"tags,omitempty"`
+}
+
+// Entries converts user dictionary to entries sorted by word
+func Entries(dictionary map[db.UserDictionaryItem]db.DictionaryItem) []Entry {
+	entries := make([]Entry, 0, len(dictionary))
+	for userItem, item := range dictionary {
+		stats := userItem.TotalStats()
+		entry := Entry{
+			Word:         userItem.Word,
+			Phonetics:    item.Phonetics.Text,
+			Translations: make([]string, 0, len(item.Translations)),
+			Meanings:     make([]Meaning, 0, len(item.Meanings)),
+			Added:        userItem.Created,
+			LastQuiz:     userItem.LastQuiz,
+			Quizzes:      stats.Total,
+			Correct:      stats.Correct,
+			Tags:         userItem.Tags(),
+		}
+		for _, t := range item.Translations {
+			entry.Translations = append(entry.Translations, t.Text)
+		}
+		for _, m := range item.Meanings {
+			entry.Meanings = append(entry.Meanings, Meaning{
+				PartOfSpeech: m.PartOfSpeech,
+				Definition:   m.Definition,
+				Examples:     m.Examples,
+			})
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Word < entries[j].Word })
+	return entries
+}
+
+// Write writes user dictionary in the format
+func Write(w io.Writer, format Format, dictionary map[db.UserDictionaryItem]db.DictionaryItem) error {
+	entries := Entries(dictionary)
+	switch format {
+	case FormatCSV:
+		return writeCSV(w, entries)
+	case FormatTSV:
+		return writeTSV(w, entries)
+	case FormatJSON:
+		if err := json.NewEncoder(w).Encode(entries); err != nil {
+			return fmt.Errorf("encode json: %w", err)
+		}
+		return nil
+	}
+	return ErrUnknownFormat
+}
+
+// writeCSV writes entries as CSV with a header, meanings are separated by new lines
+func writeCSV(w io.Writer, entries []Entry) error {
+	writer := csv.NewWriter(w)
+	header := []string{"word", "phonetics", "translations", "meanings", "examples", "added", "quizzes", "correct"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	for _, e := range entries {
+		var meanings, examples []string
+		for _, m := range e.Meanings {
+			meanings = append(meanings, fmt.Sprintf("(%v) %v", m.PartOfSpeech, m.Definition))
+			examples = append(examples, m.Examples...)
+		}
+		record := []string{
+			e.Word,
+			e.Phonetics,
+			strings.Join(e.Translations, "; "),
+			strings.Join(meanings, "\n"),
+			strings.Join(examples, "\n"),
+			e.Added.Format(time.RFC3339),
+			strconv.Itoa(e.Quizzes),
+			strconv.Itoa(e.Correct),
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("write record: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("flush csv: %w", err)
+	}
+	return nil
+}
+
+// writeTSV writes entries as Anki text import file with Front, Back and Tags columns,
+// fields are HTML and can't contain tabs or new lines
+func writeTSV(w io.Writer, entries []Entry) error {
+	if _, err := io.WriteString(w, "#separator:tab\n#html:true\n#tags column:3\n"); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	for _, e := range entries {
+		front := ankiField(e.Word)
+		if e.Phonetics != "" {
+			front += "<br>" + ankiField(e.Phonetics)
+		}
+		var back []string
+		if len(e.Translations) != 0 {
+			back = append(back, "<b>"+ankiField(strings.Join(e.Translations, ", "))+"</b>")
+		}
+		for _, m := range e.Meanings {
+			meaning := fmt.Sprintf("<i>%v</i> %v", ankiField(m.PartOfSpeech), ankiField(m.Definition))
+			for _, ex := range m.Examples {
+				meaning += "<br>— " + ankiField(ex)
+			}
+			back = append(back, meaning)
+		}
+		if e.Quizzes != 0 {
+			back = append(back, fmt.Sprintf("<small>Quizzes: %d/%d</small>", e.Correct, e.Quizzes))
+		}
+		tags := append([]string{AnkiTag}, e.Tags...)
+		line := fmt.Sprintf("%v\t%v\t%v\n", front, strings.Join(back, "<br><br>"), strings.Join(tags, " "))
+		if _, err := io.WriteString(w, line); err != nil {
+			return fmt.Errorf("write record: %w", err)
+		}
+	}
+	return nil
+}
+
+// ankiField escapes text for Anki HTML field
+func ankiField(text string) string {
+	text = strings.NewReplacer("\t", " ", "\r", "", "\n", " ").Replace(text)
+	return html.EscapeString(text)
+}
@@ -0,0 +1,110 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getDictionary(t *testing.T) map[db.UserDictionaryItem]db.DictionaryItem {
+	storage := db.NewInMemoryStorage()
+	created := time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC)
+	var item db.DictionaryItem
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"Word": "test",
+		"Phonetics": {"Text": "/test/"},
+		"Meanings": [
+			{"PartOfSpeech": "noun", "Definition": "A challenge\tor trial", "Examples": ["a <hard> test"]},
+			{"PartOfSpeech": "verb", "Definition": "To check"}
+		],
+		"Translations": [{"Text": "тест", "Language": "ru"}, {"Text": "проверка", "Language": "ru"}]
+	}`), &item))
+	require.NoError(t, storage.Save(item))
+	require.NoError(t, storage.Save(db.DictionaryItem{Word: "apple"}))
+	require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{
+		User:    1,
+		Word:    "test",
+		Created: created,
+		Stats: &db.UserItemStats{Quizzes: map[string]db.QuizStats{
+			db.QuizTypeTranslations: {Total: 3, Correct: 2},
+			db.QuizTypeMeanings:     {Total: 1, Correct: 1},
+		}},
+		Custom: &db.UserItemCustom{Tags: []string{"exam", "school"}},
+	}))
+	require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: 1, Word: "apple", Created: created}))
+	dict, err := storage.GetUserDictionary(1)
+	require.NoError(t, err)
+	return dict
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, expected := range map[string]Format{"": FormatCSV, "csv": FormatCSV, " TSV": FormatTSV, "json": FormatJSON} {
+		format, err := ParseFormat(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, format)
+	}
+	_, err := ParseFormat("xml")
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestEntries(t *testing.T) {
+	entries := Entries(getDictionary(t))
+	require.Len(t, entries, 2)
+	assert.Equal(t, "apple", entries[0].Word)
+	assert.Equal(t, Entry{
+		Word:         "test",
+		Phonetics:    "/test/",
+		Translations: []string{"тест", "проверка"},
+		Meanings: []Meaning{
+			{PartOfSpeech: "noun", Definition: "A challenge\tor trial", Examples: []string{"a <hard> test"}},
+			{PartOfSpeech: "verb", Definition: "To check"},
+		},
+		Added:   time.Date(2023, 5, 10, 12, 0, 0, 0, time.UTC),
+		Quizzes: 4,
+		Correct: 3,
+		Tags:    []string{"exam", "school"},
+	}, entries[1])
+}
+
+func TestWrite(t *testing.T) {
+	dict := getDictionary(t)
+	t.Run("csv", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, Write(buf, FormatCSV, dict))
+		records, err := csv.NewReader(buf).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"word", "phonetics", "translations", "meanings", "examples", "added", "quizzes", "correct"},
+			{"apple", "", "", "", "", "2023-05-10T12:00:00Z", "0", "0"},
+			{
+				"test", "/test/", "тест; проверка", "(noun) A challenge\tor trial\n(verb) To check", "a <hard> test",
+				"2023-05-10T12:00:00Z", "4", "3",
+			},
+		}, records)
+	})
+	t.Run("tsv", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, Write(buf, FormatTSV, dict))
+		expected := "#separator:tab\n#html:true\n#tags column:3\n" +
+			"apple\t\ttg-dictionary\n" +
+			"test<br>/test/\t<b>тест, проверка</b><br><br><i>noun</i> A challenge or trial<br>— a &lt;hard&gt; test" +
+			"<br><br><i>verb</i> To check<br><br><small>Quizzes: 3/4</small>\ttg-dictionary exam school\n"
+		assert.Equal(t, expected, buf.String())
+	})
+	t.Run("json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, Write(buf, FormatJSON, dict))
+		var entries []Entry
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entries))
+		assert.Equal(t, Entries(dict), entries)
+	})
+	t.Run("unknown format", func(t *testing.T) {
+		assert.ErrorIs(t, Write(&bytes.Buffer{}, Format("xml"), dict), ErrUnknownFormat)
+	})
+}
@@ -0,0 +1,182 @@
+// Package importer imports words from CSV/TSV files made by other tools to user dictionary
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/lookup"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Status is a result of a single record import
+type Status string
+
+// import statuses
+const (
+	// StatusAdded word is added to user dictionary
+	StatusAdded Status = "added"
+	// StatusUpdated user translations of existing word are set
+	StatusUpdated Status = "updated"
+	// StatusExists word is already in user dictionary and nothing is changed
+	StatusExists Status = "exists"
+	// StatusConflict user already has different translations for the word, they are kept
+	StatusConflict Status = "conflict"
+	// StatusUnknown word has no translations and providers don't know it
+	StatusUnknown Status = "unknown"
+	// StatusFailed word lookup failed
+	StatusFailed Status = "failed"
+	// StatusSkipped word isn't processed since import is cancelled or timed out
+	StatusSkipped Status = "skipped"
+)
+
+// Result is a result of a single record import
+type Result struct {
+	Word         string
+	Status       Status
+	Translations []string `json:",omitempty"`
+	// Existing holds current user translations for conflicts
+	Existing []string `json:",omitempty"`
+}
+
+// Report holds results of import
+type Report struct {
+	DryRun  bool
+	Results []Result
+}
+
+// Count returns number of results with the status
+func (r Report) Count(status Status) int {
+	var count int
+	for _, res := range r.Results {
+		if res.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Words returns words of results with the status
+func (r Report) Words(status Status) []string {
+	var words []string
+	for _, res := range r.Results {
+		if res.Status == status {
+			words = append(words, res.Word)
+		}
+	}
+	return words
+}
+
+// Import adds records to user dictionary. Imported translations are saved as user specific ones,
+// words without translations are looked up through providers.
+// Words unknown to providers are kept only in user dictionary with their translations.
+// Nothing is changed and providers aren't called in dry run mode, so unknown words are reported as added.
+// Records left when context is done are reported as skipped
+func Import(
+	ctx context.Context,
+	storage db.Storage,
+	lookuper lookup.Lookuper,
+	user db.UserID,
+	records []Record,
+	opts Options,
+) (Report, error) {
+	report := Report{DryRun: opts.DryRun, Results: make([]Result, 0, len(records))}
+	for _, record := range records {
+		if ctx.Err() != nil {
+			report.Results = append(report.Results, Result{
+				Word: record.Word, Status: StatusSkipped, Translations: record.Translations,
+			})
+			continue
+		}
+		res, err := importRecord(ctx, storage, lookuper, user, record, opts)
+		if err != nil {
+			return report, fmt.Errorf("import %v: %w", record.Word, err)
+		}
+		report.Results = append(report.Results, res)
+	}
+	return report, nil
+}
+
+func importRecord(
+	ctx context.Context,
+	storage db.Storage,
+	lookuper lookup.Lookuper,
+	user db.UserID,
+	record Record,
+	opts Options,
+) (Result, error) {
+	res := Result{Word: record.Word, Translations: record.Translations}
+	userItem, err := storage.GetUserItem(user, record.Word)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return res, fmt.Errorf("get user item: %w", err)
+	}
+	if err == nil {
+		current := userItem.CustomTranslations()
+		switch {
+		case len(record.Translations) == 0 || sameTranslations(current, record.Translations):
+			res.Status = StatusExists
+			return res, nil
+		case len(current) != 0 && !opts.Overwrite:
+			res.Status = StatusConflict
+			res.Existing = current
+			return res, nil
+		}
+		res.Status = StatusUpdated
+		if len(current) != 0 {
+			res.Existing = current
+		}
+		if opts.DryRun {
+			return res, nil
+		}
+		custom := userItem.CustomData()
+		custom.Translations = record.Translations
+		userItem.SetCustom(custom)
+		if err := storage.SaveUserItem(userItem); err != nil {
+			return res, fmt.Errorf("save user item: %w", err)
+		}
+		return res, nil
+	}
+
+	res.Status = StatusAdded
+	if opts.DryRun {
+		return res, nil
+	}
+	item, err := lookuper.Get(ctx, record.Word, storage)
+	if err != nil {
+		log.Error().Err(err).Str("word", record.Word).Msg("failed to look word up")
+		res.Status = StatusFailed
+		return res, nil
+	}
+	// user translations are enough to keep the word, it isn't added to the shared dictionary
+	if item == nil && len(record.Translations) == 0 {
+		res.Status = StatusUnknown
+		return res, nil
+	}
+	userItem = db.UserDictionaryItem{User: user, Word: record.Word, Created: time.Now().UTC()}
+	userItem.SetCustom(db.UserItemCustom{Translations: record.Translations})
+	if err := storage.SaveUserItem(userItem); err != nil {
+		return res, fmt.Errorf("save user item: %w", err)
+	}
+	return res, nil
+}
+
+// sameTranslations returns true if lists have the same translations in any order
+func sameTranslations(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,153 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubLookuper returns words from known map and saves them to storage
+type stubLookuper struct {
+	known map[string]bool
+}
+
+func (l stubLookuper) Get(_ context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	if word == "broken" {
+		return nil, errors.New("test")
+	}
+	if !l.known[word] {
+		return nil, nil
+	}
+	item := db.DictionaryItem{Word: word}
+	return &item, storage.Save(item)
+}
+
+func (l stubLookuper) GetNormalized(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	return l.Get(ctx, word, storage)
+}
+
+func (l stubLookuper) Preview(_ context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	return nil, nil
+}
+
+func (l stubLookuper) Refresh(_ context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	item, err := storage.Get(word)
+	return &item, err
+}
+
+func getImportStorage(t *testing.T) *db.InMemoryStorage {
+	storage := db.NewInMemoryStorage()
+	for _, item := range []db.UserDictionaryItem{
+		{User: 1, Word: "cat"},
+		{User: 1, Word: "dog", Custom: &db.UserItemCustom{Translations: []string{"собака"}}},
+		{User: 1, Word: "fox", Custom: &db.UserItemCustom{Translations: []string{"лиса"}}},
+	} {
+		require.NoError(t, storage.Save(db.DictionaryItem{Word: item.Word}))
+		require.NoError(t, storage.SaveUserItem(item))
+	}
+	return storage
+}
+
+var testRecords = []Record{
+	{Word: "cat", Translations: []string{"кот"}},
+	{Word: "dog", Translations: []string{"собака"}},
+	{Word: "fox", Translations: []string{"лис"}},
+	{Word: "house"},
+	{Word: "zzz"},
+	{Word: "qwerty", Translations: []string{"йцукен"}},
+	{Word: "broken"},
+}
+
+func TestImport(t *testing.T) {
+	lookuper := stubLookuper{known: map[string]bool{"house": true}}
+	t.Run("success", func(t *testing.T) {
+		storage := getImportStorage(t)
+		report, err := Import(context.Background(), storage, lookuper, 1, testRecords, DefaultOptions())
+		require.NoError(t, err)
+		assert.Equal(t, Report{Results: []Result{
+			{Word: "cat", Status: StatusUpdated, Translations: []string{"кот"}},
+			{Word: "dog", Status: StatusExists, Translations: []string{"собака"}},
+			{Word: "fox", Status: StatusConflict, Translations: []string{"лис"}, Existing: []string{"лиса"}},
+			{Word: "house", Status: StatusAdded},
+			{Word: "zzz", Status: StatusUnknown},
+			{Word: "qwerty", Status: StatusAdded, Translations: []string{"йцукен"}},
+			{Word: "broken", Status: StatusFailed},
+		}}, report)
+		assert.Equal(t, 2, report.Count(StatusAdded))
+		assert.Equal(t, []string{"house", "qwerty"}, report.Words(StatusAdded))
+
+		cat, err := storage.GetUserItem(1, "cat")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"кот"}, cat.CustomTranslations())
+		fox, err := storage.GetUserItem(1, "fox")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"лиса"}, fox.CustomTranslations())
+		house, err := storage.GetUserItem(1, "house")
+		require.NoError(t, err)
+		assert.Nil(t, house.Custom)
+		assert.WithinDuration(t, time.Now(), house.Created, time.Minute)
+		qwerty, err := storage.GetUserItem(1, "qwerty")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"йцукен"}, qwerty.CustomTranslations())
+		_, err = storage.Get("qwerty")
+		assert.ErrorIs(t, err, db.ErrNotFound)
+		_, err = storage.GetUserItem(1, "zzz")
+		assert.ErrorIs(t, err, db.ErrNotFound)
+	})
+	t.Run("overwrite", func(t *testing.T) {
+		storage := getImportStorage(t)
+		opts := DefaultOptions()
+		opts.Overwrite = true
+		report, err := Import(context.Background(), storage, lookuper, 1, testRecords[2:3], opts)
+		require.NoError(t, err)
+		assert.Equal(t, []Result{
+			{Word: "fox", Status: StatusUpdated, Translations: []string{"лис"}, Existing: []string{"лиса"}},
+		}, report.Results)
+		fox, err := storage.GetUserItem(1, "fox")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"лис"}, fox.CustomTranslations())
+	})
+	t.Run("dry run", func(t *testing.T) {
+		storage := getImportStorage(t)
+		before, err := storage.GetUserDictionary(1)
+		require.NoError(t, err)
+		opts := DefaultOptions()
+		opts.DryRun = true
+		report, err := Import(context.Background(), storage, lookuper, 1, testRecords, opts)
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 1, report.Count(StatusUpdated))
+		assert.Equal(t, 1, report.Count(StatusConflict))
+		assert.Equal(t, 4, report.Count(StatusAdded))
+		after, err := storage.GetUserDictionary(1)
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		storage := getImportStorage(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		report, err := Import(ctx, storage, lookuper, 1, testRecords[3:4], DefaultOptions())
+		require.NoError(t, err)
+		assert.Equal(t, []Result{{Word: "house", Status: StatusSkipped}}, report.Results)
+		_, err = storage.GetUserItem(1, "house")
+		assert.ErrorIs(t, err, db.ErrNotFound)
+	})
+	t.Run("storage error", func(t *testing.T) {
+		_, err := Import(context.Background(), errorStorage{db.NewInMemoryStorage()}, lookuper, 1, testRecords, DefaultOptions())
+		assert.Error(t, err)
+	})
+}
+
+type errorStorage struct {
+	*db.InMemoryStorage
+}
+
+func (s errorStorage) GetUserItem(db.UserID, string) (db.UserDictionaryItem, error) {
+	return db.UserDictionaryItem{}, errors.New("test")
+}
@@ -0,0 +1,311 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/export"
+)
+
+// MaxRecords limits number of records imported at once
+const MaxRecords = 1000
+
+// errors returned by parsing
+var (
+	ErrInvalidOption   = errors.New("invalid option")
+	ErrTooManyRecords  = fmt.Errorf("too many records, up to %d are allowed", MaxRecords)
+	ErrInvalidFileData = errors.New("invalid file data")
+)
+
+// Options describes import file layout and import behaviour
+type Options struct {
+	// WordColumn is zero based index of column with words
+	WordColumn int
+	// TranslationColumn is zero based index of column with translations, negative if there are none
+	TranslationColumn int
+	// Separator of columns, detected from data if zero
+	Separator rune
+	// SkipHeader skips the first record
+	SkipHeader bool
+	// DryRun only reports what would be changed
+	DryRun bool
+	// Overwrite replaces conflicting user translations
+	Overwrite bool
+	// explicitColumns is set if columns are passed by user, so they aren't taken from the header
+	explicitColumns bool
+}
+
+// DefaultOptions returns options for files with words in the first column
+// and translations in the second one
+func DefaultOptions() Options {
+	return Options{WordColumn: 0, TranslationColumn: 1}
+}
+
+// Set sets option by its name, columns are one based and translation column 0 disables translations.
+// Empty value enables boolean options
+func (o *Options) Set(key, value string) error {
+	switch strings.ToLower(key) {
+	case "word":
+		column, err := strconv.Atoi(value)
+		if err != nil || column < 1 {
+			return fmt.Errorf("%w: word column should be a positive number", ErrInvalidOption)
+		}
+		o.WordColumn = column - 1
+		o.explicitColumns = true
+	case "translation":
+		column, err := strconv.Atoi(value)
+		if err != nil || column < 0 {
+			return fmt.Errorf("%w: translation column should be a number", ErrInvalidOption)
+		}
+		o.TranslationColumn = column - 1
+		o.explicitColumns = true
+	case "sep":
+		switch strings.ToLower(value) {
+		case "tab", "\t":
+			o.Separator = '\t'
+		case "comma", ",":
+			o.Separator = ','
+		case "semicolon", ";":
+			o.Separator = ';'
+		default:
+			return fmt.Errorf("%w: separator should be tab, comma or semicolon", ErrInvalidOption)
+		}
+	case "header", "dry_run", "overwrite":
+		flag := true
+		if value != "" {
+			var err error
+			if flag, err = strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("%w: %v should be a boolean", ErrInvalidOption, key)
+			}
+		}
+		switch strings.ToLower(key) {
+		case "header":
+			o.SkipHeader = flag
+		case "dry_run":
+			o.DryRun = flag
+		default:
+			o.Overwrite = flag
+		}
+	default:
+		return fmt.Errorf("%w: unknown option %v", ErrInvalidOption, key)
+	}
+	return nil
+}
+
+// ParseOptions parses space separated options like "word=2 translation=1 sep=tab header dry_run"
+func ParseOptions(text string) (Options, error) {
+	opts := DefaultOptions()
+	for _, field := range strings.Fields(text) {
+		key, value, _ := strings.Cut(field, "=")
+		if err := opts.Set(key, value); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// Record is a single imported word
+type Record struct {
+	Word         string
+	Translations []string
+}
+
+// Parse reads records from CSV or TSV data, lines starting with # are skipped as comments,
+// so files exported from Anki can be imported as is. Columns are taken from the header
+// with known names unless they are set in options, so exported CSV files are imported back too.
+// Only the first line of the word field is used, the rest holds phonetics or notes.
+// Records with the same word are merged
+func Parse(r io.Reader, opts Options) ([]Record, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read data: %w", err)
+	}
+	var lines [][]byte
+	separator := opts.Separator
+	tagsColumn := -1
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("#")) {
+			if value, ok := bytes.CutPrefix(line, []byte("#separator:")); ok && separator == 0 {
+				var sepOpts Options
+				if err := sepOpts.Set("sep", string(bytes.TrimSpace(value))); err == nil {
+					separator = sepOpts.Separator
+				}
+			}
+			if value, ok := bytes.CutPrefix(line, []byte("#tags column:")); ok {
+				if column, err := strconv.Atoi(string(bytes.TrimSpace(value))); err == nil && column > 0 {
+					tagsColumn = column - 1
+				}
+			}
+			continue
+		}
+		lines = append(lines, line)
+	}
+	data = bytes.Join(lines, []byte("\n"))
+	if separator == 0 {
+		separator = detectSeparator(data)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var records []Record
+	index := make(map[string]int)
+	for first := true; ; first = false {
+		fields, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFileData, err)
+		}
+		if first {
+			isHeader := mapHeader(fields, &opts)
+			if isHeader || opts.SkipHeader {
+				continue
+			}
+		}
+		if opts.WordColumn >= len(fields) {
+			continue
+		}
+		word, _, _ := strings.Cut(cleanField(fields[opts.WordColumn]), "\n")
+		word = db.NormalizeWord(word)
+		if word == "" {
+			continue
+		}
+		var translations []string
+		if opts.TranslationColumn >= 0 && opts.TranslationColumn < len(fields) {
+			field := fields[opts.TranslationColumn]
+			if tagsColumn >= 0 && tagsColumn < len(fields) && hasTag(fields[tagsColumn], export.AnkiTag) {
+				field = exportedTranslations(field)
+			}
+			translations = splitTranslations(field)
+		}
+		if idx, ok := index[word]; ok {
+			records[idx].Translations = mergeTranslations(records[idx].Translations, translations)
+			continue
+		}
+		if len(records) == MaxRecords {
+			return nil, ErrTooManyRecords
+		}
+		index[word] = len(records)
+		records = append(records, Record{Word: word, Translations: translations})
+	}
+	return records, nil
+}
+
+// header names of word and translation columns, names of other columns written by export
+var (
+	wordHeaders        = []string{"word", "front"}
+	translationHeaders = []string{"translation", "translations", "back"}
+	otherHeaders       = []string{"phonetics", "meanings", "examples", "added", "quizzes", "correct", "tags"}
+)
+
+// mapHeader returns true if fields are a header with known column names,
+// word and translation columns are set from it unless they are set explicitly
+func mapHeader(fields []string, opts *Options) bool {
+	wordColumn, translationColumn, known := -1, -1, 0
+	for idx, f := range fields {
+		f = strings.ToLower(strings.TrimSpace(f))
+		switch {
+		case containsFold(wordHeaders, f) && wordColumn < 0:
+			wordColumn = idx
+		case containsFold(translationHeaders, f) && translationColumn < 0:
+			translationColumn = idx
+		case containsFold(otherHeaders, f):
+		default:
+			continue
+		}
+		known++
+	}
+	// a single "word" may be a word to import
+	if wordColumn < 0 || known < 2 {
+		return false
+	}
+	if !opts.explicitColumns {
+		opts.WordColumn, opts.TranslationColumn = wordColumn, translationColumn
+	}
+	return true
+}
+
+// hasTag returns true if Anki tags field has the tag
+func hasTag(field, tag string) bool {
+	for _, t := range strings.Fields(field) {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+var exportedTranslationsRe = regexp.MustCompile(`^<b>(.*?)</b>`)
+
+// exportedTranslations returns translations from the back field of notes made by export,
+// they are in the leading bold block followed by meanings
+func exportedTranslations(field string) string {
+	match := exportedTranslationsRe.FindStringSubmatch(field)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+// detectSeparator returns tab or semicolon if the first line has them, comma otherwise
+func detectSeparator(data []byte) rune {
+	firstLine, _, _ := bytes.Cut(data, []byte("\n"))
+	switch {
+	case bytes.ContainsRune(firstLine, '\t'):
+		return '\t'
+	case bytes.ContainsRune(firstLine, ';') && !bytes.ContainsRune(firstLine, ','):
+		return ';'
+	}
+	return ','
+}
+
+var (
+	lineBreakRe = regexp.MustCompile(`(?i)<br\s*/?>|</div>|</p>`)
+	tagRe       = regexp.MustCompile(`<[^>]*>`)
+)
+
+// cleanField removes HTML markup used by Anki from the field
+func cleanField(field string) string {
+	field = lineBreakRe.ReplaceAllString(field, "\n")
+	field = tagRe.ReplaceAllString(field, "")
+	return strings.TrimSpace(html.UnescapeString(field))
+}
+
+// splitTranslations splits field to unique translations
+func splitTranslations(field string) []string {
+	parts := strings.FieldsFunc(cleanField(field), func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '/'
+	})
+	return mergeTranslations(nil, parts)
+}
+
+// mergeTranslations adds missing translations to the list
+func mergeTranslations(translations, more []string) []string {
+	for _, t := range more {
+		t = strings.Join(strings.Fields(t), " ")
+		if t != "" && !containsFold(translations, t) {
+			translations = append(translations, t)
+		}
+	}
+	return translations
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
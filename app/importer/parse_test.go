@@ -0,0 +1,132 @@
+package importer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/export"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOptions(t *testing.T) {
+	t.Run("default", func(t *testing.T) {
+		opts, err := ParseOptions("")
+		assert.NoError(t, err)
+		assert.Equal(t, DefaultOptions(), opts)
+	})
+	t.Run("all options", func(t *testing.T) {
+		opts, err := ParseOptions("word=2 translation=1 sep=tab header dry_run overwrite=true")
+		assert.NoError(t, err)
+		assert.Equal(t, Options{
+			WordColumn:        1,
+			TranslationColumn: 0,
+			Separator:         '\t',
+			SkipHeader:        true,
+			DryRun:            true,
+			Overwrite:         true,
+			explicitColumns:   true,
+		}, opts)
+	})
+	t.Run("no translations", func(t *testing.T) {
+		opts, err := ParseOptions("translation=0")
+		assert.NoError(t, err)
+		assert.Equal(t, -1, opts.TranslationColumn)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		for _, text := range []string{"word=0", "word=a", "translation=-1", "sep=pipe", "dry_run=maybe", "columns=2"} {
+			_, err := ParseOptions(text)
+			assert.ErrorIs(t, err, ErrInvalidOption, text)
+		}
+	})
+}
+
+func TestParse(t *testing.T) {
+	t.Run("csv", func(t *testing.T) {
+		data := "word,translation\nHello,\"привет, здравствуй\"\nworld;мир\ngive up,сдаться\n,пусто\nhello,Привет;алло\n"
+		opts := DefaultOptions()
+		opts.SkipHeader = true
+		records, err := Parse(strings.NewReader(data), opts)
+		require.NoError(t, err)
+		assert.Equal(t, []Record{
+			{Word: "hello", Translations: []string{"привет", "здравствуй", "алло"}},
+			{Word: "world;мир"},
+			{Word: "give up", Translations: []string{"сдаться"}},
+		}, records)
+	})
+	t.Run("anki tsv", func(t *testing.T) {
+		data := "#separator:tab\n#html:true\n" +
+			"<b>тест</b><br>проверка\ttest\ttag\n" +
+			"дом &amp; здание\t<div>house</div>\ttag\n"
+		opts, err := ParseOptions("word=2 translation=1")
+		require.NoError(t, err)
+		records, err := Parse(strings.NewReader(data), opts)
+		require.NoError(t, err)
+		assert.Equal(t, []Record{
+			{Word: "test", Translations: []string{"тест", "проверка"}},
+			{Word: "house", Translations: []string{"дом & здание"}},
+		}, records)
+	})
+	t.Run("header", func(t *testing.T) {
+		data := "Phonetics,Word,Translations\n/kæt/,cat,кот\n"
+		records, err := Parse(strings.NewReader(data), DefaultOptions())
+		require.NoError(t, err)
+		assert.Equal(t, []Record{{Word: "cat", Translations: []string{"кот"}}}, records)
+
+		opts, err := ParseOptions("word=3 translation=0")
+		require.NoError(t, err)
+		records, err = Parse(strings.NewReader(data), opts)
+		require.NoError(t, err)
+		assert.Equal(t, []Record{{Word: "кот"}}, records)
+
+		records, err = Parse(strings.NewReader("word\ncat\n"), DefaultOptions())
+		require.NoError(t, err)
+		assert.Equal(t, []Record{{Word: "word"}, {Word: "cat"}}, records)
+	})
+	t.Run("detected separator", func(t *testing.T) {
+		for _, data := range []string{"cat\tкот\n", "cat;кот\n", "cat,кот\n"} {
+			records, err := Parse(strings.NewReader(data), DefaultOptions())
+			require.NoError(t, err)
+			assert.Equal(t, []Record{{Word: "cat", Translations: []string{"кот"}}}, records, data)
+		}
+	})
+	t.Run("too many records", func(t *testing.T) {
+		data := strings.Builder{}
+		for i := 0; i <= MaxRecords; i++ {
+			data.WriteString("word" + strings.Repeat("a", i) + "\n")
+		}
+		_, err := Parse(strings.NewReader(data.String()), DefaultOptions())
+		assert.ErrorIs(t, err, ErrTooManyRecords)
+	})
+}
+
+func TestParseExported(t *testing.T) {
+	item := db.DictionaryItem{
+		Word: "test",
+		Meanings: []db.Meaning{
+			{PartOfSpeech: "noun", Definition: "a procedure, check", Examples: []string{"a blood test"}},
+		},
+		Translations: []db.Translation{{Text: "тест", Language: "ru"}, {Text: "проверка", Language: "ru"}},
+	}
+	item.Phonetics.Text = "/tɛst/"
+	dictionary := map[db.UserDictionaryItem]db.DictionaryItem{
+		{Word: "test", Created: time.Now()}:    item,
+		{Word: "unknown", Created: time.Now()}: {},
+	}
+	for _, format := range []export.Format{export.FormatCSV, export.FormatTSV} {
+		t.Run(string(format), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			require.NoError(t, export.Write(buf, format, dictionary))
+			records, err := Parse(buf, DefaultOptions())
+			require.NoError(t, err)
+			assert.Equal(t, []Record{
+				{Word: "test", Translations: []string{"тест", "проверка"}},
+				{Word: "unknown"},
+			}, records)
+		})
+	}
+}
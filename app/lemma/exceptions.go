@@ -0,0 +1,163 @@
+package lemma
+
+// irregular maps irregular word forms to their lemmas
+var irregular = map[string]string{
+	// be, have, do
+	"am": "be", "is": "be", "are": "be", "was": "be", "were": "be", "been": "be", "being": "be",
+	"has": "have", "had": "have", "having": "have",
+	"does": "do", "did": "do", "done": "do", "doing": "do",
+	// irregular verbs
+	"arose": "arise", "arisen": "arise",
+	"awoke": "awake", "awoken": "awake",
+	"borne":  "bear",
+	"beaten": "beat",
+	"became": "become",
+	"began":  "begin", "begun": "begin",
+	"bent":   "bend",
+	"bitten": "bite",
+	"bled":   "bleed",
+	"blew":   "blow", "blown": "blow",
+	"broke": "break", "broken": "break",
+	"bred":    "breed",
+	"brought": "bring",
+	"built":   "build",
+	"burnt":   "burn",
+	"bought":  "buy",
+	"caught":  "catch",
+	"chose":   "choose", "chosen": "choose",
+	"clung": "cling",
+	"came":  "come",
+	"crept": "creep",
+	"dealt": "deal",
+	"dug":   "dig",
+	"drew":  "draw", "drawn": "draw",
+	"dreamt": "dream",
+	"drank":  "drink", "drunk": "drink",
+	"drove": "drive", "driven": "drive",
+	"ate": "eat", "eaten": "eat",
+	"fallen": "fall",
+	"fed":    "feed",
+	"felt":   "feel",
+	"fought": "fight",
+	"found":  "find",
+	"fled":   "flee",
+	"flew":   "fly", "flown": "fly",
+	"forbade": "forbid", "forbidden": "forbid",
+	"forgot": "forget", "forgotten": "forget",
+	"forgave": "forgive", "forgiven": "forgive",
+	"froze": "freeze", "frozen": "freeze",
+	"got": "get", "gotten": "get",
+	"gave": "give", "given": "give",
+	"went": "go", "gone": "go", "goes": "go",
+	"grew": "grow", "grown": "grow",
+	"hung":  "hang",
+	"heard": "hear",
+	"hid":   "hide", "hidden": "hide",
+	"held":  "hold",
+	"kept":  "keep",
+	"knelt": "kneel",
+	"knew":  "know", "known": "know",
+	"laid":   "lay",
+	"led":    "lead",
+	"leapt":  "leap",
+	"learnt": "learn",
+	"lent":   "lend",
+	"lain":   "lie",
+	"lost":   "lose",
+	"made":   "make",
+	"meant":  "mean",
+	"met":    "meet",
+	"paid":   "pay",
+	"rode":   "ride", "ridden": "ride",
+	"rang": "ring", "rung": "ring",
+	"risen":  "rise",
+	"ran":    "run",
+	"said":   "say",
+	"seen":   "see",
+	"sought": "seek",
+	"sold":   "sell",
+	"sent":   "send",
+	"shook":  "shake", "shaken": "shake",
+	"shone":  "shine",
+	"shot":   "shoot",
+	"showed": "show", "shown": "show",
+	"shrank": "shrink", "shrunk": "shrink",
+	"sang": "sing", "sung": "sing",
+	"sank": "sink", "sunk": "sink",
+	"sat":   "sit",
+	"slept": "sleep",
+	"slid":  "slide",
+	"spoke": "speak", "spoken": "speak",
+	"sped":  "speed",
+	"spent": "spend",
+	"spun":  "spin",
+	"spat":  "spit",
+	"stood": "stand",
+	"stole": "steal", "stolen": "steal",
+	"stuck":  "stick",
+	"stung":  "sting",
+	"struck": "strike",
+	"swore":  "swear", "sworn": "swear",
+	"swept": "sweep",
+	"swam":  "swim", "swum": "swim",
+	"swung": "swing",
+	"took":  "take", "taken": "take",
+	"taught": "teach",
+	"tore":   "tear", "torn": "tear",
+	"told":    "tell",
+	"thought": "think",
+	"threw":   "throw", "thrown": "throw",
+	"understood": "understand",
+	"woke":       "wake", "woken": "wake",
+	"wore": "wear", "worn": "wear",
+	"wept":     "weep",
+	"won":      "win",
+	"withdrew": "withdraw", "withdrawn": "withdraw",
+	"wrote": "write", "written": "write",
+	// irregular plurals
+	"men": "man", "women": "woman", "children": "child",
+	"feet": "foot", "teeth": "tooth", "geese": "goose", "mice": "mouse", "lice": "louse",
+	"oxen":   "ox",
+	"knives": "knife", "wives": "wife", "wolves": "wolf",
+	"halves": "half", "shelves": "shelf", "thieves": "thief", "loaves": "loaf", "calves": "calf",
+	"selves":   "self",
+	"analyses": "analysis", "crises": "crisis", "theses": "thesis", "hypotheses": "hypothesis",
+	"phenomena": "phenomenon", "criteria": "criterion",
+	"cacti": "cactus", "fungi": "fungus", "nuclei": "nucleus", "stimuli": "stimulus",
+	// irregular comparatives
+	"better": "good", "best": "good",
+	"worse": "bad", "worst": "bad",
+	"further": "far", "furthest": "far", "farther": "far", "farthest": "far",
+	// forms which don't follow suffix rules
+	"used": "use", "buses": "bus", "potatoes": "potato", "tomatoes": "tomato", "heroes": "hero",
+	"echoes": "echo", "vetoes": "veto", "lying": "lie", "dying": "die", "tying": "tie",
+}
+
+// invariant holds words which look like inflected forms but must not be changed
+var invariant = map[string]struct{}{
+	"always": {}, "as": {}, "bus": {}, "business": {}, "gas": {}, "has": {}, "his": {}, "is": {},
+	"its": {}, "news": {}, "perhaps": {}, "series": {}, "species": {}, "this": {}, "thus": {},
+	"us": {}, "was": {}, "yes": {}, "lens": {}, "chaos": {}, "plus": {}, "bias": {}, "canvas": {},
+	"physics": {}, "mathematics": {}, "economics": {}, "politics": {}, "athletics": {},
+	"red": {}, "bed": {}, "need": {}, "seed": {}, "feed": {}, "speed": {}, "weed": {}, "shed": {},
+	"hundred": {}, "sacred": {}, "naked": {}, "wicked": {}, "wretched": {}, "rugged": {},
+	"indeed": {}, "proceed": {}, "succeed": {}, "exceed": {}, "breed": {}, "bleed": {},
+	"ring": {}, "king": {}, "thing": {}, "nothing": {}, "something": {}, "anything": {},
+	"everything": {}, "sing": {}, "bring": {}, "spring": {}, "string": {}, "sting": {}, "swing": {},
+	"wing": {}, "during": {}, "morning": {}, "evening": {}, "ceiling": {}, "building": {},
+	"meaning": {}, "feeling": {}, "wedding": {}, "pudding": {}, "cling": {}, "fling": {},
+	"sly": {}, "fly": {}, "early": {}, "only": {}, "family": {}, "reply": {}, "supply": {},
+	"apply": {}, "rely": {}, "ally": {}, "belly": {}, "holy": {}, "ugly": {}, "july": {},
+	"butter": {}, "letter": {}, "matter": {}, "water": {}, "after": {}, "never": {}, "over": {},
+	"under": {}, "offer": {}, "order": {}, "other": {}, "paper": {}, "power": {}, "number": {},
+	"river": {}, "winter": {}, "summer": {}, "weather": {}, "father": {}, "mother": {},
+	"brother": {}, "sister": {}, "daughter": {}, "finger": {}, "answer": {}, "corner": {},
+	"dinner": {}, "member": {}, "computer": {}, "teacher": {}, "flower": {}, "however": {},
+	"whether": {}, "together": {}, "either": {}, "neither": {}, "rather": {}, "enter": {},
+	"consider": {}, "remember": {}, "discover": {}, "gather": {}, "wonder": {}, "suffer": {},
+	"differ": {}, "prefer": {}, "deliver": {}, "cover": {}, "recover": {}, "bitter": {},
+	"clever": {}, "proper": {}, "tender": {}, "eager": {}, "sober": {},
+	"fast": {}, "rest": {}, "test": {}, "west": {}, "east": {}, "last": {},
+	"past": {}, "just": {}, "must": {}, "trust": {}, "dust": {}, "honest": {}, "forest": {},
+	"interest": {}, "guest": {}, "chest": {}, "nest": {}, "almost": {},
+}
@@ -0,0 +1,150 @@
+// Package lemma implements a rule based lemmatizer for English words
+package lemma
+
+import "strings"
+
+// Lemmatize returns dictionary form of the word,
+// irregular forms are resolved by exceptions table, regular ones by suffix rules.
+// Only the first word of a phrase is changed: "gave up" -> "give up"
+func Lemmatize(word string) string {
+	if fields := strings.Fields(word); len(fields) > 1 {
+		fields[0] = Lemmatize(fields[0])
+		return strings.ToLower(strings.Join(fields, " "))
+	}
+	word = strings.ToLower(strings.TrimSpace(word))
+	if lemma, ok := irregular[word]; ok {
+		return lemma
+	}
+	if _, ok := invariant[word]; ok {
+		return word
+	}
+	if !isAlpha(word) || len(word) <= 3 {
+		return word
+	}
+	switch {
+	case strings.HasSuffix(word, "ing"):
+		return verbStem(word, strings.TrimSuffix(word, "ing"))
+	case strings.HasSuffix(word, "eed"):
+		// agreed -> agree, but need -> need
+		if measure(strings.TrimSuffix(word, "eed")) > 0 {
+			return strings.TrimSuffix(word, "d")
+		}
+		return word
+	case strings.HasSuffix(word, "ied"):
+		return strings.TrimSuffix(word, "ied") + "y"
+	case strings.HasSuffix(word, "ed"):
+		return verbStem(word, strings.TrimSuffix(word, "ed"))
+	case strings.HasSuffix(word, "iest") && len(word) > 5:
+		return strings.TrimSuffix(word, "iest") + "y"
+	case strings.HasSuffix(word, "est") && hasDoubleConsonant(strings.TrimSuffix(word, "est")):
+		// biggest -> big
+		stem := strings.TrimSuffix(word, "est")
+		return stem[:len(stem)-1]
+	}
+	return nounStem(word)
+}
+
+// nounStem removes plural and 3rd person suffixes
+func nounStem(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		return strings.TrimSuffix(word, "ies") + "y"
+	case strings.HasSuffix(word, "sses"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "xes"), strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "zzes"):
+		return strings.TrimSuffix(word, "es")
+	case strings.HasSuffix(word, "ss"), strings.HasSuffix(word, "us"),
+		strings.HasSuffix(word, "is"), strings.HasSuffix(word, "ous"):
+		return word
+	case strings.HasSuffix(word, "s"):
+		return strings.TrimSuffix(word, "s")
+	}
+	return word
+}
+
+// verbStem restores verb form after -ing/-ed suffix removal
+func verbStem(word, stem string) string {
+	if !hasVowel(stem) {
+		// sing, red
+		return word
+	}
+	switch {
+	case strings.HasSuffix(stem, "at"), strings.HasSuffix(stem, "bl"), strings.HasSuffix(stem, "iz"):
+		// relating -> relate, troubled -> trouble, realized -> realize
+		return stem + "e"
+	case hasDoubleConsonant(stem) && !strings.HasSuffix(stem, "l") &&
+		!strings.HasSuffix(stem, "s") && !strings.HasSuffix(stem, "z"):
+		// running -> run
+		return stem[:len(stem)-1]
+	case measure(stem) == 1 && endsCVC(stem):
+		// hoping -> hope
+		return stem + "e"
+	}
+	return stem
+}
+
+func isAlpha(word string) bool {
+	for _, r := range word {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isConsonant checks if letter at position i is a consonant,
+// y is a consonant at the beginning or after a vowel
+func isConsonant(word string, i int) bool {
+	switch word[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !isConsonant(word, i-1)
+	}
+	return true
+}
+
+func hasVowel(word string) bool {
+	for i := range word {
+		if !isConsonant(word, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasDoubleConsonant(word string) bool {
+	n := len(word)
+	return n >= 2 && word[n-1] == word[n-2] && isConsonant(word, n-1)
+}
+
+// endsCVC checks if word ends with consonant-vowel-consonant and the last one is not w, x or y
+func endsCVC(word string) bool {
+	n := len(word)
+	if n < 3 {
+		return false
+	}
+	if !isConsonant(word, n-3) || isConsonant(word, n-2) || !isConsonant(word, n-1) {
+		return false
+	}
+	switch word[n-1] {
+	case 'w', 'x', 'y':
+		return false
+	}
+	return true
+}
+
+// measure returns number of vowel-consonant sequences in the word
+func measure(word string) int {
+	var m int
+	prevVowel := false
+	for i := range word {
+		consonant := isConsonant(word, i)
+		if consonant && prevVowel {
+			m++
+		}
+		prevVowel = !consonant
+	}
+	return m
+}
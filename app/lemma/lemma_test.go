@@ -0,0 +1,55 @@
+package lemma
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLemmatize(t *testing.T) {
+	cases := map[string]map[string]string{
+		"irregular": {
+			"ran": "run", "went": "go", "was": "be", "children": "child", "written": "write",
+			"better": "good", "knives": "knife",
+		},
+		"ing": {
+			"running": "run", "playing": "play", "hoping": "hope", "making": "make", "opening": "open",
+			"relating": "relate", "telling": "tell", "visiting": "visit", "missing": "miss",
+		},
+		"ed": {
+			"wanted": "want", "stopped": "stop", "hoped": "hope", "studied": "study", "agreed": "agree",
+			"played": "play", "troubled": "trouble", "realized": "realize",
+		},
+		"plural": {
+			"runs": "run", "cats": "cat", "boxes": "box", "watches": "watch", "classes": "class",
+			"cities": "city", "houses": "house", "shoes": "shoe", "potatoes": "potato",
+		},
+		"superlative": {
+			"easiest": "easy", "biggest": "big",
+		},
+		"unchanged": {
+			"run": "run", "news": "news", "this": "this", "red": "red", "need": "need", "sing": "sing",
+			"bus": "bus", "famous": "famous", "analysis": "analysis", "morning": "morning",
+			"water": "water", "honest": "honest", "go": "go", "e-mail": "e-mail",
+		},
+		"normalized": {
+			" Running ": "run", "CATS": "cat",
+		},
+		"phrase": {
+			"gave up": "give up", "Looking  Forward to": "look forward to", "ice cream": "ice cream",
+		},
+	}
+	for name, words := range cases {
+		t.Run(name, func(t *testing.T) {
+			for word, expected := range words {
+				assert.Equal(t, expected, Lemmatize(word), word)
+			}
+		})
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	for word, expected := range map[string]int{"tr": 0, "ee": 0, "tree": 0, "trouble": 1, "oats": 1, "troubles": 2} {
+		assert.Equal(t, expected, measure(word), word)
+	}
+}
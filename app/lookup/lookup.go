@@ -0,0 +1,238 @@
+// Package lookup fetches words data from dictionary providers and caches it in storage
+package lookup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/clients/dictionaryapi"
+	yandexdictionary "github.com/rbhz/tg-dictionary/app/clients/yandexdictionary"
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/lemma"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	fromLanguage = "en"
+	toLanguage   = "ru"
+)
+
+// ErrChanged is returned if the item is changed while its data is fetched from providers
+var ErrChanged = errors.New("item is changed while refreshing")
+
+// Provider names listed in db.DictionaryItem.Incomplete
+const (
+	ProviderDictionary  = "dictionaryapi"
+	ProviderTranslation = "yandex"
+)
+
+// Lookuper looks words up
+type Lookuper interface {
+	Get(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error)
+	GetNormalized(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error)
+	Preview(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error)
+	Refresh(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error)
+}
+
+// Provider looks words up in storage first and in dictionary providers if they are missing
+type Provider struct {
+	tranlationsToken string
+}
+
+// GetNormalized returns item data for the word lemma,
+// the word itself is used if lemma is unknown
+func (p Provider) GetNormalized(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	normalized := lemma.Lemmatize(word)
+	item, err := p.Get(ctx, normalized, storage)
+	if err != nil || item != nil || normalized == word {
+		return item, err
+	}
+	return p.Get(ctx, word, storage)
+}
+
+// Get returns word data from storage or from providers, found data is saved to storage.
+// Nil item is returned for unknown words
+func (p Provider) Get(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	item, fetched, err := p.get(ctx, word, storage)
+	if err != nil || item == nil || !fetched {
+		return item, err
+	}
+	if err := storage.Save(*item); err != nil {
+		return nil, fmt.Errorf("save to db: %w", err)
+	}
+	return item, nil
+}
+
+// Preview returns item data for the word lemma like GetNormalized, but data fetched from providers
+// isn't saved, so words looked up in passing like partial inline queries don't get to storage
+func (p Provider) Preview(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	normalized := lemma.Lemmatize(word)
+	item, _, err := p.get(ctx, normalized, storage)
+	if err != nil || item != nil || normalized == word {
+		return item, err
+	}
+	item, _, err = p.get(ctx, word, storage)
+	return item, err
+}
+
+// get returns word data from storage or from providers, fetched is true for data from providers
+func (p Provider) get(ctx context.Context, word string, storage db.Storage) (item *db.DictionaryItem, fetched bool, err error) {
+	dbItem, err := storage.Get(word)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return nil, false, fmt.Errorf("fetch from db: %w", err)
+	}
+	if err == nil {
+		return &dbItem, false, nil
+	}
+	res := p.fetch(ctx, word)
+	known, err := res.known(word)
+	if err != nil || !known {
+		return nil, false, err
+	}
+	fetchedItem := res.item(word)
+	return &fetchedItem, true, nil
+}
+
+// Refresh fetches data of the saved word from providers again and updates it in storage,
+// data of failed providers is kept. Changes are saved as a new revision without author,
+// db.ErrNotFound is returned for words missing in storage
+func (p Provider) Refresh(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	prev, err := storage.Get(word)
+	if err != nil {
+		return nil, fmt.Errorf("fetch from db: %w", err)
+	}
+	rev, err := latestRevision(storage, prev.Word)
+	if err != nil {
+		return nil, err
+	}
+	item := mergeRefreshed(prev, p.fetch(ctx, prev.Word).item(prev.Word))
+	return saveRefreshed(storage, prev, rev, item)
+}
+
+// saveRefreshed saves refreshed item as a revision without author and returns the saved item.
+// The item is read again before saving and ErrChanged is returned if it's changed since prev with rev
+// were read, so edits made while providers were queried aren't overwritten
+func saveRefreshed(storage db.Storage, prev db.DictionaryItem, rev int, item db.DictionaryItem) (*db.DictionaryItem, error) {
+	current, err := storage.Get(prev.Word)
+	if err != nil {
+		return nil, fmt.Errorf("fetch from db: %w", err)
+	}
+	currentRev, err := latestRevision(storage, prev.Word)
+	if err != nil {
+		return nil, err
+	}
+	if currentRev != rev || current.EditedBy != prev.EditedBy || len(db.DiffItems(prev, current)) != 0 {
+		return nil, ErrChanged
+	}
+	if _, err := db.SaveWord(storage, item, 0, *item.FetchedAt); err != nil {
+		return nil, fmt.Errorf("save word: %w", err)
+	}
+	saved, err := storage.Get(prev.Word)
+	if err != nil {
+		return nil, fmt.Errorf("fetch from db: %w", err)
+	}
+	return &saved, nil
+}
+
+// latestRevision returns number of the latest revision of the word, it's zero for words without history
+func latestRevision(storage db.Storage, word string) (int, error) {
+	revisions, err := storage.GetWordRevisions(word)
+	if err != nil {
+		return 0, fmt.Errorf("get revisions: %w", err)
+	}
+	if len(revisions) == 0 {
+		return 0, nil
+	}
+	return revisions[len(revisions)-1].Rev, nil
+}
+
+// fetchResult holds responses of dictionary providers
+type fetchResult struct {
+	dictionary     []dictionaryapi.WordResponse
+	dictErr        error
+	translation    yandexdictionary.TranslationResponse
+	translationErr error
+}
+
+// fetch looks word up in all providers concurrently
+func (p Provider) fetch(ctx context.Context, word string) fetchResult {
+	dictOut, dictErrChan := make(chan []dictionaryapi.WordResponse), make(chan error)
+	translationOut, translationErrChar := make(chan yandexdictionary.TranslationResponse), make(chan error)
+	go func() {
+		client := dictionaryapi.NewClient(ctx)
+		dictionary, err := client.Get(word)
+		dictOut <- dictionary
+		dictErrChan <- err
+	}()
+	go func() {
+		client := yandexdictionary.NewClient(ctx, p.tranlationsToken)
+		translation, err := client.Translate(word, fromLanguage, toLanguage)
+		translationOut <- translation
+		translationErrChar <- err
+	}()
+	var res fetchResult
+	res.translation, res.translationErr = <-translationOut, <-translationErrChar
+	res.dictionary, res.dictErr = <-dictOut, <-dictErrChan
+	return res
+}
+
+// known returns false if providers don't know the word, error is returned if dictionary provider failed
+func (r fetchResult) known(word string) (bool, error) {
+	if r.dictErr == nil {
+		return true, nil
+	}
+	if !errors.Is(r.dictErr, dictionaryapi.ErrNotFound) {
+		return false, fmt.Errorf("get dictionary info: %w", r.dictErr)
+	}
+	// phrases and idioms are often missing in dictionary api, translations are enough for them,
+	// while single words missing there are misspelled or made up by lemmatizer
+	return r.translationErr == nil && len(strings.Fields(word)) > 1, nil
+}
+
+// item builds dictionary item from providers responses, failed providers are listed as incomplete.
+// Unknown words aren't treated as failures
+func (r fetchResult) item(word string) db.DictionaryItem {
+	translations := make(map[string]yandexdictionary.TranslationResponse, 1)
+	var incomplete []string
+	if r.translationErr != nil {
+		if !errors.Is(r.translationErr, yandexdictionary.ErrUnknown) {
+			log.Error().Err(r.translationErr).Str("word", word).Msg("failed to get translation info")
+			incomplete = append(incomplete, ProviderTranslation)
+		}
+	} else {
+		translations[toLanguage] = r.translation
+	}
+	if r.dictErr != nil && !errors.Is(r.dictErr, dictionaryapi.ErrNotFound) {
+		incomplete = append(incomplete, ProviderDictionary)
+	}
+	item := db.NewDictionaryItem(word, r.dictionary, translations)
+	now := time.Now().UTC()
+	item.FetchedAt = &now
+	item.Incomplete = incomplete
+	return item
+}
+
+// mergeRefreshed returns refreshed item with the previous data of providers failed or returned nothing,
+// so temporary provider errors never wipe the saved data
+func mergeRefreshed(prev, next db.DictionaryItem) db.DictionaryItem {
+	failed := make(map[string]bool, len(next.Incomplete))
+	for _, p := range next.Incomplete {
+		failed[p] = true
+	}
+	if failed[ProviderDictionary] || (len(next.Meanings) == 0 && next.Phonetics.Text == "") {
+		next.Meanings, next.Phonetics = prev.Meanings, prev.Phonetics
+	}
+	if failed[ProviderTranslation] || len(next.Translations) == 0 {
+		next.Translations = prev.Translations
+	}
+	return next
+}
+
+// NewProvider creates Provider
+func NewProvider(tranlationsToken string) Provider {
+	return Provider{tranlationsToken: tranlationsToken}
+}
@@ -0,0 +1,41 @@
+package lookup
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/rbhz/tg-dictionary/app/clients/dictionaryapi"
+	yandexdictionary "github.com/rbhz/tg-dictionary/app/clients/yandexdictionary"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchResultKnown(t *testing.T) {
+	notFound := fmt.Errorf("get: %w", dictionaryapi.ErrNotFound)
+	unknown := fmt.Errorf("translate: %w", yandexdictionary.ErrUnknown)
+	for _, tc := range []struct {
+		name    string
+		word    string
+		res     fetchResult
+		known   bool
+		wantErr bool
+	}{
+		{"found", "cat", fetchResult{}, true, false},
+		{"found without translation", "cat", fetchResult{translationErr: unknown}, true, false},
+		{"translation only word", "qwerty", fetchResult{dictErr: notFound}, false, false},
+		{"translation only phrase", "look after", fetchResult{dictErr: notFound}, true, false},
+		{"unknown phrase", "look after", fetchResult{dictErr: notFound, translationErr: unknown}, false, false},
+		{"unknown word", "qwerty", fetchResult{dictErr: notFound, translationErr: unknown}, false, false},
+		{"dictionary error", "cat", fetchResult{dictErr: errors.New("test")}, false, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			known, err := tc.res.known(tc.word)
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.known, known)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rs/zerolog/log"
+)
+
+// refreshPassInterval is a delay between checks of all dictionary items
+const refreshPassInterval = time.Hour
+
+// Refresher fetches incomplete and outdated dictionary items from providers again in background
+type Refresher struct {
+	lookuper Lookuper
+	storage  db.Storage
+	// maxAge is an age of item data after which it's refreshed
+	maxAge time.Duration
+	// interval is a minimal delay between refreshed words to limit providers requests rate
+	interval time.Duration
+}
+
+// NewRefresher creates Refresher
+func NewRefresher(lookuper Lookuper, storage db.Storage, maxAge, interval time.Duration) Refresher {
+	return Refresher{lookuper: lookuper, storage: storage, maxAge: maxAge, interval: interval}
+}
+
+// Run refreshes items until context is cancelled
+func (r Refresher) Run(ctx context.Context) {
+	for {
+		refreshed, err := r.refreshStale(ctx)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Error().Err(err).Msg("failed to refresh dictionary items")
+		}
+		if refreshed != 0 {
+			log.Info().Int("count", refreshed).Msg("dictionary items refreshed")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(refreshPassInterval):
+		}
+	}
+}
+
+// refreshStale refreshes all items needing it and returns number of refreshed items,
+// errors of single items are logged and skipped
+func (r Refresher) refreshStale(ctx context.Context) (int, error) {
+	words, err := r.storage.GetWords()
+	if err != nil {
+		return 0, err
+	}
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	var refreshed int
+	for _, word := range words {
+		item, err := r.storage.Get(word)
+		if err != nil {
+			log.Error().Err(err).Str("word", word).Msg("failed to get dictionary item")
+			continue
+		}
+		if !item.NeedsRefresh(time.Now(), r.maxAge) {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return refreshed, ctx.Err()
+		case <-ticker.C:
+		}
+		// the item might be edited by admin while waiting
+		item, err = r.storage.Get(word)
+		if err != nil {
+			log.Error().Err(err).Str("word", word).Msg("failed to get dictionary item")
+			continue
+		}
+		if !item.NeedsRefresh(time.Now(), r.maxAge) {
+			continue
+		}
+		if _, err := r.lookuper.Refresh(ctx, word, r.storage); err != nil {
+			if errors.Is(err, ErrChanged) {
+				log.Info().Str("word", word).Msg("dictionary item is changed while refreshing, skipped")
+				continue
+			}
+			log.Error().Err(err).Str("word", word).Msg("failed to refresh dictionary item")
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
@@ -0,0 +1,182 @@
+package lookup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubLookuper marks refreshed words as fetched, refreshing "broken" word fails
+// and "changed" word is reported as changed while refreshing
+type stubLookuper struct {
+	refreshed *[]string
+}
+
+func (l stubLookuper) Get(_ context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	return nil, nil
+}
+
+func (l stubLookuper) GetNormalized(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	return nil, nil
+}
+
+func (l stubLookuper) Preview(ctx context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	return nil, nil
+}
+
+func (l stubLookuper) Refresh(_ context.Context, word string, storage db.Storage) (*db.DictionaryItem, error) {
+	if word == "broken" {
+		return nil, errors.New("test")
+	}
+	if word == "changed" {
+		return nil, ErrChanged
+	}
+	*l.refreshed = append(*l.refreshed, word)
+	now := time.Now()
+	item := db.DictionaryItem{Word: word, FetchedAt: &now}
+	return &item, storage.Save(item)
+}
+
+func TestRefresherRefreshStale(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	fresh, old := time.Now(), time.Now().Add(-48*time.Hour)
+	for _, item := range []db.DictionaryItem{
+		{Word: "fresh", FetchedAt: &fresh},
+		{Word: "old", FetchedAt: &old},
+		{Word: "legacy"},
+		{Word: "incomplete", FetchedAt: &fresh, Incomplete: []string{ProviderTranslation}},
+		{Word: "edited", FetchedAt: &old, EditedBy: 1},
+		{Word: "broken", FetchedAt: &old},
+		{Word: "changed", FetchedAt: &old},
+	} {
+		require.NoError(t, storage.Save(item))
+	}
+	var refreshed []string
+	r := NewRefresher(stubLookuper{refreshed: &refreshed}, storage, 24*time.Hour, time.Millisecond)
+
+	count, err := r.refreshStale(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.ElementsMatch(t, []string{"old", "legacy", "incomplete"}, refreshed)
+
+	refreshed = nil
+	count, err = r.refreshStale(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.Empty(t, refreshed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = r.refreshStale(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMergeRefreshed(t *testing.T) {
+	prev := db.DictionaryItem{
+		Word:         "test",
+		Meanings:     []db.Meaning{{PartOfSpeech: "noun", Definition: "old"}},
+		Translations: []db.Translation{{Text: "тест", Language: "ru"}},
+	}
+	prev.Phonetics.Text = "/test/"
+
+	t.Run("failed providers", func(t *testing.T) {
+		next := db.DictionaryItem{
+			Word:       "test",
+			Meanings:   []db.Meaning{{PartOfSpeech: "noun", Definition: "new"}},
+			Incomplete: []string{ProviderTranslation},
+		}
+		res := mergeRefreshed(prev, next)
+		assert.Equal(t, next.Meanings, res.Meanings)
+		assert.Empty(t, res.Phonetics.Text)
+		assert.Equal(t, prev.Translations, res.Translations)
+		assert.Equal(t, []string{ProviderTranslation}, res.Incomplete)
+	})
+	t.Run("empty responses", func(t *testing.T) {
+		res := mergeRefreshed(prev, db.DictionaryItem{Word: "test"})
+		assert.Equal(t, prev.Meanings, res.Meanings)
+		assert.Equal(t, "/test/", res.Phonetics.Text)
+		assert.Equal(t, prev.Translations, res.Translations)
+	})
+	t.Run("new data", func(t *testing.T) {
+		next := db.DictionaryItem{Word: "test", Translations: []db.Translation{{Text: "проверка", Language: "ru"}}}
+		next.Phonetics.Text = "/tɛst/"
+		res := mergeRefreshed(prev, next)
+		assert.Empty(t, res.Meanings)
+		assert.Equal(t, next.Translations, res.Translations)
+	})
+}
+
+func TestSaveRefreshed(t *testing.T) {
+	fetched := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	refreshed := fetched.Add(48 * time.Hour)
+	prev := db.DictionaryItem{
+		Word: "test", FetchedAt: &fetched, Translations: []db.Translation{{Text: "тест", Language: "ru"}},
+	}
+	next := db.DictionaryItem{
+		Word: "test", FetchedAt: &refreshed, Translations: []db.Translation{{Text: "проверка", Language: "ru"}},
+	}
+
+	t.Run("changed data", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		require.NoError(t, storage.Save(prev))
+		item, err := saveRefreshed(storage, prev, 0, next)
+		require.NoError(t, err)
+		assert.Equal(t, next, *item)
+		revisions, err := storage.GetWordRevisions("test")
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, db.UserID(0), revisions[1].Author)
+	})
+	t.Run("same data", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		edited := prev
+		edited.EditedBy = 1
+		require.NoError(t, storage.Save(edited))
+		same := prev
+		same.FetchedAt = &refreshed
+		item, err := saveRefreshed(storage, edited, 0, same)
+		require.NoError(t, err)
+		assert.Equal(t, db.UserID(1), item.EditedBy)
+		assert.Equal(t, refreshed, *item.FetchedAt)
+		revisions, err := storage.GetWordRevisions("test")
+		require.NoError(t, err)
+		assert.Len(t, revisions, 1)
+	})
+	t.Run("edited while refreshing", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		require.NoError(t, storage.Save(prev))
+		edit := db.DictionaryItem{Word: "test", Translations: []db.Translation{{Text: "испытание", Language: "ru"}}}
+		_, err := db.SaveWord(storage, edit, 1, fetched)
+		require.NoError(t, err)
+
+		_, err = saveRefreshed(storage, prev, 0, next)
+		assert.ErrorIs(t, err, ErrChanged)
+		saved, err := storage.Get("test")
+		require.NoError(t, err)
+		assert.Equal(t, db.UserID(1), saved.EditedBy)
+		assert.Equal(t, edit.Translations, saved.Translations)
+	})
+	t.Run("reverted while refreshing", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		require.NoError(t, storage.Save(prev))
+		_, err := db.SaveWord(storage, next, 0, refreshed)
+		require.NoError(t, err)
+		current, err := storage.Get("test")
+		require.NoError(t, err)
+		rev, err := latestRevision(storage, "test")
+		require.NoError(t, err)
+		// revert restores the same data, but makes a newer revision
+		_, err = db.RevertWord(storage, "test", 1, 0, refreshed)
+		require.NoError(t, err)
+		_, err = db.RevertWord(storage, "test", 2, 0, refreshed)
+		require.NoError(t, err)
+
+		_, err = saveRefreshed(storage, current, rev, prev)
+		assert.ErrorIs(t, err, ErrChanged)
+	})
+}
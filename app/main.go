@@ -1,11 +1,15 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
+	"time"
 
 	"github.com/rbhz/tg-dictionary/app/api"
 	"github.com/rbhz/tg-dictionary/app/bot"
 	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/lookup"
 
 	"github.com/jessevdk/go-flags"
 	log "github.com/rs/zerolog/log"
@@ -20,6 +24,10 @@ type Opts struct {
 	YandexDictionaryToken string `long:"yadict-token" env:"YANDEX_DICTIONARY_TOKEN" required:"true" description:"Yandex Dictionary token"`
 	JWTSecret             string `long:"jwt" env:"JWT_SECRET" required:"true" description:"JWT secret"`
 	Port                  int    `long:"port" env:"PORT" default:"8080" description:"Port to listen on"`
+	AdminID               int64  `long:"admin" env:"ADMIN_ID" description:"Telegram ID of the user granted admin role on start"`
+
+	RefreshAge      time.Duration `long:"refresh-age" env:"REFRESH_AGE" default:"720h" description:"Age of dictionary data fetched again, 0 disables refresh"`
+	RefreshInterval time.Duration `long:"refresh-interval" env:"REFRESH_INTERVAL" default:"5s" description:"Delay between refreshed words"`
 }
 
 func main() {
@@ -31,27 +39,65 @@ func main() {
 
 	storage, closeStorage := getStorage(opts)
 	defer closeStorage()
+	if opts.AdminID != 0 {
+		bootstrapAdmin(storage, db.UserID(opts.AdminID))
+	}
+	wordLookup := lookup.NewProvider(opts.YandexDictionaryToken)
+	if opts.RefreshAge > 0 {
+		if opts.RefreshInterval <= 0 {
+			log.Fatal().Dur("interval", opts.RefreshInterval).Msg("refresh interval must be positive")
+		}
+		refresher := lookup.NewRefresher(wordLookup, storage, opts.RefreshAge, opts.RefreshInterval)
+		go refresher.Run(context.Background())
+	}
 
 	// Start API
 	go func() {
-		api := api.NewServer(storage, opts.BotToken, opts.JWTSecret)
+		api := api.NewServer(storage, wordLookup, opts.BotToken, opts.JWTSecret)
 		if err := api.Run(opts.Port); err != nil {
 			log.Fatal().Err(err).Msg("failed to run API server")
 		}
 	}()
 
 	// initialize Telegram bot
+	wordHandler := bot.NewWordHandler(wordLookup)
+	importHandler := bot.NewBulkImportHandler(wordHandler)
+	courseSeeder := bot.NewCourseSeeder(wordHandler)
 	b, err := bot.NewTelegramBot(opts.BotToken, storage, []bot.Handler{
+		bot.NewCourseSeedHandler(courseSeeder),
+		bot.NewGroupHandler(wordHandler),
 		bot.StartHandler{},
+		bot.NewInlineHandler(wordHandler),
+		bot.NewAddWordHandler(wordHandler),
 		// Settings
 		bot.ListSettingsHandler{},
 		bot.SendQuizTypesHandler{},
 		bot.SetQuizTypesHandler{},
+		bot.SendQuizTagsHandler{},
+		bot.SetQuizTagsHandler{},
+		bot.SendMasteryHandler{},
+		bot.SetMasteryHandler{},
 		// Quizzes
 		bot.QuizHandler{},
 		bot.QuizReplyHandler{},
+		// Courses
+		bot.CourseHandler{},
+		bot.NewSetCourseHandler(courseSeeder),
 		// Dictionary
-		bot.NewWordHandler(opts.YandexDictionaryToken),
+		bot.ExportHandler{},
+		bot.EditItemHandler{},
+		bot.EditItemReplyHandler{},
+		bot.TagHandler{},
+		bot.TagsHandler{},
+		bot.ListHandler{},
+		bot.TokenHandler{},
+		bot.NewAdminHandler(wordLookup),
+		bot.NewLookupHandler(wordHandler),
+		bot.NewFileImportHandler(wordHandler),
+		importHandler,
+		bot.ExtractHandler{},
+		bot.NewExtractReplyHandler(importHandler),
+		wordHandler,
 	})
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize telegram bot")
@@ -60,6 +106,26 @@ func main() {
 
 }
 
+// bootstrapAdmin grants admin role to the user and unblocks it, so the first admin
+// doesn't have to be set in the database by hand
+func bootstrapAdmin(storage db.Storage, id db.UserID) {
+	user, err := storage.GetUser(id)
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		user = db.User{ID: id}
+	case err != nil:
+		log.Fatal().Err(err).Int64("user", int64(id)).Msg("failed to get admin user")
+	case user.IsAdmin && !user.Blocked:
+		return
+	}
+	user.IsAdmin = true
+	user.Blocked = false
+	if err := storage.SaveUser(user); err != nil {
+		log.Fatal().Err(err).Int64("user", int64(id)).Msg("failed to save admin user")
+	}
+	log.Info().Int64("user", int64(id)).Msg("admin role granted")
+}
+
 func getStorage(opts Opts) (db.Storage, func()) {
 	if opts.RedisURL != "" {
 		redisStorage, err := db.NewRedisStorage(opts.RedisURL)
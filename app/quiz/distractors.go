@@ -0,0 +1,183 @@
+package quiz
+
+import (
+	_ "embed" // embed common words list
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/suggest"
+
+	"github.com/rs/zerolog/log"
+)
+
+// commonWordsJSON holds a list of common words used as distractors for small dictionaries
+//
+//go:embed data/common_words.json
+var commonWordsJSON []byte
+
+// commonWords holds parsed common words list
+var commonWords = func() []db.DictionaryItem {
+	var words []db.DictionaryItem
+	if err := json.Unmarshal(commonWordsJSON, &words); err != nil {
+		log.Fatal().Err(err).Msg("failed to parse common words list")
+	}
+	return words
+}()
+
+// distractor weights
+const (
+	distractorPartOfSpeechWeight = 3.0
+	distractorSpellingWeight     = 2.0
+	distractorLengthWeight       = 1.0
+	distractorSynonymsWeight     = 2.0
+	distractorWordCountWeight    = 2.0
+)
+
+// distractorPoolFactor defines how many of the best ranked candidates are used for random pick
+const distractorPoolFactor = 2
+
+// distractorCandidate holds a word which may be used as a wrong choice in quiz
+type distractorCandidate struct {
+	word  db.DictionaryItem
+	text  string
+	score float64
+}
+
+// rankDistractors returns suitable candidates sorted by similarity to the correct word,
+// candidates which may be considered correct answers are skipped
+func rankDistractors(correct db.DictionaryItem, qType string, words []db.DictionaryItem) []distractorCandidate {
+	correctText := ChoiceText(correct, qType)
+	result := make([]distractorCandidate, 0, len(words))
+	for _, word := range words {
+		if word.Word == correct.Word || isSynonym(correct, word) {
+			continue
+		}
+		if qType == db.QuizTypeMeanings && len(word.Meanings) == 0 {
+			continue
+		}
+		if qType != db.QuizTypeMeanings && sharesTranslation(correct, word) {
+			continue
+		}
+		text := ChoiceText(word, qType)
+		if text == "" || text == correctText {
+			continue
+		}
+		result = append(result, distractorCandidate{word: word, text: text, score: distractorScore(correct, word)})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].score > result[j].score })
+	return result
+}
+
+// distractorScore estimates how close candidate is to the correct word,
+// higher score makes harder distractor
+func distractorScore(correct, candidate db.DictionaryItem) float64 {
+	var score float64
+	if sharesPartOfSpeech(correct, candidate) {
+		score += distractorPartOfSpeechWeight
+	}
+	score += distractorSpellingWeight * spellingSimilarity(correct.Word, candidate.Word)
+	score += distractorLengthWeight * lengthSimilarity(correct.Word, candidate.Word)
+	if sharesSynonyms(correct, candidate) {
+		score += distractorSynonymsWeight
+	}
+	// phrases among single words are easy to spot, so prefer same number of words
+	if len(strings.Fields(correct.Word)) == len(strings.Fields(candidate.Word)) {
+		score += distractorWordCountWeight
+	}
+	return score
+}
+
+// partsOfSpeech returns set of parts of speech for dictionary item
+func partsOfSpeech(item db.DictionaryItem) map[string]struct{} {
+	result := make(map[string]struct{})
+	for _, m := range item.Meanings {
+		result[m.PartOfSpeech] = struct{}{}
+	}
+	for _, t := range item.Translations {
+		result[t.PartOfSpeech] = struct{}{}
+	}
+	delete(result, "")
+	return result
+}
+
+func sharesPartOfSpeech(a, b db.DictionaryItem) bool {
+	aParts := partsOfSpeech(a)
+	for p := range partsOfSpeech(b) {
+		if _, ok := aParts[p]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// synonyms returns set of synonyms for dictionary item
+func synonyms(item db.DictionaryItem) map[string]struct{} {
+	result := make(map[string]struct{})
+	for _, m := range item.Meanings {
+		for _, s := range m.Synonyms {
+			result[strings.ToLower(s)] = struct{}{}
+		}
+	}
+	return result
+}
+
+// isSynonym returns true if one of the words is listed as a synonym of another
+func isSynonym(a, b db.DictionaryItem) bool {
+	if _, ok := synonyms(a)[b.Word]; ok {
+		return true
+	}
+	_, ok := synonyms(b)[a.Word]
+	return ok
+}
+
+// sharesSynonyms returns true if words have common synonyms
+func sharesSynonyms(a, b db.DictionaryItem) bool {
+	aSynonyms := synonyms(a)
+	for s := range synonyms(b) {
+		if _, ok := aSynonyms[s]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sharesTranslation returns true if words have common translation
+func sharesTranslation(a, b db.DictionaryItem) bool {
+	aTranslations := make(map[string]struct{}, len(a.Translations))
+	for _, t := range a.Translations {
+		aTranslations[strings.ToLower(t.Text)] = struct{}{}
+	}
+	for _, t := range b.Translations {
+		if _, ok := aTranslations[strings.ToLower(t.Text)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// spellingSimilarity returns value in [0, 1] range based on edit distance between words,
+// typo-like differences make words more similar
+func spellingSimilarity(a, b string) float64 {
+	maxLen := len([]rune(a))
+	if l := len([]rune(b)); l > maxLen {
+		maxLen = l
+	}
+	if maxLen == 0 {
+		return 1
+	}
+	return 1 - suggest.Distance(a, b)/float64(maxLen)
+}
+
+// lengthSimilarity returns value in [0, 1] range based on words lengths difference
+func lengthSimilarity(a, b string) float64 {
+	aLen, bLen := len([]rune(a)), len([]rune(b))
+	if aLen < bLen {
+		aLen, bLen = bLen, aLen
+	}
+	if aLen == 0 {
+		return 1
+	}
+	return float64(bLen) / float64(aLen)
+}
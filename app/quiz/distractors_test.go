@@ -0,0 +1,72 @@
+package quiz
+
+import (
+	"testing"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankDistractors(t *testing.T) {
+	correct := getItem(t, "cat", "кошка", "a small mammal")
+	words := []db.DictionaryItem{
+		getItem(t, "table", "стол", "a piece of furniture"),
+		getItem(t, "car", "машина", "a road vehicle"),
+		getItem(t, "kitty", "кошка", "a young cat"),
+		correct,
+	}
+	res := rankDistractors(correct, db.QuizTypeTranslations, words)
+	require.Len(t, res, 2)
+	assert.Equal(t, "car", res[0].word.Word)
+	assert.Equal(t, "table", res[1].word.Word)
+
+	t.Run("synonyms", func(t *testing.T) {
+		correct := getItem(t, "big", "большой", "of considerable size")
+		correct.Meanings[0].Synonyms = []string{"Large"}
+		words := []db.DictionaryItem{getItem(t, "large", "крупный", "of great size"), getItem(t, "bag", "сумка", "a container")}
+		res := rankDistractors(correct, db.QuizTypeTranslations, words)
+		require.Len(t, res, 1)
+		assert.Equal(t, "bag", res[0].word.Word)
+	})
+	t.Run("meanings", func(t *testing.T) {
+		noMeanings := db.DictionaryItem{Word: "cap", Translations: []db.Translation{{Text: "кепка", Language: "ru"}}}
+		res := rankDistractors(correct, db.QuizTypeMeanings, []db.DictionaryItem{noMeanings, words[0]})
+		require.Len(t, res, 1)
+		assert.Equal(t, "a piece of furniture", res[0].text)
+	})
+	t.Run("phrases", func(t *testing.T) {
+		words := []db.DictionaryItem{getItem(t, "cat nap", "дремота", "a short sleep"), getItem(t, "dog", "собака", "a pet")}
+		res := rankDistractors(correct, db.QuizTypeTranslations, words)
+		require.Len(t, res, 2)
+		assert.Equal(t, "dog", res[0].word.Word)
+	})
+}
+
+func TestSpellingSimilarity(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		want float64
+	}{
+		{"cat", "cat", 1},
+		{"", "", 1},
+		{"cat", "mop", 0},
+		{"cat", "cap", 2.0 / 3},
+		// adjacent keys typo is closer than other substitutions
+		{"cat", "cst", 1 - 0.5/3},
+		{"form", "from", 1 - 0.75/4},
+		{"cat", "", 0},
+	} {
+		t.Run(tc.a+"-"+tc.b, func(t *testing.T) {
+			assert.InDelta(t, tc.want, spellingSimilarity(tc.a, tc.b), 1e-9)
+			assert.InDelta(t, tc.want, spellingSimilarity(tc.b, tc.a), 1e-9)
+		})
+	}
+}
+
+func TestLengthSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, lengthSimilarity("", ""))
+	assert.Equal(t, 1.0, lengthSimilarity("cat", "dog"))
+	assert.Equal(t, 0.5, lengthSimilarity("cat", "catcat"))
+	assert.InDelta(t, 3.0/7, lengthSimilarity("котёнок", "кот"), 1e-9)
+}
@@ -0,0 +1,400 @@
+// Package quiz generates quizzes from user dictionaries and group word lists
+package quiz
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/rbhz/tg-dictionary/app/wordlist"
+	"github.com/rs/zerolog/log"
+)
+
+// ChoiceLanguage is a language of translations used in quizzes
+const ChoiceLanguage = "ru"
+
+// ChoicesCount is a number of choices in quiz
+const ChoicesCount = 4
+
+// fallbackListSize limits number of word list words used as distractors
+const fallbackListSize = 100
+
+// masteredReviewChance is a chance to quiz a mastered word instead of a word being learned
+const masteredReviewChance = 0.1
+
+// quiz generation errors
+var (
+	ErrEmptyDictionary = errors.New("empty dictionary")
+	ErrNoTaggedWords   = errors.New("no tagged words")
+	ErrNotEnoughWords  = errors.New("not enough words")
+)
+
+// New generates quiz for the user and saves it, quizzed words are restricted to the tags.
+// Mastered words are moved to a review pool which is used only occasionally
+func New(storage db.Storage, user db.User, tags []string) (db.Quiz, error) {
+	dictionary, err := storage.GetUserDictionary(user.ID)
+	if err != nil {
+		return db.Quiz{}, fmt.Errorf("get user dictionary: %w", err)
+	}
+	if len(dictionary) == 0 {
+		return db.Quiz{}, ErrEmptyDictionary
+	}
+	for userItem, item := range dictionary {
+		dictionary[userItem] = userItem.Personalize(item)
+	}
+	// only tagged words are quizzed, but the whole dictionary is used for choices
+	tagged := db.FilterByTags(dictionary, tags)
+	if len(tagged) == 0 {
+		return db.Quiz{}, ErrNoTaggedWords
+	}
+	quizWord, err := PickWord(tagged, user.Config.EffectiveMasteryThreshold())
+	if err != nil {
+		return db.Quiz{}, err
+	}
+	var fallbackWords []db.DictionaryItem
+	fallback := func() []db.DictionaryItem {
+		if fallbackWords == nil {
+			fallbackWords = append(courseWords(storage, user, fallbackListSize), commonWords...)
+		}
+		return fallbackWords
+	}
+	quizType, choices, err := TypeAndChoices(quizWord, user.Config.EnabledQuizTypes(), dictionary, fallback)
+	if err != nil {
+		return db.Quiz{}, err
+	}
+	displayWord := DisplayWord(dictionary[quizWord], quizType)
+	quiz := db.NewQuiz(user.ID, quizWord.Word, displayWord, ChoiceLanguage, choices, quizType)
+	if err := storage.SaveQuiz(quiz); err != nil {
+		return db.Quiz{}, fmt.Errorf("save quiz: %w", err)
+	}
+	return quiz, nil
+}
+
+// NewGroup generates quiz for a random word from group list and saves it,
+// common words are used as distractors for small lists
+func NewGroup(storage db.Storage, group db.Group) (db.GroupQuiz, error) {
+	dictionary := make(map[db.UserDictionaryItem]db.DictionaryItem, len(group.Words))
+	for _, word := range group.Words {
+		item, err := storage.Get(word)
+		if err != nil {
+			log.Error().Err(err).Str("word", word).Msg("failed to get item")
+			continue
+		}
+		dictionary[db.UserDictionaryItem{Word: word}] = item
+	}
+	quizWord, err := RandomWord(dictionary)
+	if err != nil {
+		return db.GroupQuiz{}, err
+	}
+	fallback := func() []db.DictionaryItem { return commonWords }
+	quizType, choices, err := TypeAndChoices(quizWord, db.QuizTypes, dictionary, fallback)
+	if err != nil {
+		return db.GroupQuiz{}, fmt.Errorf("get choices for %q: %w", quizWord.Word, err)
+	}
+	displayWord := DisplayWord(dictionary[quizWord], quizType)
+	quiz := db.NewGroupQuiz(group.ID, quizWord.Word, displayWord, ChoiceLanguage, choices, quizType)
+	if err := storage.SaveGroupQuiz(quiz); err != nil {
+		return db.GroupQuiz{}, fmt.Errorf("save group quiz: %w", err)
+	}
+	return quiz, nil
+}
+
+// SplitMastered splits user dictionary to words being learned and mastered ones
+func SplitMastered(
+	dictionary map[db.UserDictionaryItem]db.DictionaryItem, threshold int,
+) (learning, mastered map[db.UserDictionaryItem]db.DictionaryItem) {
+	learning = make(map[db.UserDictionaryItem]db.DictionaryItem, len(dictionary))
+	mastered = make(map[db.UserDictionaryItem]db.DictionaryItem)
+	for userItem, item := range dictionary {
+		if userItem.IsMastered(threshold) {
+			mastered[userItem] = item
+		} else {
+			learning[userItem] = item
+		}
+	}
+	return learning, mastered
+}
+
+// getPools returns user dictionary parts in order they should be used for picking quiz word:
+// mastered words are picked first only occasionally
+func getPools(
+	dictionary map[db.UserDictionaryItem]db.DictionaryItem, threshold int,
+) []map[db.UserDictionaryItem]db.DictionaryItem {
+	learning, mastered := SplitMastered(dictionary, threshold)
+	if rand.Float64() < masteredReviewChance {
+		return []map[db.UserDictionaryItem]db.DictionaryItem{mastered, learning}
+	}
+	return []map[db.UserDictionaryItem]db.DictionaryItem{learning, mastered}
+}
+
+// PickWord returns random word to quiz, mastered words are picked only occasionally
+// or if there are no other suitable words
+func PickWord(dict map[db.UserDictionaryItem]db.DictionaryItem, masteryThreshold int) (db.UserDictionaryItem, error) {
+	for _, pool := range getPools(dict, masteryThreshold) {
+		if len(pool) == 0 {
+			continue
+		}
+		word, err := RandomWord(pool)
+		if !errors.Is(err, ErrNotEnoughWords) {
+			return word, err
+		}
+	}
+	return db.UserDictionaryItem{}, ErrNotEnoughWords
+}
+
+// DisplayWord returns text shown as a quiz question,
+// translation is shown instead of the word for reverse translations quizzes
+func DisplayWord(item db.DictionaryItem, qType string) string {
+	if qType == db.QuizTypeReverseTranslations {
+		for _, tr := range item.Translations {
+			if tr.Language == ChoiceLanguage {
+				return tr.Text
+			}
+		}
+	}
+	return item.Word
+}
+
+// RandomWord returns random word from dictionary based on last quiz time
+func RandomWord(dict map[db.UserDictionaryItem]db.DictionaryItem) (db.UserDictionaryItem, error) {
+	if len(dict) == 0 {
+		return db.UserDictionaryItem{}, ErrEmptyDictionary
+	}
+	var minTime, maxTime *time.Time
+	items := make([]db.UserDictionaryItem, 0, len(dict))
+
+	for item, word := range dict {
+		var hasTranslation bool
+		for _, t := range word.Translations {
+			if t.Language == ChoiceLanguage {
+				hasTranslation = true
+				break
+			}
+		}
+		if !hasTranslation {
+			continue
+		}
+		if len(dict[item].Translations) == 0 {
+			continue
+		}
+		if item.LastQuiz != nil {
+			if minTime == nil || item.LastQuiz.Before(*minTime) {
+				minTime = item.LastQuiz
+			}
+			if maxTime == nil || item.LastQuiz.After(*maxTime) {
+				maxTime = item.LastQuiz
+			}
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return db.UserDictionaryItem{}, ErrNotEnoughWords
+	}
+
+	if minTime == nil || maxTime == nil {
+		return items[rand.Intn(len(items))], nil
+	}
+	weights := make(map[db.UserDictionaryItem]int, len(dict))
+	timeRange := maxTime.Sub(*minTime).Seconds()
+	var totalWeight uint
+	for _, item := range items {
+		weight := 100
+		if item.LastQuiz != nil {
+			if timeRange > 0 {
+				weight = int(maxTime.Sub(*item.LastQuiz).Seconds() / timeRange * 100)
+			} else {
+				weight = 0
+			}
+		}
+		if weight == 0 {
+			weight = 1
+		}
+		totalWeight += uint(weight)
+		weights[item] = weight
+	}
+	value := rand.Intn(int(totalWeight))
+	result := db.UserDictionaryItem{}
+	for _, item := range items {
+		value -= weights[item]
+		result = item
+		if value <= 0 {
+			break
+		}
+	}
+	return result, nil
+}
+
+// TypeAndChoices picks quiz type for the word and generates choices for it,
+// types without enough suitable words are skipped
+func TypeAndChoices(
+	item db.UserDictionaryItem,
+	types []string,
+	dict map[db.UserDictionaryItem]db.DictionaryItem,
+	fallback func() []db.DictionaryItem,
+) (string, []db.QuizItem, error) {
+	types = getSuitableTypes(dict[item], types)
+	for len(types) != 0 {
+		qType := pickType(item, types)
+		choices, err := getChoices(item, qType, dict, fallback, ChoicesCount)
+		if err == nil {
+			return qType, choices, nil
+		}
+		if !errors.Is(err, ErrNotEnoughWords) {
+			return "", nil, err
+		}
+		for idx, t := range types {
+			if t == qType {
+				types = append(types[:idx:idx], types[idx+1:]...)
+				break
+			}
+		}
+	}
+	return "", nil, ErrNotEnoughWords
+}
+
+// getSuitableTypes filters out quiz types which can't be used for the word
+func getSuitableTypes(word db.DictionaryItem, types []string) []string {
+	result := make([]string, 0, len(types))
+	for _, t := range types {
+		if t == db.QuizTypeMeanings && len(word.Meanings) == 0 {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// pickType returns random quiz type,
+// types with lower accuracy for the item have higher chance to be picked
+func pickType(item db.UserDictionaryItem, types []string) string {
+	weights := make([]float64, len(types))
+	var totalWeight float64
+	for idx, t := range types {
+		weights[idx] = 1 - item.TypeStats(t).Accuracy()
+		totalWeight += weights[idx]
+	}
+	value := rand.Float64() * totalWeight
+	for idx, t := range types {
+		value -= weights[idx]
+		if value <= 0 {
+			return t
+		}
+	}
+	return types[len(types)-1]
+}
+
+// getChoices returns choices for the word, wrong choices are picked among the most similar words
+// from the dictionary, fallback words are used if the dictionary is too small
+func getChoices(
+	item db.UserDictionaryItem,
+	qType string,
+	dict map[db.UserDictionaryItem]db.DictionaryItem,
+	fallback func() []db.DictionaryItem,
+	count int,
+) ([]db.QuizItem, error) {
+	correctWord, ok := dict[item]
+	if !ok {
+		return nil, errors.New("item not in dictionary")
+	}
+	words := make([]db.DictionaryItem, 0, len(dict))
+	known := make(map[string]struct{}, len(dict))
+	for _, word := range dict {
+		words = append(words, word)
+		known[word.Word] = struct{}{}
+	}
+	candidates := rankDistractors(correctWord, qType, words)
+	if len(candidates) < count-1 {
+		extra := make([]db.DictionaryItem, 0)
+		for _, word := range fallback() {
+			if _, ok := known[word.Word]; !ok {
+				known[word.Word] = struct{}{}
+				extra = append(extra, word)
+			}
+		}
+		candidates = append(candidates, rankDistractors(correctWord, qType, extra)...)
+	}
+	if len(candidates) < count-1 {
+		return nil, ErrNotEnoughWords
+	}
+	poolSize := (count - 1) * distractorPoolFactor
+	if poolSize > len(candidates) {
+		poolSize = len(candidates)
+	}
+	pool := candidates[:poolSize]
+	rand.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	choices := make([]db.QuizItem, 0, count)
+	texts := map[string]struct{}{ChoiceText(correctWord, qType): {}}
+	for _, c := range candidates {
+		if len(choices) == count-1 {
+			break
+		}
+		if _, ok := texts[c.text]; ok {
+			continue
+		}
+		texts[c.text] = struct{}{}
+		choices = append(choices, db.QuizItem{Word: c.word.Word, Text: c.text, Correct: false})
+	}
+	if len(choices) < count-1 {
+		return nil, ErrNotEnoughWords
+	}
+	correctChoice := db.QuizItem{Word: correctWord.Word, Text: ChoiceText(correctWord, qType), Correct: true}
+	choices = append(choices, correctChoice)
+
+	sort.Slice(choices, func(i, j int) bool { return choices[i].Word < choices[j].Word })
+	return choices, nil
+}
+
+// ChoiceText returns choice text based on quiz type
+func ChoiceText(word db.DictionaryItem, qType string) (text string) {
+	switch qType {
+	case db.QuizTypeTranslations:
+		translations := make([]string, 0, len(word.Translations))
+		for _, translation := range word.Translations {
+			if translation.Language == ChoiceLanguage {
+				translations = append(translations, translation.Text)
+			}
+		}
+		text = strings.Join(translations, ", ")
+	case db.QuizTypeReverseTranslations:
+		text = word.Word
+	case db.QuizTypeMeanings:
+		if len(word.Meanings) != 0 {
+			text = word.Meanings[0].Definition
+		}
+	}
+	return
+}
+
+// courseWords returns cached dictionary items from the user's course level,
+// they are used as quiz distractors
+func courseWords(storage db.Storage, user db.User, limit int) []db.DictionaryItem {
+	level := wordlist.LevelA1
+	if user.Config.Course != nil {
+		level = wordlist.Level(user.Config.Course.Level)
+	}
+	list, err := wordlist.Words(level)
+	if err != nil {
+		log.Error().Err(err).Str("level", string(level)).Msg("failed to get word list")
+		return nil
+	}
+	if len(list) > limit {
+		list = list[:limit]
+	}
+	result := make([]db.DictionaryItem, 0, len(list))
+	for _, word := range list {
+		item, err := storage.Get(word)
+		if err != nil {
+			if !errors.Is(err, db.ErrNotFound) {
+				log.Error().Err(err).Str("word", word).Msg("failed to get word")
+			}
+			continue
+		}
+		result = append(result, item)
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package quiz
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/rbhz/tg-dictionary/app/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getItem returns dictionary item with a translation and a meaning
+func getItem(t *testing.T, word, translation, definition string) db.DictionaryItem {
+	var item db.DictionaryItem
+	jdata := fmt.Sprintf(
+		`{"Word": %q, "Meanings": [{"PartOfSpeech": "noun", "Definition": %q}], `+
+			`"Translations": [{"Text": %q, "Language": "ru", "PartOfSpeech": "noun"}]}`,
+		word, definition, translation,
+	)
+	require.NoError(t, json.Unmarshal([]byte(jdata), &item))
+	return item
+}
+
+func TestNew(t *testing.T) {
+	user := db.User{ID: db.UserID(1)}
+	t.Run("success", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		item := getItem(t, "cat", "кошка", "a small domesticated carnivorous mammal")
+		require.NoError(t, storage.Save(item))
+		require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: user.ID, Word: "cat"}))
+
+		quiz, err := New(storage, user, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "cat", quiz.Word)
+		assert.Equal(t, "cat", quiz.DisplayWord)
+		assert.Equal(t, db.QuizTypeTranslations, quiz.Type)
+		require.Len(t, quiz.Choices, ChoicesCount)
+		var correct []string
+		for _, c := range quiz.Choices {
+			if c.Correct {
+				correct = append(correct, c.Text)
+			}
+		}
+		assert.Equal(t, []string{"кошка"}, correct)
+		saved, err := storage.GetQuiz(quiz.ID)
+		require.NoError(t, err)
+		assert.Equal(t, quiz, saved)
+	})
+	t.Run("empty dictionary", func(t *testing.T) {
+		_, err := New(db.NewInMemoryStorage(), user, nil)
+		assert.ErrorIs(t, err, ErrEmptyDictionary)
+	})
+	t.Run("no tagged words", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: user.ID, Word: "cat"}))
+		_, err := New(storage, user, []string{"work"})
+		assert.ErrorIs(t, err, ErrNoTaggedWords)
+	})
+	t.Run("no translations", func(t *testing.T) {
+		storage := db.NewInMemoryStorage()
+		require.NoError(t, storage.Save(db.DictionaryItem{Word: "cat"}))
+		require.NoError(t, storage.SaveUserItem(db.UserDictionaryItem{User: user.ID, Word: "cat"}))
+		_, err := New(storage, user, nil)
+		assert.ErrorIs(t, err, ErrNotEnoughWords)
+	})
+}
+
+func TestNewGroup(t *testing.T) {
+	storage := db.NewInMemoryStorage()
+	require.NoError(t, storage.Save(getItem(t, "cat", "кошка", "a small domesticated carnivorous mammal")))
+
+	quiz, err := NewGroup(storage, db.Group{ID: db.ChatID(-1), Words: []string{"cat"}})
+	require.NoError(t, err)
+	assert.Equal(t, "cat", quiz.Word)
+	assert.Len(t, quiz.Choices, ChoicesCount)
+	_, err = storage.GetGroupQuiz(quiz.ID)
+	assert.NoError(t, err)
+
+	_, err = NewGroup(storage, db.Group{ID: db.ChatID(-1)})
+	assert.ErrorIs(t, err, ErrEmptyDictionary)
+}
+
+func TestChoiceText(t *testing.T) {
+	item := getItem(t, "cat", "кошка", "a small mammal")
+	assert.Equal(t, "кошка", ChoiceText(item, db.QuizTypeTranslations))
+	assert.Equal(t, "cat", ChoiceText(item, db.QuizTypeReverseTranslations))
+	assert.Equal(t, "a small mammal", ChoiceText(item, db.QuizTypeMeanings))
+	assert.Equal(t, "кошка", DisplayWord(item, db.QuizTypeReverseTranslations))
+	assert.Equal(t, "cat", DisplayWord(item, db.QuizTypeMeanings))
+}
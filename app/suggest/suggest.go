@@ -0,0 +1,161 @@
+// Package suggest finds known words similar to misspelled ones
+package suggest
+
+import (
+	"sort"
+	"strings"
+)
+
+// edit costs
+const (
+	costEdit          = 1.0
+	costAdjacentKey   = 0.5
+	costTransposition = 0.75
+)
+
+// keyboardRows holds QWERTY keyboard layout used to find adjacent keys
+var keyboardRows = []string{"qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// keyPositions maps letters to their row and column on the keyboard
+var keyPositions = func() map[rune][2]int {
+	res := make(map[rune][2]int)
+	for row, keys := range keyboardRows {
+		for col, key := range keys {
+			res[key] = [2]int{row, col}
+		}
+	}
+	return res
+}()
+
+// isAdjacentKey returns true if keys are neighbours on QWERTY keyboard
+func isAdjacentKey(a, b rune) bool {
+	pa, okA := keyPositions[a]
+	pb, okB := keyPositions[b]
+	if !okA || !okB {
+		return false
+	}
+	rowDiff, colDiff := pa[0]-pb[0], pa[1]-pb[1]
+	switch rowDiff {
+	case 0:
+		return colDiff == 1 || colDiff == -1
+	case 1:
+		// lower rows are shifted right
+		return colDiff == 0 || colDiff == -1
+	case -1:
+		return colDiff == 0 || colDiff == 1
+	}
+	return false
+}
+
+// Distance returns weighted Damerau-Levenshtein distance between words,
+// substitutions of adjacent keys and transpositions are cheaper than other edits
+func Distance(a, b string) float64 {
+	ar, br := []rune(a), []rune(b)
+	d := make([][]float64, len(ar)+1)
+	for i := range d {
+		d[i] = make([]float64, len(br)+1)
+		d[i][0] = float64(i) * costEdit
+	}
+	for j := range d[0] {
+		d[0][j] = float64(j) * costEdit
+	}
+	for i := 1; i <= len(ar); i++ {
+		for j := 1; j <= len(br); j++ {
+			substitution := 0.0
+			if ar[i-1] != br[j-1] {
+				substitution = costEdit
+				if isAdjacentKey(ar[i-1], br[j-1]) {
+					substitution = costAdjacentKey
+				}
+			}
+			d[i][j] = min(
+				d[i-1][j]+costEdit,
+				d[i][j-1]+costEdit,
+				d[i-1][j-1]+substitution,
+			)
+			if i > 1 && j > 1 && ar[i-1] == br[j-2] && ar[i-2] == br[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+costTransposition)
+			}
+		}
+	}
+	return d[len(ar)][len(br)]
+}
+
+func min(values ...float64) float64 {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
+
+// maxDistance returns distance limit for suggestions based on word length
+func maxDistance(word string) float64 {
+	switch l := len([]rune(word)); {
+	case l <= 4:
+		return 1
+	case l <= 8:
+		return 2
+	default:
+		return 2.5
+	}
+}
+
+// Engine holds known words to pick suggestions from
+type Engine struct {
+	words []string
+}
+
+// Suggest returns up to limit known words closest to the word,
+// equally distant words are ordered as they were passed to New
+func (e *Engine) Suggest(word string, limit int) []string {
+	word = strings.ToLower(strings.TrimSpace(word))
+	limitDistance := maxDistance(word)
+	wordLen := len([]rune(word))
+
+	type candidate struct {
+		word     string
+		distance float64
+	}
+	var candidates []candidate
+	for _, w := range e.words {
+		if w == word {
+			continue
+		}
+		lenDiff := float64(len([]rune(w)) - wordLen)
+		if lenDiff > limitDistance || -lenDiff > limitDistance {
+			continue
+		}
+		if distance := Distance(word, w); distance <= limitDistance {
+			candidates = append(candidates, candidate{word: w, distance: distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	result := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		result = append(result, c.word)
+	}
+	return result
+}
+
+// New creates Engine from words lists, duplicates are skipped
+func New(lists ...[]string) *Engine {
+	seen := make(map[string]struct{})
+	var words []string
+	for _, list := range lists {
+		for _, w := range list {
+			w = strings.ToLower(strings.TrimSpace(w))
+			if _, ok := seen[w]; ok || w == "" {
+				continue
+			}
+			seen[w] = struct{}{}
+			words = append(words, w)
+		}
+	}
+	return &Engine{words: words}
+}
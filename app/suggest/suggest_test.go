@@ -0,0 +1,60 @@
+package suggest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected float64
+	}{
+		{"word", "word", 0},
+		{"word", "words", 1},
+		{"word", "ward", 1},
+		{"word", "wprd", 0.5},
+		{"word", "owrd", 0.75},
+		{"", "abc", 3},
+		{"кот", "кит", 1},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, Distance(c.a, c.b), "%v-%v", c.a, c.b)
+		assert.Equal(t, c.expected, Distance(c.b, c.a), "%v-%v", c.b, c.a)
+	}
+}
+
+func TestIsAdjacentKey(t *testing.T) {
+	for _, pair := range []string{"qw", "as", "qa", "wa", "sz", "hb", "ol"} {
+		r := []rune(pair)
+		assert.True(t, isAdjacentKey(r[0], r[1]), pair)
+		assert.True(t, isAdjacentKey(r[1], r[0]), pair)
+	}
+	for _, pair := range []string{"qe", "qs", "ax", "pz", "a1", "aa"} {
+		r := []rune(pair)
+		assert.False(t, isAdjacentKey(r[0], r[1]), pair)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	engine := New(
+		[]string{"house", "horse", "mouse", "hose"},
+		[]string{"House", "because", "believe", "receive"},
+	)
+	t.Run("keyboard proximity first", func(t *testing.T) {
+		assert.Equal(t, []string{"house", "horse", "mouse", "hose"}, engine.Suggest("hiuse", 5))
+	})
+	t.Run("limit", func(t *testing.T) {
+		assert.Equal(t, []string{"house", "horse"}, engine.Suggest("hiuse", 2))
+	})
+	t.Run("transposition", func(t *testing.T) {
+		assert.Equal(t, []string{"believe", "receive"}, engine.Suggest("beleive", 3))
+	})
+	t.Run("exact word skipped", func(t *testing.T) {
+		assert.NotContains(t, engine.Suggest("house", 5), "house")
+	})
+	t.Run("nothing close", func(t *testing.T) {
+		assert.Empty(t, engine.Suggest("xyzzy", 3))
+	})
+}
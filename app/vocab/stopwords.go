@@ -0,0 +1,26 @@
+package vocab
+
+// stopwords holds function words which are never worth learning separately
+var stopwords = toSet([]string{
+	"a", "about", "above", "after", "again", "against", "all", "am", "an", "and", "any", "are", "as", "at",
+	"be", "because", "been", "before", "being", "below", "between", "both", "but", "by",
+	"can", "could", "did", "do", "does", "doing", "down", "during",
+	"each", "few", "for", "from", "further", "had", "has", "have", "having", "he", "her", "here", "hers",
+	"herself", "him", "himself", "his", "how", "i", "if", "in", "into", "is", "it", "its", "itself",
+	"just", "me", "might", "more", "most", "must", "my", "myself", "no", "nor", "not", "now",
+	"of", "off", "on", "once", "only", "or", "other", "ought", "our", "ours", "ourselves", "out", "over", "own",
+	"same", "shall", "she", "should", "so", "some", "such", "than", "that", "the", "their", "theirs", "them",
+	"themselves", "then", "there", "these", "they", "this", "those", "through", "to", "too",
+	"under", "until", "up", "upon", "very", "was", "we", "were", "what", "when", "where", "which", "while",
+	"who", "whom", "whose", "why", "will", "with", "would", "yet", "you", "your", "yours", "yourself",
+	"yourselves", "also", "although", "another", "however", "many", "much", "per", "since", "though",
+	"unless", "whether", "within", "without", "one", "two", "three", "mr", "mrs", "ms",
+})
+
+func toSet(words []string) map[string]struct{} {
+	res := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		res[w] = struct{}{}
+	}
+	return res
+}
@@ -0,0 +1,76 @@
+// Package vocab extracts candidate vocabulary from a text
+package vocab
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/rbhz/tg-dictionary/app/lemma"
+)
+
+// minWordLength is a minimum length of extracted word
+const minWordLength = 3
+
+// Words splits text into lower cased words,
+// apostrophes and hyphens are kept inside of words
+func Words(text string) []string {
+	tokens := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\'' && r != '’' && r != '-'
+	})
+	result := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		token = strings.Trim(token, "'’-")
+		if token != "" {
+			result = append(result, token)
+		}
+	}
+	return result
+}
+
+// Extract returns up to limit lemmas of the text words worth learning,
+// stopwords, possessive and contracted forms and words from the skip lists are ignored.
+// Words are ordered by number of occurrences, then by first appearance
+func Extract(text string, limit int, skip ...[]string) []string {
+	skipped := make(map[string]struct{})
+	for _, list := range skip {
+		for _, w := range list {
+			skipped[strings.ToLower(w)] = struct{}{}
+		}
+	}
+	counts := make(map[string]int)
+	var order []string
+	for _, word := range Words(text) {
+		if !isCandidate(word) {
+			continue
+		}
+		word = lemma.Lemmatize(word)
+		if _, ok := skipped[word]; ok {
+			continue
+		}
+		if _, ok := stopwords[word]; ok {
+			continue
+		}
+		if counts[word] == 0 {
+			order = append(order, word)
+		}
+		counts[word]++
+	}
+	sort.SliceStable(order, func(i, j int) bool { return counts[order[i]] > counts[order[j]] })
+	if len(order) > limit {
+		order = order[:limit]
+	}
+	return order
+}
+
+// isCandidate returns true if word is long enough, isn't a stopword or a contraction
+func isCandidate(word string) bool {
+	if len([]rune(word)) < minWordLength {
+		return false
+	}
+	if strings.ContainsAny(word, "'’") {
+		return false
+	}
+	_, ok := stopwords[word]
+	return !ok
+}
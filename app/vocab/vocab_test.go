@@ -0,0 +1,42 @@
+package vocab
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWords(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"it's", "a", "well-known", "fact", "children", "don’t", "read", "enough", "times"},
+		Words("It's a well-known fact: children don’t read enough -- 42 times!"),
+	)
+	assert.Empty(t, Words(" 123, ... "))
+}
+
+func TestExtract(t *testing.T) {
+	text := "The old lighthouse keeper was climbing the stairs. He climbed them every night, " +
+		"and the lighthouse kept shining while storms were raging. Don't worry, it's fine."
+	t.Run("success", func(t *testing.T) {
+		assert.Equal(
+			t,
+			[]string{"lighthouse", "climb", "old", "keeper", "stair", "every", "night", "keep", "shine", "storm",
+				"rage", "worry", "fine"},
+			Extract(text, 20),
+		)
+	})
+	t.Run("limit", func(t *testing.T) {
+		assert.Equal(t, []string{"lighthouse", "climb", "old"}, Extract(text, 3))
+	})
+	t.Run("skip lists", func(t *testing.T) {
+		assert.Equal(
+			t,
+			[]string{"lighthouse", "climb", "keeper", "stair", "shine", "storm", "rage"},
+			Extract(text, 20, []string{"old", "every", "Night"}, []string{"keep", "worry", "fine"}),
+		)
+	})
+	t.Run("empty", func(t *testing.T) {
+		assert.Empty(t, Extract("It is what it is.", 10))
+	})
+}
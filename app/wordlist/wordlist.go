@@ -0,0 +1,80 @@
+// Package wordlist provides built-in lists of frequent English words grouped by CEFR level
+package wordlist
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+//go:embed lists/*.txt
+var lists embed.FS
+
+// Level is a CEFR language level
+type Level string
+
+// supported levels
+const (
+	LevelA1 Level = "A1"
+	LevelA2 Level = "A2"
+	LevelB1 Level = "B1"
+	LevelB2 Level = "B2"
+	LevelC1 Level = "C1"
+)
+
+// Levels lists all supported levels from the easiest one
+var Levels = []Level{LevelA1, LevelA2, LevelB1, LevelB2, LevelC1}
+
+// ErrUnknownLevel is returned for unsupported levels
+var ErrUnknownLevel = errors.New("unknown level")
+
+// ParseLevel returns level by its name, case insensitive
+func ParseLevel(name string) (Level, error) {
+	level := Level(strings.ToUpper(strings.TrimSpace(name)))
+	for _, l := range Levels {
+		if l == level {
+			return level, nil
+		}
+	}
+	return "", ErrUnknownLevel
+}
+
+// Words returns words for the level ordered by frequency
+func Words(level Level) ([]string, error) {
+	if _, err := ParseLevel(string(level)); err != nil {
+		return nil, err
+	}
+	data, err := lists.ReadFile(fmt.Sprintf("lists/%s.txt", strings.ToLower(string(level))))
+	if err != nil {
+		return nil, fmt.Errorf("read list: %w", err)
+	}
+	var words []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan list: %w", err)
+	}
+	return words, nil
+}
+
+// All returns words from all levels, from the easiest level to the hardest one
+func All() ([]string, error) {
+	var words []string
+	for _, level := range Levels {
+		levelWords, err := Words(level)
+		if err != nil {
+			return nil, err
+		}
+		words = append(words, levelWords...)
+	}
+	return words, nil
+}
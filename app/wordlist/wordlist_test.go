@@ -0,0 +1,63 @@
+package wordlist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		for name, expected := range map[string]Level{"A1": LevelA1, "b2": LevelB2, " c1 ": LevelC1} {
+			level, err := ParseLevel(name)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, level)
+		}
+	})
+	t.Run("unknown", func(t *testing.T) {
+		for _, name := range []string{"", "C2", "A"} {
+			_, err := ParseLevel(name)
+			assert.ErrorIs(t, err, ErrUnknownLevel)
+		}
+	})
+}
+
+func TestWords(t *testing.T) {
+	t.Run("all levels", func(t *testing.T) {
+		seen := make(map[string]Level)
+		for _, level := range Levels {
+			words, err := Words(level)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, len(words), 50, level)
+			for _, w := range words {
+				assert.Equal(t, strings.ToLower(w), w)
+				assert.NotContains(t, w, "#")
+				prevLevel, ok := seen[w]
+				assert.False(t, ok, "%v is duplicated in %v and %v", w, prevLevel, level)
+				seen[w] = level
+			}
+		}
+	})
+	t.Run("frequency order", func(t *testing.T) {
+		words, err := Words(LevelA1)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"be", "have", "do"}, words[:3])
+	})
+	t.Run("unknown level", func(t *testing.T) {
+		_, err := Words(Level("Z1"))
+		assert.ErrorIs(t, err, ErrUnknownLevel)
+	})
+}
+
+func TestAll(t *testing.T) {
+	words, err := All()
+	require.NoError(t, err)
+	a1, err := Words(LevelA1)
+	require.NoError(t, err)
+	c1, err := Words(LevelC1)
+	require.NoError(t, err)
+	assert.Equal(t, a1, words[:len(a1)])
+	assert.Equal(t, c1, words[len(words)-len(c1):])
+}